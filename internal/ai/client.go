@@ -4,33 +4,376 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
 )
 
+// maxStopSequences caps how many stop sequences a single request may carry
+const maxStopSequences = 8
+
+// DefaultAssistantName is the assistant's name unless rebranded
+const DefaultAssistantName = "Kilo"
+
+// maxAssistantNameLen caps the rebranded name, in runes
+const maxAssistantNameLen = 32
+
+// DefaultMaxTokens caps the length of each response unless overridden
+const DefaultMaxTokens = 4096
+
+// maxMaxTokens is the largest response length accepted by SetMaxTokens
+const maxMaxTokens = 64000
+
+// DefaultModel is the model used unless WithModel or SetModel picks another
+const DefaultModel = "claude-sonnet-4-20250514"
+
+// maxLanguageLen caps the response language setting, in runes
+const maxLanguageLen = 32
+
 type Client struct {
-	client anthropic.Client
-	model  string
+	client        anthropic.Client
+	model         string
+	stopSequences []string // Default stop sequences applied to every request
+	assistantName string
+	systemPrompt  string // Replaces the default prompt when set
+	language      string // Language for responses, "" to follow the user
+	temperature   *float64
+	topP          *float64
+	maxTokens     int
+
+	rateMu    sync.Mutex
+	rateLimit RateLimitStatus // Latest budget from response headers
+
+	limiter *requestLimiter // Paces requests, see WithRateLimit; nil if unpaced
+
+	maxAttempts int // Tries per request for transient errors, see WithRetry
+	retryBase   time.Duration
+
+	usageMu    sync.Mutex
+	totalUsage Usage // Tokens used by every call so far
+
+	logger *requestLogger // Transcript of API calls, see WithLogger
+}
+
+// ClientOption configures a Client
+type ClientOption func(*Client)
+
+// WithModel sets the model used for requests. It isn't validated here; use
+// ValidateModel first unless unknown models are acceptable.
+func WithModel(model string) ClientOption {
+	return func(c *Client) {
+		if model != "" {
+			c.model = model
+		}
+	}
+}
+
+// WithSystemPrompt replaces the default system prompt, e.g. to adapt the
+// assistant to another domain. The response language and per-request
+// context are still appended to it.
+func WithSystemPrompt(prompt string) ClientOption {
+	return func(c *Client) {
+		c.systemPrompt = strings.TrimSpace(prompt)
+	}
+}
+
+// WithTemperature sets the sampling temperature, as SetTemperature does.
+// Values outside 0 to 1 are ignored.
+func WithTemperature(temperature float64) ClientOption {
+	return func(c *Client) {
+		c.SetTemperature(&temperature)
+	}
+}
+
+// WithTopP sets nucleus sampling, as SetTopP does. Values outside 0 to 1
+// are ignored.
+func WithTopP(topP float64) ClientOption {
+	return func(c *Client) {
+		c.SetTopP(&topP)
+	}
+}
+
+// WithMaxTokens sets the response length cap, as SetMaxTokens does. Values
+// out of range are ignored.
+func WithMaxTokens(n int) ClientOption {
+	return func(c *Client) {
+		c.SetMaxTokens(n)
+	}
 }
 
-func NewClient(apiKey string) *Client {
+func NewClient(apiKey string, opts ...ClientOption) *Client {
 	client := anthropic.NewClient(
 		option.WithAPIKey(apiKey),
+		// Retries are ours, so the UI can report them
+		option.WithMaxRetries(0),
 	)
 
-	return &Client{
-		client: client,
-		model:  "claude-sonnet-4-20250514",
+	c := &Client{
+		client:        client,
+		model:         DefaultModel,
+		assistantName: DefaultAssistantName,
+		maxTokens:     DefaultMaxTokens,
+		maxAttempts:   DefaultMaxAttempts,
+		retryBase:     DefaultRetryBase,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.logger != nil {
+		c.logger.secret = apiKey
+	}
+	return c
+}
+
+// AssistantName returns the name the assistant introduces itself with
+func (c *Client) AssistantName() string {
+	return c.assistantName
+}
+
+// SetAssistantName rebrands the assistant. The name is sanitized before it
+// enters the system prompt; an empty result falls back to the default.
+func (c *Client) SetAssistantName(name string) {
+	c.assistantName = SanitizeAssistantName(name)
+}
+
+// SystemPrompt returns the base system prompt: the one set with
+// WithSystemPrompt, or else the default for the assistant's name
+func (c *Client) SystemPrompt() string {
+	if c.systemPrompt != "" {
+		return c.systemPrompt
+	}
+	return DefaultSystemPrompt(c.assistantName)
+}
+
+// SanitizeAssistantName strips control characters and prompt-significant
+// punctuation, collapses whitespace, and caps the length
+func SanitizeAssistantName(name string) string {
+	if cleaned := sanitizePromptText(name, maxAssistantNameLen); cleaned != "" {
+		return cleaned
+	}
+	return DefaultAssistantName
+}
+
+// Language returns the language responses are written in, or "" if unset
+func (c *Client) Language() string {
+	return c.language
+}
+
+// SetLanguage asks Claude to respond in language (e.g. "German", "pt-BR").
+// An empty name clears the setting.
+func (c *Client) SetLanguage(language string) {
+	c.language = sanitizePromptText(language, maxLanguageLen)
+}
+
+// sanitizePromptText makes user-supplied text safe to embed in the system prompt
+func sanitizePromptText(text string, maxLen int) string {
+	cleaned := strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsControl(r):
+			return ' '
+		case strings.ContainsRune("`<>{}[]#\"", r):
+			return -1
+		}
+		return r
+	}, text)
+	cleaned = strings.Join(strings.Fields(cleaned), " ")
+
+	if runes := []rune(cleaned); len(runes) > maxLen {
+		cleaned = strings.TrimSpace(string(runes[:maxLen]))
+	}
+	return cleaned
+}
+
+// Model returns the model ID used for requests
+func (c *Client) Model() string {
+	return c.model
+}
+
+// SetModel changes the model used for requests
+func (c *Client) SetModel(model string) {
+	c.model = model
+}
+
+// Temperature returns the sampling temperature, or nil for the API default
+func (c *Client) Temperature() *float64 {
+	return c.temperature
+}
+
+// SetTemperature sets the sampling temperature (0 to 1); nil restores the API default
+func (c *Client) SetTemperature(temperature *float64) error {
+	if temperature != nil && (*temperature < 0 || *temperature > 1) {
+		return fmt.Errorf("temperature must be between 0 and 1, got %g", *temperature)
+	}
+	c.temperature = temperature
+	return nil
+}
+
+// TopP returns the nucleus sampling cutoff, or nil for the API default
+func (c *Client) TopP() *float64 {
+	return c.topP
+}
+
+// SetTopP sets nucleus sampling (0 to 1): only the most likely tokens whose
+// probabilities add up to topP are sampled from. nil restores the API
+// default. Anthropic recommends changing temperature or top_p, not both.
+func (c *Client) SetTopP(topP *float64) error {
+	if topP != nil && (*topP < 0 || *topP > 1) {
+		return fmt.Errorf("top_p must be between 0 and 1, got %g", *topP)
+	}
+	c.topP = topP
+	return nil
+}
+
+// MaxTokens returns the response length cap
+func (c *Client) MaxTokens() int {
+	return c.maxTokens
+}
+
+// SetMaxTokens sets the response length cap
+func (c *Client) SetMaxTokens(n int) error {
+	if n < 1 || n > maxMaxTokens {
+		return fmt.Errorf("max tokens must be between 1 and %d, got %d", maxMaxTokens, n)
+	}
+	c.maxTokens = n
+	return nil
+}
+
+// Settings returns the options that describe how this client responds, for
+// saving alongside a conversation
+func (c *Client) Settings() Settings {
+	return Settings{
+		Model:       c.model,
+		Temperature: c.temperature,
+		TopP:        c.topP,
+		MaxTokens:   c.maxTokens,
+		Persona:     c.assistantName,
+	}
+}
+
+// ApplySettings restores settings saved with a conversation. Unset fields
+// keep the client's current values.
+func (c *Client) ApplySettings(s Settings) error {
+	if s.MaxTokens != 0 {
+		if err := c.SetMaxTokens(s.MaxTokens); err != nil {
+			return err
+		}
+	}
+	if s.Temperature != nil {
+		if err := c.SetTemperature(s.Temperature); err != nil {
+			return err
+		}
+	}
+	if s.TopP != nil {
+		if err := c.SetTopP(s.TopP); err != nil {
+			return err
+		}
+	}
+	if s.Model != "" {
+		c.SetModel(s.Model)
+	}
+	if s.Persona != "" {
+		c.SetAssistantName(s.Persona)
+	}
+	return nil
+}
+
+// SetStopSequences sets the default stop sequences used by SendMessageWithTools
+func (c *Client) SetStopSequences(sequences []string) error {
+	if err := validateStopSequences(sequences); err != nil {
+		return err
+	}
+	c.stopSequences = sequences
+	return nil
+}
+
+// RequestOption customizes a single SendMessageWithTools call
+type RequestOption func(*requestParams)
+
+type requestParams struct {
+	stopSequences []string
+	systemContext []string
+	onRetry       func(RetryStatus)
+	onThrottle    func(time.Duration)
+	maxTokens     int // Overrides the client's cap when set
+}
+
+// requestParams applies opts over the client's defaults
+func (c *Client) requestParams(opts []RequestOption) requestParams {
+	params := requestParams{stopSequences: c.stopSequences}
+	for _, opt := range opts {
+		opt(&params)
+	}
+	return params
+}
+
+// StopSequences makes the model halt when it generates any of the given strings,
+// overriding the client's default stop sequences for this request
+func StopSequences(sequences ...string) RequestOption {
+	return func(p *requestParams) {
+		p.stopSequences = sequences
+	}
+}
+
+// SystemContext appends a fragment to the system prompt for this request,
+// e.g. refreshed environment state the model should be aware of
+func SystemContext(fragment string) RequestOption {
+	return func(p *requestParams) {
+		if fragment != "" {
+			p.systemContext = append(p.systemContext, fragment)
+		}
+	}
+}
+
+// MaxTokens overrides the client's response length cap for this request,
+// e.g. to give a final answer more room than the calls deciding on tools
+func MaxTokens(n int) RequestOption {
+	return func(p *requestParams) {
+		p.maxTokens = n
 	}
 }
 
+func validateStopSequences(sequences []string) error {
+	if len(sequences) > maxStopSequences {
+		return fmt.Errorf("too many stop sequences: %d (max %d)", len(sequences), maxStopSequences)
+	}
+	for _, seq := range sequences {
+		if strings.TrimSpace(seq) == "" {
+			return fmt.Errorf("stop sequences must contain non-whitespace characters")
+		}
+	}
+	return nil
+}
+
 type Message struct {
-	Role          string
-	Content       string
-	ToolCallID    string // For assistant messages with tool calls, and tool result messages
-	ToolCallName  string // For assistant messages with tool calls
-	ToolCallInput string // For assistant messages with tool calls (JSON string)
+	Role          string `json:"role"`
+	Content       string `json:"content,omitempty"`
+	ToolCallID    string `json:"tool_call_id,omitempty"`    // For assistant messages with tool calls, and tool result messages
+	ToolCallName  string `json:"tool_call_name,omitempty"`  // For assistant messages with tool calls, and tool result messages
+	ToolCallInput string `json:"tool_call_input,omitempty"` // For assistant messages with tool calls (JSON string)
+	ToolCommand   string `json:"tool_command,omitempty"`    // For tool result messages: the resolved command(s) that ran
+	ToolError     bool   `json:"tool_error,omitempty"`      // For tool result messages: the tool failed, so Content is an error
+	// Prompt is a user message as typed, when Content adds attached files or
+	// referenced tool output to it; it's never sent
+	Prompt string `json:"prompt,omitempty"`
+	// DisplayContent is the complete output of a tool result whose Content
+	// was truncated for the API; it's shown to the user but never sent
+	DisplayContent string `json:"display_content,omitempty"`
+	// Images attached to a user message, sent ahead of Content
+	Images []Image `json:"images,omitempty"`
+}
+
+// Display returns the content to show the user: the complete output when
+// only a truncated version is sent to Claude
+func (m Message) Display() string {
+	if m.DisplayContent != "" {
+		return m.DisplayContent
+	}
+	return m.Content
 }
 
 func (c *Client) SendMessage(ctx context.Context, messages []Message) (string, error) {
@@ -39,9 +382,7 @@ func (c *Client) SendMessage(ctx context.Context, messages []Message) (string, e
 	for _, msg := range messages {
 		switch msg.Role {
 		case "user":
-			anthropicMessages = append(anthropicMessages, anthropic.NewUserMessage(
-				anthropic.NewTextBlock(msg.Content),
-			))
+			anthropicMessages = append(anthropicMessages, anthropic.NewUserMessage(userBlocks(msg)...))
 		case "assistant":
 			anthropicMessages = append(anthropicMessages, anthropic.NewAssistantMessage(
 				anthropic.NewTextBlock(msg.Content),
@@ -50,17 +391,26 @@ func (c *Client) SendMessage(ctx context.Context, messages []Message) (string, e
 		// Skip tool messages in simple send
 	}
 
-	response, err := c.client.Messages.New(
-		ctx,
-		anthropic.MessageNewParams{
-			Model:     anthropic.Model(c.model),
-			MaxTokens: 1024,
-			Messages:  anthropicMessages,
-		},
-	)
+	var response *anthropic.Message
+	err := c.withRetry(ctx, nil, func() error {
+		if err := c.pace(ctx, nil); err != nil {
+			return fmt.Errorf("waiting for rate limit: %w", err)
+		}
+		var err error
+		response, err = c.client.Messages.New(
+			ctx,
+			anthropic.MessageNewParams{
+				Model:     anthropic.Model(c.model),
+				MaxTokens: int64(c.maxTokens),
+				Messages:  anthropicMessages,
+			},
+		)
+		return err
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to send message: %w", err)
+		return "", fmt.Errorf("failed to send message: %w", c.checkModelError(err))
 	}
+	c.recordUsage(usageFrom(response.Usage))
 
 	var content string
 	for _, block := range response.Content {
@@ -73,15 +423,62 @@ func (c *Client) SendMessage(ctx context.Context, messages []Message) (string, e
 }
 
 // SendMessageWithTools sends a message with tool support
-func (c *Client) SendMessageWithTools(ctx context.Context, messages []Message, tools []Tool) (*Response, error) {
+func (c *Client) SendMessageWithTools(ctx context.Context, messages []Message, tools []Tool, opts ...RequestOption) (*Response, error) {
+	params := c.requestParams(opts)
+	request, err := c.newRequest(messages, tools, params)
+	if err != nil {
+		return nil, err
+	}
+	logged := c.logRequest(request, false)
+
+	var response *anthropic.Message
+	err = c.withRetry(ctx, params.onRetry, func() error {
+		if err := c.pace(ctx, params.onThrottle); err != nil {
+			return fmt.Errorf("waiting for rate limit: %w", err)
+		}
+
+		var httpResponse *http.Response
+		var err error
+		response, err = c.client.Messages.New(
+			ctx,
+			request,
+			option.WithResponseInto(&httpResponse),
+		)
+		c.recordRateLimit(httpResponse)
+		return err
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to send message: %w", c.checkModelError(err))
+		c.logResult(logged, nil, err)
+		return nil, err
+	}
+
+	result := toResponse(response)
+	c.recordUsage(result.Usage)
+	c.logResult(logged, result, nil)
+	return result, nil
+}
+
+// newRequest builds the API request for SendMessageWithTools and
+// StreamMessageWithTools
+func (c *Client) newRequest(messages []Message, tools []Tool, params requestParams) (anthropic.MessageNewParams, error) {
+	if err := validateStopSequences(params.stopSequences); err != nil {
+		return anthropic.MessageNewParams{}, err
+	}
+	maxTokens := c.maxTokens
+	if params.maxTokens != 0 {
+		if params.maxTokens < 1 || params.maxTokens > maxMaxTokens {
+			return anthropic.MessageNewParams{}, fmt.Errorf("max tokens must be between 1 and %d, got %d", maxMaxTokens, params.maxTokens)
+		}
+		maxTokens = params.maxTokens
+	}
+
 	// Convert messages to Anthropic format
 	anthropicMessages := make([]anthropic.MessageParam, 0, len(messages))
 	for _, msg := range messages {
 		switch msg.Role {
 		case "user":
-			anthropicMessages = append(anthropicMessages, anthropic.NewUserMessage(
-				anthropic.NewTextBlock(msg.Content),
-			))
+			anthropicMessages = append(anthropicMessages, anthropic.NewUserMessage(userBlocks(msg)...))
 		case "assistant":
 			// For assistant messages, check if there are tool calls
 			if msg.ToolCallID != "" && msg.ToolCallName != "" && msg.ToolCallInput != "" {
@@ -99,7 +496,7 @@ func (c *Client) SendMessageWithTools(ctx context.Context, messages []Message, t
 		case "tool":
 			// Tool result message
 			anthropicMessages = append(anthropicMessages, anthropic.NewUserMessage(
-				anthropic.NewToolResultBlock(msg.ToolCallID, msg.Content, false),
+				anthropic.NewToolResultBlock(msg.ToolCallID, msg.Content, msg.ToolError),
 			))
 		}
 	}
@@ -117,44 +514,49 @@ func (c *Client) SendMessageWithTools(ctx context.Context, messages []Message, t
 		anthropicTools[i] = anthropic.ToolUnionParam{OfTool: &toolParam}
 	}
 
-	response, err := c.client.Messages.New(
-		ctx,
-		anthropic.MessageNewParams{
-			Model:     anthropic.Model(c.model),
-			MaxTokens: 1024,
-			Messages:  anthropicMessages,
-			Tools:     anthropicTools,
-			System: []anthropic.TextBlockParam{
-				{
-					Text: `You are Kilo, a helpful AI support agent. Use the tools available to you to assist the user.
-
-# Tool Usage
-- When you need information to answer a question, use tools immediately without announcing your intention
-- The user sees the tool output, so you should interpret and explain what the results mean
-- Be concise and direct in your responses
-
-# Examples
-<example>
-user: what time is it?
-assistant: [uses get_time tool which returns "Sat Oct 18 14:23:45 PDT 2025"]
-The current time is 2:23 PM on Saturday, October 18th, 2025.
-</example>
-
-<example>
-user: list files in current directory
-assistant: [uses bash tool with "ls" which returns file list]
-Your directory contains: main.go, README.md, and an internal/ folder.
-</example>
-
-IMPORTANT: Keep responses under 4 lines unless the user asks for more detail.`,
-				},
-			},
-		},
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send message: %w", err)
+	system := []anthropic.TextBlockParam{
+		{Text: c.SystemPrompt()},
+	}
+	if c.language != "" {
+		// Localize the prose only; tool calls and their output must stay exact
+		system = append(system, anthropic.TextBlockParam{
+			Text: "# Language\nAlways respond in " + c.language + ", whatever language the user writes in. Keep commands, code, file paths, and quoted tool output exactly as they are; only your own explanations are translated.",
+		})
+	}
+	for _, fragment := range params.systemContext {
+		system = append(system, anthropic.TextBlockParam{Text: fragment})
+	}
+
+	request := anthropic.MessageNewParams{
+		Model:         anthropic.Model(c.model),
+		MaxTokens:     int64(maxTokens),
+		Messages:      anthropicMessages,
+		Tools:         anthropicTools,
+		StopSequences: params.stopSequences,
+		System:        system,
+	}
+	// Unset, they're left out so the API applies its defaults
+	if c.temperature != nil {
+		request.Temperature = anthropic.Float(*c.temperature)
+	}
+	if c.topP != nil {
+		request.TopP = anthropic.Float(*c.topP)
 	}
+	return request, nil
+}
 
+// userBlocks converts a user message to content blocks: its images, which
+// Claude reads best before the question about them, then its text
+func userBlocks(msg Message) []anthropic.ContentBlockParamUnion {
+	blocks := make([]anthropic.ContentBlockParamUnion, 0, len(msg.Images)+1)
+	for _, image := range msg.Images {
+		blocks = append(blocks, anthropic.NewImageBlockBase64(image.MediaType, image.Data))
+	}
+	return append(blocks, anthropic.NewTextBlock(msg.Content))
+}
+
+// toResponse extracts content and tool calls from an API message
+func toResponse(response *anthropic.Message) *Response {
 	// Extract content and tool calls
 	var content string
 	var toolCalls []ToolCall
@@ -172,16 +574,31 @@ IMPORTANT: Keep responses under 4 lines unless the user asks for more detail.`,
 		}
 	}
 
-	return &Response{
-		Content:   content,
-		ToolCalls: toolCalls,
-	}, nil
+	result := &Response{
+		Content:    content,
+		ToolCalls:  toolCalls,
+		StopReason: string(response.StopReason),
+		Usage:      usageFrom(response.Usage),
+	}
+	if response.StopReason == anthropic.StopReasonStopSequence {
+		result.StopSequence = response.StopSequence
+	}
+
+	return result
 }
 
 // Response represents an AI response
 type Response struct {
-	Content   string
-	ToolCalls []ToolCall
+	Content      string
+	ToolCalls    []ToolCall
+	StopReason   string // e.g. "end_turn", "tool_use", "stop_sequence", "max_tokens"
+	StopSequence string // The matched sequence when StopReason is "stop_sequence"
+	Usage        Usage  // Tokens billed for this call
+}
+
+// Truncated reports whether the response was cut off by the max tokens cap
+func (r *Response) Truncated() bool {
+	return r.StopReason == string(anthropic.StopReasonMaxTokens)
 }
 
 // ToolCall represents a tool call from Claude
@@ -197,4 +614,30 @@ type Tool struct {
 	Description string
 	Parameters  map[string]any
 	Required    []string
+	Idempotent  bool     // Safe to re-run without side effects (e.g. read-only queries); never set for tools that run external commands
+	Requires    []string // External binaries the tool needs on PATH
+	// MaxOutputChars overrides the executor's global output cap when > 0
+	MaxOutputChars int
+	// ConfirmReason explains why a call with this input always needs the
+	// user's approval (e.g. it would reveal secrets), or returns "" if it
+	// doesn't. Nil means never.
+	ConfirmReason func(input string) string
+	// CommandParam names the input parameter holding a shell command the
+	// tool runs, so the executor can vet the command before it runs
+	CommandParam string
+	// PathParam names the input parameter holding a file the tool writes to,
+	// vetted the same way
+	PathParam string
+	// Dangerous marks tools that can change the system (run shell commands,
+	// write files), which the user approves before each call by default
+	Dangerous bool
+	// Serial keeps calls to this tool from running at the same time as
+	// other tool calls, for tools whose effects could interfere
+	Serial bool
+}
+
+// RequiresConfirmation reports whether calls to this tool wait for the
+// user's approval by default
+func (t Tool) RequiresConfirmation() bool {
+	return t.Dangerous
 }
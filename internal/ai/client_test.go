@@ -0,0 +1,176 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// wireMessages converts messages as a request would and summarizes each
+// resulting API message as "role[block block ...]"
+func wireMessages(t *testing.T, messages []Message) []string {
+	t.Helper()
+	params, err := NewClient("").newRequest(messages, nil, requestParams{})
+	if err != nil {
+		t.Fatalf("newRequest: %v", err)
+	}
+	data, err := json.Marshal(params.Messages)
+	if err != nil {
+		t.Fatalf("marshal messages: %v", err)
+	}
+	var wire []struct {
+		Role    string           `json:"role"`
+		Content []map[string]any `json:"content"`
+	}
+	if err := json.Unmarshal(data, &wire); err != nil {
+		t.Fatalf("unmarshal messages: %v", err)
+	}
+
+	summary := make([]string, len(wire))
+	for i, msg := range wire {
+		blocks := make([]string, len(msg.Content))
+		for j, block := range msg.Content {
+			switch block["type"] {
+			case "text":
+				blocks[j] = fmt.Sprintf("text:%s", block["text"])
+			case "image":
+				source, _ := block["source"].(map[string]any)
+				blocks[j] = fmt.Sprintf("image:%s", source["media_type"])
+			case "tool_use":
+				input, _ := json.Marshal(block["input"])
+				blocks[j] = fmt.Sprintf("tool_use:%s:%s:%s", block["id"], block["name"], input)
+			case "tool_result":
+				blocks[j] = fmt.Sprintf("tool_result:%s:%v", block["tool_use_id"], block["is_error"] == true)
+			default:
+				blocks[j] = fmt.Sprint(block["type"])
+			}
+		}
+		summary[i] = msg.Role + "[" + strings.Join(blocks, " ") + "]"
+	}
+	return summary
+}
+
+func TestNewRequestConvertsMessages(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages []Message
+		want     []string
+	}{
+		{
+			name:     "user text",
+			messages: []Message{{Role: "user", Content: "hello"}},
+			want:     []string{"user[text:hello]"},
+		},
+		{
+			name: "user with image",
+			messages: []Message{{Role: "user", Content: "what's this?", Images: []Image{
+				{Name: "shot.png", MediaType: "image/png", Data: "iVBORw0KGgo="},
+			}}},
+			want: []string{"user[image:image/png text:what's this?]"},
+		},
+		{
+			name: "assistant text",
+			messages: []Message{
+				{Role: "user", Content: "hi"},
+				{Role: "assistant", Content: "Hello!"},
+			},
+			want: []string{"user[text:hi]", "assistant[text:Hello!]"},
+		},
+		{
+			name: "tool call and result",
+			messages: []Message{
+				{Role: "user", Content: "what time is it?"},
+				{Role: "assistant", ToolCallID: "toolu_1", ToolCallName: "get_time", ToolCallInput: `{"zone":"UTC"}`},
+				{Role: "tool", ToolCallID: "toolu_1", Content: "12:00"},
+				{Role: "assistant", Content: "It's noon."},
+			},
+			want: []string{
+				"user[text:what time is it?]",
+				`assistant[tool_use:toolu_1:get_time:{"zone":"UTC"}]`,
+				"user[tool_result:toolu_1:false]",
+				"assistant[text:It's noon.]",
+			},
+		},
+		{
+			name: "failed tool",
+			messages: []Message{
+				{Role: "assistant", ToolCallID: "toolu_2", ToolCallName: "bash", ToolCallInput: `{"command":"false"}`},
+				{Role: "tool", ToolCallID: "toolu_2", Content: "exit status 1", ToolError: true},
+			},
+			want: []string{
+				`assistant[tool_use:toolu_2:bash:{"command":"false"}]`,
+				"user[tool_result:toolu_2:true]",
+			},
+		},
+		{
+			name: "system notes and empty replies are left out",
+			messages: []Message{
+				{Role: "user", Content: "hi"},
+				{Role: "system", Content: "[session saved]"},
+				{Role: "assistant"},
+			},
+			want: []string{"user[text:hi]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := wireMessages(t, tt.messages)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToResponseRoundTrip(t *testing.T) {
+	var message anthropic.Message
+	err := json.Unmarshal([]byte(`{
+		"id": "msg_1",
+		"type": "message",
+		"role": "assistant",
+		"model": "claude-sonnet-4-5",
+		"content": [
+			{"type": "text", "text": "Let me check."},
+			{"type": "tool_use", "id": "toolu_1", "name": "get_time", "input": {"zone": "UTC"}}
+		],
+		"stop_reason": "tool_use",
+		"usage": {"input_tokens": 10, "output_tokens": 5}
+	}`), &message)
+	if err != nil {
+		t.Fatalf("unmarshal message: %v", err)
+	}
+
+	response := toResponse(&message)
+	if response.Content != "Let me check." {
+		t.Errorf("content = %q", response.Content)
+	}
+	if response.StopReason != "tool_use" {
+		t.Errorf("stop reason = %q", response.StopReason)
+	}
+	if response.Usage.InputTokens != 10 || response.Usage.OutputTokens != 5 {
+		t.Errorf("usage = %+v", response.Usage)
+	}
+	if len(response.ToolCalls) != 1 {
+		t.Fatalf("got %d tool calls, want 1", len(response.ToolCalls))
+	}
+	call := response.ToolCalls[0]
+	if call.ID != "toolu_1" || call.Name != "get_time" {
+		t.Errorf("tool call = %+v", call)
+	}
+
+	// The call, recorded as the TUI records it, converts back to the same
+	// tool_use block
+	got := wireMessages(t, []Message{
+		{Role: "assistant", ToolCallID: call.ID, ToolCallName: call.Name, ToolCallInput: call.Input},
+		{Role: "tool", ToolCallID: call.ID, Content: "12:00"},
+	})
+	want := []string{`assistant[tool_use:toolu_1:get_time:{"zone":"UTC"}]`, "user[tool_result:toolu_1:false]"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+}
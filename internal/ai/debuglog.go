@@ -0,0 +1,138 @@
+package ai
+
+import (
+	"encoding/json"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// apiKeyPattern matches Anthropic API keys, redacted wherever they appear in
+// the log, e.g. pasted into a message
+var apiKeyPattern = regexp.MustCompile(`sk-ant-[A-Za-z0-9_-]+`)
+
+// WithLogger writes a transcript of the API calls SendMessageWithTools and
+// StreamMessageWithTools make to w, as JSON lines: each request as sent,
+// then its response (text, tool calls, token usage) or error, matched by id.
+// API keys are redacted.
+func WithLogger(w io.Writer) ClientOption {
+	return func(c *Client) {
+		c.logger = &requestLogger{w: w}
+	}
+}
+
+// requestLogger serializes log lines from concurrent calls
+type requestLogger struct {
+	mu     sync.Mutex
+	w      io.Writer
+	secret string // The client's API key
+	lastID int
+}
+
+// logEntry is one line of the log
+type logEntry struct {
+	Time       time.Time                   `json:"time"`
+	ID         int                         `json:"id"`
+	Kind       string                      `json:"kind"` // "request", "response", or "error"
+	Stream     bool                        `json:"stream,omitempty"`
+	Request    *anthropic.MessageNewParams `json:"request,omitempty"`
+	Response   *logResponse                `json:"response,omitempty"`
+	Error      string                      `json:"error,omitempty"`
+	DurationMS int64                       `json:"duration_ms,omitempty"`
+}
+
+// logResponse is the part of a Response worth logging
+type logResponse struct {
+	Content      string         `json:"content,omitempty"`
+	ToolCalls    []logToolCall  `json:"tool_calls,omitempty"`
+	StopReason   string         `json:"stop_reason,omitempty"`
+	StopSequence string         `json:"stop_sequence,omitempty"`
+	Usage        map[string]int `json:"usage"`
+}
+
+type logToolCall struct {
+	ID    string          `json:"id"`
+	Name  string          `json:"name"`
+	Input json.RawMessage `json:"input"`
+}
+
+// loggedCall is a request that has been logged, awaiting its outcome
+type loggedCall struct {
+	id    int
+	start time.Time
+}
+
+// logRequest records a request about to be sent. It does nothing without a
+// logger.
+func (c *Client) logRequest(request anthropic.MessageNewParams, stream bool) loggedCall {
+	if c.logger == nil {
+		return loggedCall{}
+	}
+	c.logger.mu.Lock()
+	c.logger.lastID++
+	call := loggedCall{id: c.logger.lastID, start: time.Now()}
+	c.logger.mu.Unlock()
+
+	c.logger.write(logEntry{Time: call.start, ID: call.id, Kind: "request", Stream: stream, Request: &request})
+	return call
+}
+
+// logResult records how a logged request ended. A failed stream may still
+// carry the part of the response that arrived.
+func (c *Client) logResult(call loggedCall, response *Response, err error) {
+	if c.logger == nil {
+		return
+	}
+	now := time.Now()
+	entry := logEntry{Time: now, ID: call.id, Kind: "response", DurationMS: now.Sub(call.start).Milliseconds()}
+	if response != nil {
+		entry.Response = newLogResponse(response)
+	}
+	if err != nil {
+		entry.Kind = "error"
+		entry.Error = err.Error()
+	}
+	c.logger.write(entry)
+}
+
+func newLogResponse(response *Response) *logResponse {
+	logged := &logResponse{
+		Content:      response.Content,
+		StopReason:   response.StopReason,
+		StopSequence: response.StopSequence,
+		Usage: map[string]int{
+			"input_tokens":  response.Usage.InputTokens,
+			"output_tokens": response.Usage.OutputTokens,
+		},
+	}
+	for _, call := range response.ToolCalls {
+		input := json.RawMessage(call.Input)
+		if !json.Valid(input) {
+			input, _ = json.Marshal(call.Input)
+		}
+		logged.ToolCalls = append(logged.ToolCalls, logToolCall{ID: call.ID, Name: call.Name, Input: input})
+	}
+	return logged
+}
+
+// write appends entry as one line. Logging is best effort: a failure to
+// encode or write never affects the request.
+func (l *requestLogger) write(entry logEntry) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		line, _ = json.Marshal(logEntry{Time: entry.Time, ID: entry.ID, Kind: entry.Kind, Error: "failed to encode log entry: " + err.Error()})
+	}
+	text := string(line)
+	if l.secret != "" {
+		text = strings.ReplaceAll(text, l.secret, "[REDACTED]")
+	}
+	text = apiKeyPattern.ReplaceAllString(text, "[REDACTED]")
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	io.WriteString(l.w, text+"\n")
+}
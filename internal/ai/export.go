@@ -0,0 +1,82 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ExportMarkdown renders a conversation as a Markdown transcript headed by
+// the model and export time. Tool calls show their name and JSON input, and
+// tool results their complete output, each in a fenced code block. Local-only
+// system messages are left out.
+func ExportMarkdown(messages []Message, model string, exported time.Time) string {
+	var out strings.Builder
+	out.WriteString("# Kilo conversation\n\n")
+	fmt.Fprintf(&out, "- Exported: %s\n", exported.Format("2006-01-02 15:04:05 MST"))
+	fmt.Fprintf(&out, "- Model: %s\n", model)
+
+	// Tool calls and results are part of the assistant's turn, so the
+	// heading only changes when the speaker does
+	speaker := ""
+	heading := func(name string) {
+		if speaker != name {
+			fmt.Fprintf(&out, "\n## %s\n", name)
+			speaker = name
+		}
+	}
+
+	for _, msg := range messages {
+		switch {
+		case msg.Role == "user":
+			heading("User")
+			fmt.Fprintf(&out, "\n%s\n", strings.TrimSpace(msg.Content))
+			for _, image := range msg.Images {
+				fmt.Fprintf(&out, "\n*Attached image: %s*\n", image.Name)
+			}
+		case msg.Role == "assistant" && msg.ToolCallID != "":
+			heading("Assistant")
+			fmt.Fprintf(&out, "\n**Tool call: `%s`**\n\n", msg.ToolCallName)
+			writeFenced(&out, "json", indentJSON(msg.ToolCallInput))
+		case msg.Role == "assistant":
+			heading("Assistant")
+			fmt.Fprintf(&out, "\n%s\n", strings.TrimSpace(msg.Content))
+		case msg.Role == "tool":
+			heading("Assistant")
+			label := "Result"
+			if msg.ToolError {
+				label = "Failed"
+			}
+			fmt.Fprintf(&out, "\n**%s: `%s`**\n\n", label, msg.ToolCallName)
+			writeFenced(&out, "", msg.Display())
+		}
+	}
+	return out.String()
+}
+
+// writeFenced writes content in a code block whose fence is longer than any
+// backtick run inside it, so output containing ``` can't end the block early
+func writeFenced(out *strings.Builder, lang, content string) {
+	longest, run := 0, 0
+	for _, r := range content {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	fmt.Fprintf(out, "%s%s\n%s\n%s\n", fence, lang, strings.TrimRight(content, "\n"), fence)
+}
+
+// indentJSON pretty-prints a tool input, leaving invalid JSON as it is
+func indentJSON(input string) string {
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, []byte(input), "", "  "); err != nil {
+		return input
+	}
+	return indented.String()
+}
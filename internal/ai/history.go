@@ -0,0 +1,90 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// conversationVersion is bumped when the saved conversation format changes.
+// Version 2 added per-conversation settings.
+const conversationVersion = 2
+
+// Settings are the per-conversation options saved with a session and
+// restored when it's loaded, overriding the global defaults
+type Settings struct {
+	Model       string   `json:"model,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"` // Nil uses the API default
+	TopP        *float64 `json:"top_p,omitempty"`       // Nil uses the API default
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	Persona     string   `json:"persona,omitempty"` // Assistant name
+}
+
+// Session is a saved conversation together with the settings it ran with
+type Session struct {
+	Settings *Settings // Nil for conversations saved before settings existed
+	Messages []Message
+}
+
+// savedConversation is the on-disk format of a conversation
+type savedConversation struct {
+	Version  int       `json:"version"`
+	Settings *Settings `json:"settings,omitempty"`
+	Messages []Message `json:"messages"`
+}
+
+// SaveConversation writes messages to path as JSON, creating parent
+// directories as needed. Tool-call and tool-result fields are preserved so a
+// reloaded conversation can continue mid-tool-use.
+func SaveConversation(path string, messages []Message) error {
+	return SaveSession(path, Session{Messages: messages})
+}
+
+// SaveSession writes a conversation and its settings to path as JSON
+func SaveSession(path string, session Session) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+
+	data, err := json.MarshalIndent(savedConversation{
+		Version:  conversationVersion,
+		Settings: session.Settings,
+		Messages: session.Messages,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode conversation: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// LoadConversation reads the messages of a conversation saved by SaveConversation
+func LoadConversation(path string) ([]Message, error) {
+	session, err := LoadSession(path)
+	if err != nil {
+		return nil, err
+	}
+	return session.Messages, nil
+}
+
+// LoadSession reads a conversation and its settings saved by SaveSession
+func LoadSession(path string) (Session, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Session{}, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var saved savedConversation
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return Session{}, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if saved.Version > conversationVersion {
+		return Session{}, fmt.Errorf("%s was saved by a newer version of Kilo (format %d)", path, saved.Version)
+	}
+
+	return Session{Settings: saved.Settings, Messages: saved.Messages}, nil
+}
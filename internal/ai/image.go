@@ -0,0 +1,86 @@
+package ai
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// MaxImageSize is the largest image file that can be attached; the API
+// rejects larger ones
+const MaxImageSize = 5 * 1024 * 1024
+
+// imageTokens roughly estimates what one image costs in context: the API
+// charges by pixel area, about 1,600 tokens for a screenshot-sized image
+const imageTokens = 1600
+
+// imageTypes maps the image formats the API accepts to their extensions
+var imageTypes = map[string][]string{
+	"image/png":  {".png"},
+	"image/jpeg": {".jpg", ".jpeg"},
+	"image/gif":  {".gif"},
+	"image/webp": {".webp"},
+}
+
+// otherImageExtensions are image formats that aren't supported, so they get
+// a clear error rather than being read as text
+var otherImageExtensions = []string{".bmp", ".tif", ".tiff", ".svg", ".ico", ".heic", ".heif", ".avif"}
+
+// Image is an image attached to a user message, sent as a base64 content
+// block ahead of the message's text
+type Image struct {
+	Name      string `json:"name"`       // File name, for display
+	MediaType string `json:"media_type"` // One of image/png, image/jpeg, image/gif, image/webp
+	Data      string `json:"data"`       // Base64-encoded file contents
+}
+
+// IsImageFile reports whether path has an image extension, supported or
+// not; LoadImage says which
+func IsImageFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, exts := range imageTypes {
+		for _, e := range exts {
+			if ext == e {
+				return true
+			}
+		}
+	}
+	for _, e := range otherImageExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadImage reads an image file to attach to a message. The format is
+// checked from the file's contents, and must be PNG, JPEG, GIF, or WebP.
+func LoadImage(path string) (Image, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Image{}, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if info.IsDir() {
+		return Image{}, fmt.Errorf("%s is a directory", path)
+	}
+	if info.Size() > MaxImageSize {
+		return Image{}, fmt.Errorf("%s is too large to attach (%d bytes, max %d)", path, info.Size(), MaxImageSize)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Image{}, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	mediaType := http.DetectContentType(data)
+	if _, ok := imageTypes[mediaType]; !ok {
+		return Image{}, fmt.Errorf("%s is not a supported image (%s); use PNG, JPEG, GIF, or WebP", path, mediaType)
+	}
+	return Image{
+		Name:      filepath.Base(path),
+		MediaType: mediaType,
+		Data:      base64.StdEncoding.EncodeToString(data),
+	}, nil
+}
@@ -0,0 +1,108 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// KnownModels are models known to work with Kilo, used when the live model
+// list can't be fetched. Keep this in sync with Anthropic's model docs.
+var KnownModels = []string{
+	"claude-sonnet-4-5-20250929",
+	"claude-sonnet-4-20250514",
+	"claude-opus-4-1-20250805",
+	"claude-opus-4-20250514",
+	"claude-haiku-4-5-20251001",
+	"claude-3-5-haiku-20241022",
+}
+
+// DefaultContextWindow is the context size, in tokens, assumed for models
+// missing from contextWindows
+const DefaultContextWindow = 200000
+
+// contextWindows maps models to how many tokens a request may hold: the
+// system prompt, tool definitions, history, and the response
+var contextWindows = map[string]int{
+	"claude-sonnet-4-5-20250929": 200000,
+	"claude-sonnet-4-20250514":   200000,
+	"claude-opus-4-1-20250805":   200000,
+	"claude-opus-4-20250514":     200000,
+	"claude-haiku-4-5-20251001":  200000,
+	"claude-3-5-haiku-20241022":  200000,
+}
+
+// ContextWindow returns the context size of model in tokens
+func ContextWindow(model string) int {
+	if size, ok := contextWindows[model]; ok {
+		return size
+	}
+	return DefaultContextWindow
+}
+
+// ValidateModel checks model against KnownModels, so a typo fails at startup
+// rather than on the first request
+func ValidateModel(model string) error {
+	for _, known := range KnownModels {
+		if model == known {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown model %q (known models: %s)", model, strings.Join(KnownModels, ", "))
+}
+
+// ModelUnavailableError reports that the configured model does not exist or
+// has been retired
+type ModelUnavailableError struct {
+	Model     string
+	Available []string
+	Err       error
+}
+
+func (e *ModelUnavailableError) Error() string {
+	return fmt.Sprintf("model %s is unavailable — set KILO_MODEL to a supported model: %s",
+		e.Model, strings.Join(e.Available, ", "))
+}
+
+func (e *ModelUnavailableError) Unwrap() error {
+	return e.Err
+}
+
+// AvailableModels lists the model IDs the API key can use, falling back to
+// KnownModels if the list can't be fetched
+func (c *Client) AvailableModels(ctx context.Context) []string {
+	page, err := c.client.Models.List(ctx, anthropic.ModelListParams{})
+	if err != nil || len(page.Data) == 0 {
+		return KnownModels
+	}
+
+	models := make([]string, 0, len(page.Data))
+	for _, info := range page.Data {
+		models = append(models, info.ID)
+	}
+	return models
+}
+
+// checkModelError turns a model-not-found API error into an actionable
+// ModelUnavailableError and passes other errors through unchanged
+func (c *Client) checkModelError(err error) error {
+	var apiErr *anthropic.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		return err
+	}
+
+	// The request context may already be spent, so look up models separately
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return &ModelUnavailableError{
+		Model:     c.model,
+		Available: c.AvailableModels(ctx),
+		Err:       err,
+	}
+}
@@ -0,0 +1,57 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Provider sends conversations to a model API. Message, Tool, and Response
+// are provider-agnostic; each implementation converts them to its own
+// wire format. Client is the Anthropic implementation; tests substitute a
+// fake to run the TUI without the network.
+type Provider interface {
+	SendMessageWithTools(ctx context.Context, messages []Message, tools []Tool, opts ...RequestOption) (*Response, error)
+	StreamMessageWithTools(ctx context.Context, messages []Message, tools []Tool, opts ...RequestOption) (<-chan StreamEvent, error)
+}
+
+var _ Provider = (*Client)(nil)
+
+// DefaultProvider is used unless KILO_PROVIDER picks another
+const DefaultProvider = "anthropic"
+
+// ProviderFactory creates a provider, reading its own configuration (API
+// key, endpoint, ...) from the environment
+type ProviderFactory func() (Provider, error)
+
+var providers = map[string]ProviderFactory{
+	DefaultProvider: func() (Provider, error) {
+		return NewClient(os.Getenv("ANTHROPIC_API_KEY")), nil
+	},
+}
+
+// RegisterProvider makes a provider available to NewProvider under name
+func RegisterProvider(name string, factory ProviderFactory) {
+	providers[strings.ToLower(name)] = factory
+}
+
+// ProviderNames lists the registered providers in order
+func ProviderNames() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewProvider creates the named provider
+func NewProvider(name string) (Provider, error) {
+	factory, ok := providers[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q (available: %s)", name, strings.Join(ProviderNames(), ", "))
+	}
+	return factory()
+}
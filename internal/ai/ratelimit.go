@@ -0,0 +1,229 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxRateLimitWait caps how long a request is held back when the remaining
+// rate-limit budget is low
+const maxRateLimitWait = 30 * time.Second
+
+// lowTokenFraction is the share of the token limit below which the budget
+// counts as low
+const lowTokenFraction = 0.1
+
+// RateLimitStatus is the latest rate-limit budget reported in API response
+// headers. Counts are -1 when the header was absent.
+type RateLimitStatus struct {
+	RequestsLimit     int
+	RequestsRemaining int
+	RequestsReset     time.Time
+	TokensLimit       int
+	TokensRemaining   int
+	TokensReset       time.Time
+	UpdatedAt         time.Time
+}
+
+// Known reports whether any rate-limit headers have been seen yet
+func (s RateLimitStatus) Known() bool {
+	return !s.UpdatedAt.IsZero()
+}
+
+// Low reports whether the remaining budget is nearly exhausted
+func (s RateLimitStatus) Low() bool {
+	if !s.Known() {
+		return false
+	}
+	if s.RequestsRemaining >= 0 && s.RequestsRemaining <= 1 {
+		return true
+	}
+	return s.TokensRemaining >= 0 && s.TokensLimit > 0 &&
+		float64(s.TokensRemaining) < lowTokenFraction*float64(s.TokensLimit)
+}
+
+func (s RateLimitStatus) String() string {
+	if !s.Known() {
+		return "Rate limits: not reported yet"
+	}
+	return fmt.Sprintf("Rate limits: %s requests, %s tokens remaining (as of %s)",
+		formatBudget(s.RequestsRemaining, s.RequestsLimit),
+		formatBudget(s.TokensRemaining, s.TokensLimit),
+		s.UpdatedAt.Format(time.Kitchen))
+}
+
+func formatBudget(remaining, limit int) string {
+	if remaining < 0 {
+		return "?"
+	}
+	if limit < 0 {
+		return strconv.Itoa(remaining)
+	}
+	return fmt.Sprintf("%d/%d", remaining, limit)
+}
+
+// RateLimit returns the latest rate-limit budget reported by the API
+func (c *Client) RateLimit() RateLimitStatus {
+	c.rateMu.Lock()
+	defer c.rateMu.Unlock()
+	return c.rateLimit
+}
+
+// recordRateLimit captures the rate-limit headers from a response
+func (c *Client) recordRateLimit(resp *http.Response) {
+	if resp == nil || resp.Header.Get("anthropic-ratelimit-requests-limit") == "" {
+		return
+	}
+
+	h := resp.Header
+	status := RateLimitStatus{
+		RequestsLimit:     headerInt(h, "anthropic-ratelimit-requests-limit"),
+		RequestsRemaining: headerInt(h, "anthropic-ratelimit-requests-remaining"),
+		RequestsReset:     headerTime(h, "anthropic-ratelimit-requests-reset"),
+		TokensLimit:       headerInt(h, "anthropic-ratelimit-tokens-limit"),
+		TokensRemaining:   headerInt(h, "anthropic-ratelimit-tokens-remaining"),
+		TokensReset:       headerTime(h, "anthropic-ratelimit-tokens-reset"),
+		UpdatedAt:         time.Now(),
+	}
+
+	c.rateMu.Lock()
+	c.rateLimit = status
+	c.rateMu.Unlock()
+}
+
+// waitForRateLimit holds a request back until the budget resets when the last
+// response reported it nearly exhausted, rather than running into a 429
+func (c *Client) waitForRateLimit(ctx context.Context, notify func(time.Duration)) error {
+	status := c.RateLimit()
+	if !status.Low() {
+		return nil
+	}
+
+	reset := status.RequestsReset
+	if status.TokensReset.After(reset) {
+		reset = status.TokensReset
+	}
+	wait := time.Until(reset)
+	if wait <= 0 {
+		return nil
+	}
+	if wait > maxRateLimitWait {
+		wait = maxRateLimitWait
+	}
+	if notify != nil {
+		notify(wait)
+	}
+	return sleep(ctx, wait)
+}
+
+// WithRateLimit paces requests to at most rpm per minute, so a burst of
+// messages or a busy tool loop waits its turn instead of running into 429s.
+// A few requests may go out back to back before pacing starts. rpm <= 0
+// leaves requests unpaced, the default.
+func WithRateLimit(rpm int) ClientOption {
+	return func(c *Client) {
+		c.limiter = nil
+		if rpm > 0 {
+			c.limiter = newRequestLimiter(rpm)
+		}
+	}
+}
+
+// OnThrottle calls notify when this request is held back, by WithRateLimit
+// or because the API reported the budget nearly spent, with the wait ahead
+func OnThrottle(notify func(wait time.Duration)) RequestOption {
+	return func(p *requestParams) {
+		p.onThrottle = notify
+	}
+}
+
+// requestLimiter is a token bucket refilled at rpm per minute. Tokens may go
+// negative: each caller reserves the next slot, so waiters go out in order.
+type requestLimiter struct {
+	mu       sync.Mutex
+	tokens   float64
+	burst    float64
+	interval time.Duration // Time to refill one token
+	updated  time.Time
+}
+
+func newRequestLimiter(rpm int) *requestLimiter {
+	burst := float64(max(1, min(rpm/10, 5)))
+	return &requestLimiter{
+		tokens:   burst,
+		burst:    burst,
+		interval: time.Minute / time.Duration(rpm),
+		updated:  time.Now(),
+	}
+}
+
+// reserve takes a token, returning how long to wait before using it
+func (l *requestLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens = min(l.burst, l.tokens+float64(now.Sub(l.updated))/float64(l.interval))
+	l.updated = now
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens * float64(l.interval))
+}
+
+// cancel gives back a token reserved by a request that was abandoned
+func (l *requestLimiter) cancel() {
+	l.mu.Lock()
+	l.tokens = min(l.burst, l.tokens+1)
+	l.mu.Unlock()
+}
+
+// pace holds a request back as WithRateLimit and the reported budget
+// require, telling notify, if set, about any wait
+func (c *Client) pace(ctx context.Context, notify func(time.Duration)) error {
+	if c.limiter != nil {
+		if wait := c.limiter.reserve(); wait > 0 {
+			if notify != nil {
+				notify(wait)
+			}
+			if err := sleep(ctx, wait); err != nil {
+				c.limiter.cancel()
+				return err
+			}
+		}
+	}
+	return c.waitForRateLimit(ctx, notify)
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func headerInt(h http.Header, key string) int {
+	value, err := strconv.Atoi(h.Get(key))
+	if err != nil {
+		return -1
+	}
+	return value
+}
+
+func headerTime(h http.Header, key string) time.Time {
+	value, err := time.Parse(time.RFC3339, h.Get(key))
+	if err != nil {
+		return time.Time{}
+	}
+	return value
+}
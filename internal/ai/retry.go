@@ -0,0 +1,107 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// Default retry policy for transient API errors, overridable with WithRetry
+const (
+	DefaultMaxAttempts = 4
+	DefaultRetryBase   = time.Second
+)
+
+// maxRetryDelay caps the wait before any single retry
+const maxRetryDelay = 30 * time.Second
+
+// WithRetry retries requests that fail with a transient error (rate
+// limited, overloaded, server errors, dropped connections) up to
+// maxAttempts times in total, waiting about base, 2*base, 4*base, ... in
+// between. maxAttempts of 1 disables retries.
+func WithRetry(maxAttempts int, base time.Duration) ClientOption {
+	return func(c *Client) {
+		c.maxAttempts = max(maxAttempts, 1)
+		c.retryBase = base
+	}
+}
+
+// RetryStatus describes a retry about to happen
+type RetryStatus struct {
+	Attempt     int           // The attempt about to be made, starting at 2
+	MaxAttempts int           // Attempts allowed in total
+	Delay       time.Duration // Wait before the attempt
+	Err         error         // Why the previous attempt failed
+}
+
+// OnRetry calls notify before each retry of this request, e.g. so the UI can
+// show "retrying (2/5)..."
+func OnRetry(notify func(RetryStatus)) RequestOption {
+	return func(p *requestParams) {
+		p.onRetry = notify
+	}
+}
+
+// retryable reports whether err is worth retrying: rate limiting,
+// overloading, server errors, and network failures. Client errors such as a
+// bad request or invalid key fail fast, as does cancellation.
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr *anthropic.Error
+	if !errors.As(err, &apiErr) {
+		// No response at all, e.g. a reset connection
+		return true
+	}
+	switch apiErr.StatusCode {
+	case http.StatusRequestTimeout, http.StatusConflict, http.StatusTooManyRequests, 529:
+		return true
+	}
+	return apiErr.StatusCode >= 500
+}
+
+// retryDelay is the wait before the given attempt (2 for the first retry):
+// exponential backoff with jitter, or longer if the server asked for it
+func (c *Client) retryDelay(attempt int, err error) time.Duration {
+	delay := c.retryBase << (attempt - 2)
+	if delay <= 0 || delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	// Full jitter over the upper half keeps clients from retrying in lockstep
+	delay = delay/2 + rand.N(delay/2+1)
+
+	var apiErr *anthropic.Error
+	if errors.As(err, &apiErr) && apiErr.Response != nil {
+		if seconds, err := strconv.Atoi(apiErr.Response.Header.Get("retry-after")); err == nil {
+			delay = max(delay, min(time.Duration(seconds)*time.Second, maxRetryDelay))
+		}
+	}
+	return delay
+}
+
+// withRetry calls attempt until it succeeds, fails with a non-retryable
+// error, or runs out of attempts. notify, if set, is told about each retry.
+func (c *Client) withRetry(ctx context.Context, notify func(RetryStatus), attempt func() error) error {
+	for n := 1; ; n++ {
+		err := attempt()
+		if err == nil || n >= c.maxAttempts || !retryable(err) {
+			return err
+		}
+
+		status := RetryStatus{Attempt: n + 1, MaxAttempts: c.maxAttempts, Delay: c.retryDelay(n+1, err), Err: err}
+		if notify != nil {
+			notify(status)
+		}
+		select {
+		case <-time.After(status.Delay):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+)
+
+// streamAbandonTimeout is how long the final event waits for a reader
+const streamAbandonTimeout = 5 * time.Second
+
+// StreamEventType identifies what a StreamEvent carries
+type StreamEventType int
+
+const (
+	StreamTextDelta StreamEventType = iota // Text holds newly generated text
+	StreamToolCall                         // ToolCall holds a complete tool call
+	StreamDone                             // Response holds the full response
+	StreamError                            // Err says why the stream ended early
+	StreamRetry                            // Retry describes a retry after a transient error
+	StreamThrottled                        // Wait says how long the request is held back by rate limiting
+)
+
+// StreamEvent is one update from StreamMessageWithTools. The channel ends
+// with exactly one StreamDone or StreamError event before it is closed.
+type StreamEvent struct {
+	Type     StreamEventType
+	Text     string
+	ToolCall *ToolCall
+	Response *Response
+	Retry    *RetryStatus
+	Wait     time.Duration
+	Err      error
+}
+
+// PartialResponseError reports a stream that broke off (e.g. the context was
+// cancelled) after some text had already arrived
+type PartialResponseError struct {
+	Content string // Text received before the interruption
+	Err     error
+}
+
+func (e *PartialResponseError) Error() string {
+	return fmt.Sprintf("response interrupted after %d characters: %v", len(e.Content), e.Err)
+}
+
+func (e *PartialResponseError) Unwrap() error {
+	return e.Err
+}
+
+// StreamMessageWithTools is SendMessageWithTools, but delivers the response
+// incrementally: text deltas as they're generated, and each tool call once
+// its input is complete. Errors before the request is sent are returned
+// directly; later ones arrive as a StreamError event. A transient failure
+// before anything has streamed is retried, announced by a StreamRetry event.
+func (c *Client) StreamMessageWithTools(ctx context.Context, messages []Message, tools []Tool, opts ...RequestOption) (<-chan StreamEvent, error) {
+	params := c.requestParams(opts)
+	request, err := c.newRequest(messages, tools, params)
+	if err != nil {
+		return nil, err
+	}
+	logged := c.logRequest(request, true)
+
+	events := make(chan StreamEvent)
+	go func() {
+		defer close(events)
+
+		// send gives up if the consumer has gone away
+		send := func(event StreamEvent) bool {
+			select {
+			case events <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		var message anthropic.Message
+		var content string
+
+		// Tokens are billed even when the stream breaks off
+		recorded := false
+		recordUsage := func() {
+			if !recorded {
+				c.recordUsage(usageFrom(message.Usage))
+				recorded = true
+			}
+		}
+		defer recordUsage()
+
+		// finish delivers the final event even after cancellation, so a
+		// consumer still reading sees the partial content, but doesn't wait
+		// forever for one that has stopped
+		finish := func(event StreamEvent) {
+			recordUsage()
+			select {
+			case events <- event:
+			case <-time.After(streamAbandonTimeout):
+			}
+		}
+
+		// Only a stream that fails before its first event is retried; after
+		// that, the consumer has already seen part of the response, so the
+		// failure is kept aside where withRetry won't see it
+		started := false
+		var streamErr error
+		notify := func(status RetryStatus) {
+			if params.onRetry != nil {
+				params.onRetry(status)
+			}
+			send(StreamEvent{Type: StreamRetry, Retry: &status})
+		}
+		throttled := func(wait time.Duration) {
+			if params.onThrottle != nil {
+				params.onThrottle(wait)
+			}
+			send(StreamEvent{Type: StreamThrottled, Wait: wait})
+		}
+		err := c.withRetry(ctx, notify, func() error {
+			if err := c.pace(ctx, throttled); err != nil {
+				return fmt.Errorf("waiting for rate limit: %w", err)
+			}
+
+			var httpResponse *http.Response
+			stream := c.client.Messages.NewStreaming(
+				ctx,
+				request,
+				option.WithResponseInto(&httpResponse),
+			)
+			defer stream.Close()
+
+			// fail records an error after streaming began, when it's too
+			// late to retry
+			fail := func(err error) error {
+				if started {
+					streamErr = err
+					return nil
+				}
+				return err
+			}
+
+			for stream.Next() {
+				if !started {
+					started = true
+					c.recordRateLimit(httpResponse)
+				}
+				event := stream.Current()
+				if err := message.Accumulate(event); err != nil {
+					return fail(err)
+				}
+
+				switch e := event.AsAny().(type) {
+				case anthropic.ContentBlockDeltaEvent:
+					if delta, ok := e.Delta.AsAny().(anthropic.TextDelta); ok && delta.Text != "" {
+						content += delta.Text
+						if !send(StreamEvent{Type: StreamTextDelta, Text: delta.Text}) {
+							return fail(ctx.Err())
+						}
+					}
+				case anthropic.ContentBlockStopEvent:
+					block := message.Content[len(message.Content)-1]
+					if toolUse, ok := block.AsAny().(anthropic.ToolUseBlock); ok {
+						call := &ToolCall{ID: toolUse.ID, Name: toolUse.Name, Input: string(toolUse.Input)}
+						if !send(StreamEvent{Type: StreamToolCall, ToolCall: call}) {
+							return fail(ctx.Err())
+						}
+					}
+				}
+			}
+			if !started {
+				c.recordRateLimit(httpResponse)
+			}
+
+			// A cancelled context may end the stream without a decoding error
+			if err := stream.Err(); err != nil {
+				return fail(err)
+			}
+			return fail(ctx.Err())
+		})
+		if err == nil {
+			err = streamErr
+		}
+
+		if err != nil {
+			err = c.streamError(content, err)
+			c.logResult(logged, toResponse(&message), err)
+			finish(StreamEvent{Type: StreamError, Err: err})
+			return
+		}
+		response := toResponse(&message)
+		c.logResult(logged, response, nil)
+		finish(StreamEvent{Type: StreamDone, Response: response})
+	}()
+
+	return events, nil
+}
+
+// streamError wraps a failure, keeping any text that arrived before it
+func (c *Client) streamError(content string, err error) error {
+	err = fmt.Errorf("failed to send message: %w", c.checkModelError(err))
+	if content == "" {
+		return err
+	}
+	return &PartialResponseError{Content: content, Err: err}
+}
@@ -2,90 +2,107 @@ package ai
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"os/exec"
-	"strings"
 )
 
+// ToolExecutor is a registry of tools, each definition paired with the
+// handler that runs it, so the tools offered to Claude and the tools that can
+// be executed are always the same set
 type ToolExecutor struct {
-	tools map[string]ToolHandler
+	tools []registeredTool
+	index map[string]int // Position in tools by name
+}
+
+type registeredTool struct {
+	def     Tool
+	handler ToolHandler
 }
 
 type ToolHandler func(ctx context.Context, input string) (string, error)
 
-func NewToolExecutor() *ToolExecutor {
-	executor := &ToolExecutor{
-		tools: make(map[string]ToolHandler),
-	}
+// ProgressFunc receives a tool's complete output so far while it is still running
+type ProgressFunc func(output string)
 
-	// Register built-in tools
-	executor.RegisterTool("bash", executeBash)
-	executor.RegisterTool("get_time", executeGetTime)
+type progressKey struct{}
 
-	return executor
+// WithProgress attaches a listener for partial tool output to ctx
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
 }
 
-func (te *ToolExecutor) RegisterTool(name string, handler ToolHandler) {
-	te.tools[name] = handler
+// ReportProgress sends partial output to the listener on ctx, if any
+func ReportProgress(ctx context.Context, output string) {
+	if fn, ok := ctx.Value(progressKey{}).(ProgressFunc); ok {
+		fn(output)
+	}
 }
 
-func (te *ToolExecutor) Execute(ctx context.Context, toolCall ToolCall) (string, error) {
-	handler, exists := te.tools[toolCall.Name]
-	if !exists {
-		return "", fmt.Errorf("tool not found: %s", toolCall.Name)
-	}
+// AbortError is returned by a ToolHandler to stop the whole tool-calling
+// loop, e.g. when the task is impossible. Message is shown to the user as the
+// final answer instead of letting Claude keep trying.
+type AbortError struct {
+	Message string
+}
 
-	return handler(ctx, toolCall.Input)
+func (e *AbortError) Error() string {
+	return "aborted: " + e.Message
 }
 
-func (te *ToolExecutor) GetAvailableTools() []Tool {
-	return []Tool{
-		{
-			Name:        "bash",
-			Description: "Execute a bash command and return the output",
-			Parameters: map[string]any{
-				"command": map[string]any{
-					"type":        "string",
-					"description": "The bash command to execute",
-				},
-			},
-			Required: []string{"command"},
-		},
-		{
-			Name:        "get_time",
-			Description: "Get the current date and time",
-			Parameters:  map[string]any{},
-			Required:    []string{},
-		},
-	}
+// Abort returns an error that stops the tool-calling loop with message
+func Abort(message string) error {
+	return &AbortError{Message: message}
 }
 
-func executeBash(ctx context.Context, input string) (string, error) {
-	var params struct {
-		Command string `json:"command"`
+// ToolResult is a tool's output after post-processing, ready to send to Claude
+type ToolResult struct {
+	Content       string
+	Truncated     bool   // Content was cut to MaxChars
+	OriginalChars int    // Length of the output before truncation
+	MaxChars      int    // Cap that was applied
+	Aborted       bool   // The tool returned an AbortError; Content is its message
+	IsError       bool   // The tool failed or was refused; Content describes why
+	Command       string // Resolved command line(s) the tool ran, one per line
+	Full          string // The complete output when Content was truncated
+}
+
+// NewToolExecutor returns an empty registry
+func NewToolExecutor() *ToolExecutor {
+	return &ToolExecutor{index: make(map[string]int)}
+}
+
+// Register adds a tool, replacing any registered under the same name
+func (te *ToolExecutor) Register(tool Tool, handler ToolHandler) {
+	if i, ok := te.index[tool.Name]; ok {
+		te.tools[i] = registeredTool{def: tool, handler: handler}
+		return
 	}
+	te.index[tool.Name] = len(te.tools)
+	te.tools = append(te.tools, registeredTool{def: tool, handler: handler})
+}
 
-	if err := json.Unmarshal([]byte(input), &params); err != nil {
-		return "", fmt.Errorf("invalid input: %w", err)
+// Tool returns the definition of the named tool
+func (te *ToolExecutor) Tool(name string) (Tool, bool) {
+	i, ok := te.index[name]
+	if !ok {
+		return Tool{}, false
 	}
+	return te.tools[i].def, true
+}
 
-	cmd := exec.CommandContext(ctx, "bash", "-c", params.Command)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return "", fmt.Errorf("command failed: %w\nOutput: %s", err, string(output))
+func (te *ToolExecutor) Execute(ctx context.Context, toolCall ToolCall) (string, error) {
+	i, exists := te.index[toolCall.Name]
+	if !exists {
+		return "", fmt.Errorf("tool not found: %s", toolCall.Name)
 	}
 
-	return strings.TrimSpace(string(output)), nil
+	return te.tools[i].handler(ctx, toolCall.Input)
 }
 
-// executeGetTime returns the current time
-func executeGetTime(ctx context.Context, input string) (string, error) {
-	cmd := exec.CommandContext(ctx, "date")
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return "", fmt.Errorf("failed to get time: %w", err)
+// GetAvailableTools returns the registered tool definitions in registration order
+func (te *ToolExecutor) GetAvailableTools() []Tool {
+	tools := make([]Tool, len(te.tools))
+	for i, tool := range te.tools {
+		tools[i] = tool.def
 	}
-
-	return strings.TrimSpace(string(output)), nil
+	return tools
 }
@@ -0,0 +1,101 @@
+package ai
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultHistoryBudget is the estimated tokens of conversation history sent
+// with each request unless configured otherwise. It leaves room within the
+// 200K context window for the system prompt, tool definitions, and response.
+const DefaultHistoryBudget = 150000
+
+// messageOverhead approximates the tokens each message costs beyond its text
+const messageOverhead = 8
+
+// Limits on the note that replaces trimmed history
+const (
+	maxSummaryPrompts   = 20  // Most recent earlier requests listed
+	maxSummaryPromptLen = 200 // Bytes kept of each
+)
+
+// TrimHistory returns the most recent messages whose estimated size fits in
+// maxTokens, so long conversations don't overflow the context window. It
+// only cuts before a user prompt, so a tool call is never separated from its
+// result and the history still starts with the user. Dropped turns are
+// summarized by a note listing the user's earlier requests. The latest turn
+// is always kept, even when it alone is over budget. maxTokens <= 0 keeps
+// everything.
+func TrimHistory(messages []Message, maxTokens int) []Message {
+	if maxTokens <= 0 {
+		return messages
+	}
+
+	// Walk back from the end, remembering the earliest turn start that fits
+	cut, total := -1, 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		total += messageTokens(messages[i])
+		if total > maxTokens && cut >= 0 {
+			break
+		}
+		if startsTurn(messages[i]) {
+			cut = i
+		}
+	}
+	if cut <= 0 {
+		return messages
+	}
+
+	kept := append([]Message(nil), messages[cut:]...)
+	kept[0].Content = summarizeTrimmed(messages[:cut]) + "\n\n" + kept[0].Content
+	return kept
+}
+
+// startsTurn reports whether a message is a user prompt, where history can
+// be cut without separating tool calls from their results
+func startsTurn(msg Message) bool {
+	return msg.Role == "user"
+}
+
+// messageTokens estimates what a message costs in a request. Local-only
+// system messages are never sent, so they're free.
+func messageTokens(msg Message) int {
+	if msg.Role == "system" {
+		return 0
+	}
+	return EstimateTokens(msg.Content) + EstimateTokens(msg.ToolCallInput) + len(msg.Images)*imageTokens + messageOverhead
+}
+
+// summarizeTrimmed describes dropped history: how much was left out and the
+// user's most recent requests in it, so Claude keeps the thread
+func summarizeTrimmed(dropped []Message) string {
+	var prompts []string
+	count := 0
+	for _, msg := range dropped {
+		if msg.Role != "system" {
+			count++
+		}
+		if msg.Role != "user" {
+			continue
+		}
+		prompt := strings.Join(strings.Fields(msg.Content), " ")
+		if len(prompt) > maxSummaryPromptLen {
+			prompt = strings.ToValidUTF8(prompt[:maxSummaryPromptLen], "") + "…"
+		}
+		prompts = append(prompts, prompt)
+	}
+
+	var note strings.Builder
+	fmt.Fprintf(&note, "[%d earlier messages were left out to fit the context window.", count)
+	if skipped := len(prompts) - maxSummaryPrompts; skipped > 0 {
+		prompts = prompts[skipped:]
+		fmt.Fprintf(&note, " The user's last %d earlier requests were:", len(prompts))
+	} else if len(prompts) > 0 {
+		note.WriteString(" The user's earlier requests were:")
+	}
+	for _, prompt := range prompts {
+		note.WriteString("\n- " + prompt)
+	}
+	note.WriteString("]")
+	return note.String()
+}
@@ -0,0 +1,50 @@
+package ai
+
+import "github.com/anthropics/anthropic-sdk-go"
+
+// Usage counts the tokens billed for one or more API calls
+type Usage struct {
+	InputTokens  int
+	OutputTokens int
+}
+
+// Add returns the sum of two usages
+func (u Usage) Add(other Usage) Usage {
+	return Usage{
+		InputTokens:  u.InputTokens + other.InputTokens,
+		OutputTokens: u.OutputTokens + other.OutputTokens,
+	}
+}
+
+// Total returns input and output tokens combined
+func (u Usage) Total() int {
+	return u.InputTokens + u.OutputTokens
+}
+
+// EstimateTokens roughly counts the tokens in text, for progress display
+// while a response streams and for budgeting history; the API only reports
+// exact counts at the end
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+func usageFrom(usage anthropic.Usage) Usage {
+	return Usage{
+		InputTokens:  int(usage.InputTokens),
+		OutputTokens: int(usage.OutputTokens),
+	}
+}
+
+// TotalUsage returns the tokens used by every call this client has made
+func (c *Client) TotalUsage() Usage {
+	c.usageMu.Lock()
+	defer c.usageMu.Unlock()
+	return c.totalUsage
+}
+
+// recordUsage adds a response's token counts to the running total
+func (c *Client) recordUsage(usage Usage) {
+	c.usageMu.Lock()
+	defer c.usageMu.Unlock()
+	c.totalUsage = c.totalUsage.Add(usage)
+}
@@ -0,0 +1,169 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"kilo/internal/storage"
+	"kilo/internal/theme"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the config file looked up in storage.ConfigDir
+const FileName = "config.yaml"
+
+// Config holds the settings that may come from the config file. Zero values
+// mean unset, leaving the built-in default in place.
+type Config struct {
+	Model     string        // Claude model ID
+	Timeout   time.Duration // How long a tool's command may run
+	MaxTokens int           // Response length cap
+	Theme     string        // Color scheme, one of theme.Names()
+
+	// End a turn at the first failed tool call and ask the user, rather
+	// than letting Claude try alternatives
+	StopOnToolError bool
+
+	// Characters of tool output sent to Claude, for tools that don't set
+	// their own cap
+	MaxOutputChars int
+
+	// Shell commands that run without confirmation, like "ls" or "git
+	// status"; nil keeps tools.DefaultSafeCommands and empty allows none
+	SafeCommands []string
+}
+
+// file is the on-disk format. The timeout is a string so it can be written
+// as "2m" or as a number of seconds.
+type file struct {
+	Model     string `yaml:"model"`
+	Timeout   string `yaml:"timeout"`
+	MaxTokens int    `yaml:"max_tokens"`
+	Theme     string `yaml:"theme"`
+
+	StopOnToolError bool     `yaml:"stop_on_tool_error"`
+	MaxOutputChars  int      `yaml:"max_output_chars"`
+	SafeCommands    []string `yaml:"safe_commands"`
+}
+
+// Path returns the config file's location
+func Path() (string, error) {
+	dir, err := storage.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, FileName), nil
+}
+
+// LoadConfig reads the config file, if there is one, then applies the
+// environment over it: KILO_MODEL, KILO_TOOL_TIMEOUT, KILO_MAX_TOKENS,
+// KILO_THEME, KILO_STOP_ON_TOOL_ERROR, KILO_MAX_OUTPUT_CHARS, and
+// KILO_SAFE_COMMANDS (comma-separated). Command-line flags
+// are left to the caller to apply last.
+func LoadConfig() (Config, error) {
+	path, err := Path()
+	if err != nil {
+		return Config{}, err
+	}
+	config, err := readFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	if model := os.Getenv("KILO_MODEL"); model != "" {
+		config.Model = model
+	}
+	if d, ok := ParseTimeout(os.Getenv("KILO_TOOL_TIMEOUT")); ok {
+		config.Timeout = d
+	}
+	if n, err := strconv.Atoi(os.Getenv("KILO_MAX_TOKENS")); err == nil {
+		config.MaxTokens = n
+	}
+	if theme := os.Getenv("KILO_THEME"); theme != "" {
+		config.Theme = theme
+	}
+	switch strings.ToLower(os.Getenv("KILO_STOP_ON_TOOL_ERROR")) {
+	case "1", "true", "yes", "on":
+		config.StopOnToolError = true
+	case "0", "false", "no", "off":
+		config.StopOnToolError = false
+	}
+	if n, err := strconv.Atoi(os.Getenv("KILO_MAX_OUTPUT_CHARS")); err == nil {
+		config.MaxOutputChars = n
+	}
+	if list, ok := os.LookupEnv("KILO_SAFE_COMMANDS"); ok {
+		config.SafeCommands = []string{}
+		for _, command := range strings.Split(list, ",") {
+			if command = strings.TrimSpace(command); command != "" {
+				config.SafeCommands = append(config.SafeCommands, command)
+			}
+		}
+	}
+
+	if config.MaxOutputChars < 0 {
+		return Config{}, fmt.Errorf("max output chars must be positive, got %d", config.MaxOutputChars)
+	}
+	if config.Theme != "" && !theme.Valid(config.Theme) {
+		return Config{}, fmt.Errorf("unknown theme %q (choose from %s)", config.Theme, strings.Join(theme.Names(), ", "))
+	}
+	return config, nil
+}
+
+// readFile parses the config file at path. A missing file is an empty
+// config; unknown keys are an error, so typos don't go unnoticed.
+func readFile(path string) (Config, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Config{}, nil
+	}
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer f.Close()
+
+	var raw file
+	decoder := yaml.NewDecoder(f)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&raw); err != nil && !errors.Is(err, io.EOF) {
+		return Config{}, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	config := Config{
+		Model:           raw.Model,
+		MaxTokens:       raw.MaxTokens,
+		Theme:           raw.Theme,
+		StopOnToolError: raw.StopOnToolError,
+		MaxOutputChars:  raw.MaxOutputChars,
+		SafeCommands:    raw.SafeCommands,
+	}
+	if raw.Timeout != "" {
+		d, ok := ParseTimeout(raw.Timeout)
+		if !ok {
+			return Config{}, fmt.Errorf("invalid timeout %q in %s (use e.g. 90s, 2m, or a number of seconds)", raw.Timeout, path)
+		}
+		config.Timeout = d
+	}
+	return config, nil
+}
+
+// ParseTimeout reads a duration like "2m" or "90s"; a bare number is seconds
+func ParseTimeout(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return d, true
+	}
+	return 0, false
+}
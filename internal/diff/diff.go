@@ -0,0 +1,123 @@
+package diff
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Kind classifies a segment of an edit script
+type Kind int
+
+const (
+	Equal Kind = iota
+	Insert
+	Delete
+)
+
+// Segment is a run of tokens that are unchanged, inserted, or deleted
+type Segment struct {
+	Kind Kind
+	Text string
+}
+
+// Compute returns the edit script that turns a into b, one segment per token.
+// It uses a longest-common-subsequence table, so it is meant for modest inputs
+// like the tokens of a line or the lines of a single file.
+func Compute(a, b []string) []Segment {
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	segments := make([]Segment, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			segments = append(segments, Segment{Kind: Equal, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			segments = append(segments, Segment{Kind: Delete, Text: a[i]})
+			i++
+		default:
+			segments = append(segments, Segment{Kind: Insert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		segments = append(segments, Segment{Kind: Delete, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		segments = append(segments, Segment{Kind: Insert, Text: b[j]})
+	}
+
+	return segments
+}
+
+// Words diffs two lines word by word, merging adjacent segments of the same kind
+func Words(a, b string) []Segment {
+	var merged []Segment
+	for _, seg := range Compute(tokenize(a), tokenize(b)) {
+		if n := len(merged); n > 0 && merged[n-1].Kind == seg.Kind {
+			merged[n-1].Text += seg.Text
+			continue
+		}
+		merged = append(merged, seg)
+	}
+	return merged
+}
+
+// tokenize splits a line into words, whitespace runs, and single punctuation
+// characters, so that a one-character change only affects its own token
+func tokenize(s string) []string {
+	var tokens []string
+	var current strings.Builder
+	currentClass := -1
+
+	flush := func() {
+		if current.Len() > 0 {
+			tokens = append(tokens, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, r := range s {
+		class := tokenClass(r)
+		// Punctuation never groups, so each character is its own token
+		if class != currentClass || class == classPunct {
+			flush()
+		}
+		current.WriteRune(r)
+		currentClass = class
+	}
+	flush()
+
+	return tokens
+}
+
+const (
+	classWord = iota
+	classSpace
+	classPunct
+)
+
+func tokenClass(r rune) int {
+	switch {
+	case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+		return classWord
+	case unicode.IsSpace(r):
+		return classSpace
+	}
+	return classPunct
+}
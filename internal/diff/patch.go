@@ -0,0 +1,289 @@
+package diff
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DevNull is the file name a patch uses for the missing side of a created
+// or deleted file
+const DevNull = "/dev/null"
+
+// FilePatch is the part of a unified diff that changes one file
+type FilePatch struct {
+	OldName, NewName string // Without git's a/ and b/ prefixes
+	Hunks            []Hunk
+}
+
+// Hunk is one @@ section of a FilePatch. The line numbers are as the header
+// states them, and only hint at where the hunk applies.
+type Hunk struct {
+	OldStart, OldCount int
+	NewStart, NewCount int
+	Header             string   // The @@ line, for error messages
+	Lines              []string // Each starting with ' ', '-', or '+'
+	OldNoNewline       bool     // The old side's last line has no newline
+	NewNoNewline       bool     // The new side's last line has no newline
+}
+
+// hunkHeader matches "@@ -12,3 +12,4 @@", where a missing count means 1
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// Parse reads a unified diff, possibly covering several files, as produced
+// by diff -u or git diff. Lines outside the file sections, like git's
+// "diff --git" and "index" lines, are skipped. The line counts in hunk
+// headers aren't trusted: a hunk runs until the next hunk or file.
+func Parse(patch string) ([]FilePatch, error) {
+	lines := strings.Split(strings.ReplaceAll(patch, "\r\n", "\n"), "\n")
+	var files []FilePatch
+
+	for i := 0; i < len(lines); i++ {
+		if !isFileHeader(lines, i) {
+			if strings.HasPrefix(lines[i], "@@") && len(files) == 0 {
+				return nil, fmt.Errorf("line %d: hunk before any ---/+++ file header", i+1)
+			}
+			continue
+		}
+		file := FilePatch{
+			OldName: patchName(lines[i], "--- ", "a/"),
+			NewName: patchName(lines[i+1], "+++ ", "b/"),
+		}
+		i += 2
+
+		for i < len(lines) && strings.HasPrefix(lines[i], "@@") {
+			hunk, next, err := parseHunk(lines, i)
+			if err != nil {
+				return nil, err
+			}
+			file.Hunks = append(file.Hunks, hunk)
+			i = next
+		}
+		if len(file.Hunks) == 0 {
+			return nil, fmt.Errorf("no hunks for %s", file.NewName)
+		}
+		files = append(files, file)
+		i--
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no ---/+++ file headers found; expected a unified diff")
+	}
+	return files, nil
+}
+
+// isFileHeader reports whether lines[i] starts a file section: a "---" line
+// followed by "+++"
+func isFileHeader(lines []string, i int) bool {
+	return strings.HasPrefix(lines[i], "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ")
+}
+
+// patchName extracts the file name from a ---/+++ line, dropping the
+// timestamp diff -u adds and git's prefix
+func patchName(line, marker, prefix string) string {
+	name := strings.TrimPrefix(line, marker)
+	if tab := strings.IndexByte(name, '\t'); tab >= 0 {
+		name = name[:tab]
+	}
+	name = strings.TrimSpace(name)
+	if name == DevNull {
+		return name
+	}
+	return strings.TrimPrefix(name, prefix)
+}
+
+// parseHunk reads the hunk whose header is lines[start], returning it and
+// the index of the line after it
+func parseHunk(lines []string, start int) (Hunk, int, error) {
+	match := hunkHeader.FindStringSubmatch(lines[start])
+	if match == nil {
+		return Hunk{}, 0, fmt.Errorf("line %d: malformed hunk header %q", start+1, lines[start])
+	}
+	number := func(s string) int {
+		if s == "" {
+			return 1
+		}
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+	hunk := Hunk{
+		OldStart: number(match[1]), OldCount: number(match[2]),
+		NewStart: number(match[3]), NewCount: number(match[4]),
+		Header: match[0],
+	}
+
+	i := start + 1
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if strings.HasPrefix(line, "@@") || isFileHeader(lines, i) {
+			break
+		}
+		switch {
+		case line == "":
+			// Editors and models often strip the space from blank context
+			// lines, but blank lines may also just separate files or end
+			// the patch
+			if endsHunk(lines, i) {
+				return hunk, i, checkHunk(hunk)
+			}
+			hunk.Lines = append(hunk.Lines, " ")
+		case line[0] == ' ' || line[0] == '-' || line[0] == '+':
+			hunk.Lines = append(hunk.Lines, line)
+		case strings.HasPrefix(line, `\`):
+			// "\ No newline at end of file" applies to the line before it
+			if len(hunk.Lines) > 0 {
+				switch hunk.Lines[len(hunk.Lines)-1][0] {
+				case ' ':
+					hunk.OldNoNewline, hunk.NewNoNewline = true, true
+				case '-':
+					hunk.OldNoNewline = true
+				case '+':
+					hunk.NewNoNewline = true
+				}
+			}
+		default:
+			// Anything else, like the next "diff --git" line, ends the hunk
+			return hunk, i, checkHunk(hunk)
+		}
+	}
+	return hunk, i, checkHunk(hunk)
+}
+
+// endsHunk reports whether the blank line at lines[i] is followed only by
+// more blank lines and then the end of the patch or something other than
+// hunk lines
+func endsHunk(lines []string, i int) bool {
+	for i < len(lines) && lines[i] == "" {
+		i++
+	}
+	if i == len(lines) || strings.HasPrefix(lines[i], "@@") || isFileHeader(lines, i) {
+		return true
+	}
+	switch lines[i][0] {
+	case ' ', '-', '+', '\\':
+		return false
+	}
+	return true
+}
+
+func checkHunk(hunk Hunk) error {
+	if len(hunk.Lines) == 0 {
+		return fmt.Errorf("hunk %s has no lines", hunk.Header)
+	}
+	return nil
+}
+
+// sides returns the lines a hunk expects to find and the lines it leaves
+func (h Hunk) sides() (old, updated []string) {
+	for _, line := range h.Lines {
+		text := line[1:]
+		switch line[0] {
+		case ' ':
+			old = append(old, text)
+			updated = append(updated, text)
+		case '-':
+			old = append(old, text)
+		case '+':
+			updated = append(updated, text)
+		}
+	}
+	return old, updated
+}
+
+// Apply applies hunks to text in order. Each hunk's context and removed
+// lines must match the text exactly; like patch, a hunk may apply at an
+// offset from the line its header gives, choosing the nearest match.
+func Apply(text string, hunks []Hunk) (string, error) {
+	lines := splitLines(text)
+	var out []string
+	pos := 0
+	newline := text == "" || strings.HasSuffix(text, "\n")
+
+	for n, hunk := range hunks {
+		old, updated := hunk.sides()
+		// An empty old range is numbered by the line before it
+		hint := hunk.OldStart - 1
+		if len(old) == 0 {
+			hint = hunk.OldStart
+		}
+		at, ok := findLines(lines, old, pos, hint)
+		if !ok {
+			return "", mismatch(n+1, hunk, lines, old, max(hint, pos))
+		}
+		out = append(out, lines[pos:at]...)
+		out = append(out, updated...)
+		pos = at + len(old)
+
+		// Only a hunk reaching the end of the file changes how it ends
+		if pos == len(lines) {
+			if hunk.NewNoNewline {
+				newline = false
+			} else if hunk.OldNoNewline {
+				newline = true
+			}
+		}
+	}
+	out = append(out, lines[pos:]...)
+
+	if len(out) == 0 {
+		return "", nil
+	}
+	result := strings.Join(out, "\n")
+	if newline {
+		result += "\n"
+	}
+	return result, nil
+}
+
+// findLines finds want in lines at or after from, returning the match
+// nearest to hint
+func findLines(lines, want []string, from, hint int) (int, bool) {
+	if len(want) == 0 {
+		return min(max(hint, from), len(lines)), true
+	}
+	best, found := 0, false
+	for at := from; at+len(want) <= len(lines); at++ {
+		if !equalLines(lines[at:at+len(want)], want) {
+			continue
+		}
+		if !found || abs(at-hint) < abs(best-hint) {
+			best, found = at, true
+		}
+		if at >= hint {
+			break
+		}
+	}
+	return best, found
+}
+
+func equalLines(a, b []string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// mismatch explains why hunk n doesn't apply, quoting the first line that
+// differs where the header says the hunk belongs
+func mismatch(n int, hunk Hunk, lines, old []string, at int) error {
+	for i, want := range old {
+		line := at + i
+		if line >= len(lines) {
+			return fmt.Errorf("hunk %d (%s) doesn't apply: expected %q at line %d, but the file has only %d lines", n, hunk.Header, want, line+1, len(lines))
+		}
+		if lines[line] != want {
+			return fmt.Errorf("hunk %d (%s) doesn't apply: expected %q at line %d, found %q, and the hunk's lines appear nowhere else in the file", n, hunk.Header, want, line+1, lines[line])
+		}
+	}
+	return fmt.Errorf("hunk %d (%s) doesn't apply: its lines overlap an earlier hunk", n, hunk.Header)
+}
@@ -0,0 +1,154 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxLCSCells bounds the LCS table Unified builds; larger changes are shown
+// as a full replacement rather than a minimal diff
+const maxLCSCells = 4_000_000
+
+// Unified renders the changes from a to b as a unified diff with the given
+// number of context lines, labeling the files oldName and newName. It
+// returns "" when a and b are identical.
+func Unified(oldName, newName, a, b string, context int) string {
+	if a == b {
+		return ""
+	}
+	ops := lineOps(splitLines(a), splitLines(b))
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+
+	// Walk the script, emitting a hunk around each run of changes
+	for start := 0; start < len(ops); {
+		first := start
+		for first < len(ops) && ops[first].Kind == Equal {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+
+		// Extend the hunk while changes are within 2*context lines of each other
+		last := first
+		for i := first; i < len(ops); i++ {
+			if ops[i].Kind != Equal {
+				last = i
+			} else if i-last > 2*context {
+				break
+			}
+		}
+
+		from := max(first-context, start)
+		to := min(last+context+1, len(ops))
+		writeHunk(&out, ops, from, to)
+		start = to
+	}
+
+	return out.String()
+}
+
+// lineOp is one line of the edit script, with its line numbers in a and b
+type lineOp struct {
+	Segment
+	oldLine, newLine int
+}
+
+// lineOps diffs two line slices. The common prefix and suffix are matched
+// directly so that a small edit to a large file stays cheap.
+func lineOps(a, b []string) []lineOp {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var segments []Segment
+	for _, line := range a[:prefix] {
+		segments = append(segments, Segment{Kind: Equal, Text: line})
+	}
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if (len(midA)+1)*(len(midB)+1) <= maxLCSCells {
+		segments = append(segments, Compute(midA, midB)...)
+	} else {
+		for _, line := range midA {
+			segments = append(segments, Segment{Kind: Delete, Text: line})
+		}
+		for _, line := range midB {
+			segments = append(segments, Segment{Kind: Insert, Text: line})
+		}
+	}
+	for _, line := range a[len(a)-suffix:] {
+		segments = append(segments, Segment{Kind: Equal, Text: line})
+	}
+
+	ops := make([]lineOp, len(segments))
+	oldLine, newLine := 1, 1
+	for i, seg := range segments {
+		ops[i] = lineOp{Segment: seg, oldLine: oldLine, newLine: newLine}
+		if seg.Kind != Insert {
+			oldLine++
+		}
+		if seg.Kind != Delete {
+			newLine++
+		}
+	}
+	return ops
+}
+
+// writeHunk writes ops[from:to] as one @@ hunk
+func writeHunk(out *strings.Builder, ops []lineOp, from, to int) {
+	oldCount, newCount := 0, 0
+	for _, op := range ops[from:to] {
+		if op.Kind != Insert {
+			oldCount++
+		}
+		if op.Kind != Delete {
+			newCount++
+		}
+	}
+
+	// An empty range is numbered by the line before it, as diff -u does
+	oldStart, newStart := ops[from].oldLine, ops[from].newLine
+	if oldCount == 0 {
+		oldStart--
+	}
+	if newCount == 0 {
+		newStart--
+	}
+	fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+
+	for _, op := range ops[from:to] {
+		switch op.Kind {
+		case Equal:
+			out.WriteString(" ")
+		case Delete:
+			out.WriteString("-")
+		case Insert:
+			out.WriteString("+")
+		}
+		out.WriteString(op.Text)
+		out.WriteString("\n")
+	}
+}
+
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// splitLines splits text into lines without their newlines; a trailing
+// newline doesn't start another line
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
@@ -0,0 +1,156 @@
+package doctor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"kilo/internal/ai"
+	"kilo/internal/storage"
+	"kilo/internal/tools"
+
+	"github.com/joho/godotenv"
+)
+
+// Check is the outcome of a single diagnostic
+type Check struct {
+	Name   string
+	OK     bool
+	Detail string
+}
+
+// Options controls which checks run
+type Options struct {
+	// Live makes a tiny API call to verify the key and model. Off by default
+	// because it spends tokens.
+	Live bool
+}
+
+// Run executes all diagnostics and returns their results in display order
+func Run(ctx context.Context, opts Options) []Check {
+	var checks []Check
+
+	apiKey := os.Getenv("ANTHROPIC_API_KEY")
+	checks = append(checks, checkAPIKey(apiKey))
+	checks = append(checks, checkAPIReachable(ctx, apiKey, opts.Live))
+	checks = append(checks, checkToolBinaries(tools.New().GetAvailableTools())...)
+	checks = append(checks, checkGPUTools())
+	checks = append(checks, checkEnvFile(".env"))
+	checks = append(checks, checkDataDir())
+
+	return checks
+}
+
+// Passed reports whether every check succeeded
+func Passed(checks []Check) bool {
+	for _, check := range checks {
+		if !check.OK {
+			return false
+		}
+	}
+	return true
+}
+
+// Format renders checks as a pass/fail list
+func Format(checks []Check) string {
+	var output strings.Builder
+	for _, check := range checks {
+		mark := "PASS"
+		if !check.OK {
+			mark = "FAIL"
+		}
+		fmt.Fprintf(&output, "[%s] %s", mark, check.Name)
+		if check.Detail != "" {
+			fmt.Fprintf(&output, ": %s", check.Detail)
+		}
+		output.WriteString("\n")
+	}
+	return strings.TrimRight(output.String(), "\n")
+}
+
+func checkAPIKey(apiKey string) Check {
+	if apiKey == "" {
+		return Check{Name: "API key", Detail: "ANTHROPIC_API_KEY is not set (export it or add it to .env)"}
+	}
+	return Check{Name: "API key", OK: true, Detail: "ANTHROPIC_API_KEY is set"}
+}
+
+func checkAPIReachable(ctx context.Context, apiKey string, live bool) Check {
+	name := "API reachable"
+	if !live {
+		return Check{Name: name, OK: true, Detail: "skipped (run with --live to make a test call)"}
+	}
+	if apiKey == "" {
+		return Check{Name: name, Detail: "skipped, no API key"}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	client := ai.NewClient(apiKey, ai.WithModel(os.Getenv("KILO_MODEL")))
+	_, err := client.SendMessage(ctx, []ai.Message{{Role: "user", Content: "ping"}})
+	if err != nil {
+		return Check{Name: name, Detail: err.Error()}
+	}
+	return Check{Name: name, OK: true, Detail: fmt.Sprintf("model %s responded", client.Model())}
+}
+
+func checkToolBinaries(available []ai.Tool) []Check {
+	var checks []Check
+	for _, tool := range available {
+		for _, binary := range tool.Requires {
+			name := fmt.Sprintf("Tool %s", tool.Name)
+			path, err := exec.LookPath(binary)
+			if err != nil {
+				checks = append(checks, Check{Name: name, Detail: fmt.Sprintf("%s not found on PATH", binary)})
+				continue
+			}
+			checks = append(checks, Check{Name: name, OK: true, Detail: path})
+		}
+	}
+	return checks
+}
+
+// checkGPUTools reports whether the GPU tools are offered. A host without
+// nvidia-smi is fine; the tools are just left out.
+func checkGPUTools() Check {
+	name := "GPU tools"
+	if !tools.NvidiaSmiAvailable() {
+		return Check{Name: name, OK: true, Detail: "nvidia-smi not found on PATH, nvidia_smi and gpu_metrics are disabled"}
+	}
+	return Check{Name: name, OK: true, Detail: "nvidia_smi and gpu_metrics enabled"}
+}
+
+func checkEnvFile(path string) Check {
+	name := "Config " + path
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return Check{Name: name, OK: true, Detail: "not present"}
+	}
+	if _, err := godotenv.Read(path); err != nil {
+		return Check{Name: name, Detail: fmt.Sprintf("failed to parse: %v", err)}
+	}
+	return Check{Name: name, OK: true, Detail: "parsed"}
+}
+
+func checkDataDir() Check {
+	name := "Data directory"
+	dir, err := storage.DataDir()
+	if err != nil {
+		return Check{Name: name, Detail: err.Error()}
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return Check{Name: name, Detail: fmt.Sprintf("cannot create %s: %v", dir, err)}
+	}
+	file, err := os.CreateTemp(dir, ".doctor-*")
+	if err != nil {
+		return Check{Name: name, Detail: fmt.Sprintf("%s is not writable: %v", dir, err)}
+	}
+	file.Close()
+	os.Remove(file.Name())
+
+	return Check{Name: name, OK: true, Detail: dir + " is writable"}
+}
@@ -0,0 +1,332 @@
+package files
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// MaxFileSize is the largest file offered for attachment
+const MaxFileSize = 256 * 1024
+
+// MaxFiles stops the walk in very large trees so the picker stays responsive
+const MaxFiles = 10000
+
+// List walks root and returns the slash-separated relative paths of files
+// worth attaching: text files under MaxFileSize that .gitignore doesn't
+// exclude. The .git directory is always skipped.
+func List(root string) ([]string, error) {
+	var paths []string
+
+	err := walk(root, func(rel string, d fs.DirEntry) error {
+		if d.IsDir() || !d.Type().IsRegular() {
+			return nil
+		}
+		if info, err := d.Info(); err != nil || info.Size() > MaxFileSize {
+			return nil
+		}
+		if !IsText(filepath.Join(root, filepath.FromSlash(rel))) {
+			return nil
+		}
+
+		paths = append(paths, rel)
+		if len(paths) >= MaxFiles {
+			return fs.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	return paths, nil
+}
+
+// Tree renders the layout of root as an indented listing in lexical order,
+// directories marked with a trailing slash. Directories deeper than maxDepth are shown
+// but not expanded, and output stops after maxEntries lines. Like List it
+// honors .gitignore and skips .git.
+func Tree(root string, maxDepth, maxEntries int) (string, error) {
+	var out strings.Builder
+	entries := 0
+	truncated := false
+
+	err := walk(root, func(rel string, d fs.DirEntry) error {
+		if entries >= maxEntries {
+			truncated = true
+			return fs.SkipAll
+		}
+
+		depth := strings.Count(rel, "/")
+		name := d.Name()
+		if d.IsDir() {
+			name += "/"
+		}
+		out.WriteString(strings.Repeat("  ", depth) + name + "\n")
+		entries++
+
+		if d.IsDir() && depth+1 >= maxDepth {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list files: %w", err)
+	}
+
+	if truncated {
+		fmt.Fprintf(&out, "... (stopped after %d entries)\n", maxEntries)
+	}
+	return strings.TrimRight(out.String(), "\n"), nil
+}
+
+// Walk visits the entries under root the way List and Tree do: in lexical
+// order, honoring .gitignore, and skipping .git and unreadable entries
+func Walk(root string, fn func(rel string, d fs.DirEntry) error) error {
+	return walk(root, fn)
+}
+
+// walk visits every entry under root that .gitignore doesn't exclude, in
+// lexical order, passing its slash-separated path relative to root. The .git
+// directory and unreadable entries are skipped. fn may return fs.SkipDir or
+// fs.SkipAll like a WalkDirFunc.
+func walk(root string, fn func(rel string, d fs.DirEntry) error) error {
+	var rules []ignoreRule
+
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable entries are skipped rather than failing the walk
+			if d != nil && d.IsDir() && p != root {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+
+		if rel == "." {
+			rules = append(rules, loadIgnore(filepath.Join(p, ".gitignore"), "")...)
+			return nil
+		}
+		if (d.IsDir() && d.Name() == ".git") || ignored(rules, rel, d.IsDir()) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if err := fn(rel, d); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			rules = append(rules, loadIgnore(filepath.Join(p, ".gitignore"), rel)...)
+		}
+		return nil
+	})
+}
+
+// IsText reports whether the file at p looks like text: no NUL bytes in its
+// first few kilobytes
+func IsText(p string) bool {
+	f, err := os.Open(p)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	head := make([]byte, 8000)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return false
+	}
+	return !bytes.Contains(head[:n], []byte{0})
+}
+
+// Read returns the contents of an attachable file, refusing huge or binary ones
+func Read(p string) (string, error) {
+	info, err := os.Stat(p)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", p, err)
+	}
+	if info.Size() > MaxFileSize {
+		return "", fmt.Errorf("%s is too large to attach (%d bytes, max %d)", p, info.Size(), MaxFileSize)
+	}
+	if !IsText(p) {
+		return "", fmt.Errorf("%s looks like a binary file", p)
+	}
+
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", p, err)
+	}
+	return string(data), nil
+}
+
+// ignoreRule is one line of a .gitignore file, scoped to the directory it
+// was found in
+type ignoreRule struct {
+	base     string // Directory of the .gitignore, relative to the root
+	pattern  string
+	negate   bool // "!pattern" re-includes a path
+	dirOnly  bool // "pattern/" only matches directories
+	anchored bool // Contains a slash, so it matches from base rather than any level
+}
+
+// loadIgnore parses a .gitignore file. A missing file yields no rules.
+func loadIgnore(file, base string) []ignoreRule {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := ignoreRule{base: base}
+		if rest, ok := strings.CutPrefix(line, "!"); ok {
+			rule.negate = true
+			line = rest
+		}
+		if rest, ok := strings.CutSuffix(line, "/"); ok {
+			rule.dirOnly = true
+			line = rest
+		}
+		// "**/name" matches name at any depth, same as a bare "name"
+		line = strings.TrimPrefix(line, "**/")
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		rule.pattern = line
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// ignored applies rules in order; as in git, the last matching rule wins
+func ignored(rules []ignoreRule, rel string, isDir bool) bool {
+	result := false
+	for _, rule := range rules {
+		if rule.matches(rel, isDir) {
+			result = !rule.negate
+		}
+	}
+	return result
+}
+
+func (r ignoreRule) matches(rel string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if r.base != "" {
+		rest, ok := strings.CutPrefix(rel, r.base+"/")
+		if !ok {
+			return false
+		}
+		rel = rest
+	}
+
+	if r.anchored {
+		ok, _ := path.Match(r.pattern, rel)
+		return ok
+	}
+	ok, _ := path.Match(r.pattern, path.Base(rel))
+	return ok
+}
+
+// Match returns the paths containing query's characters in order (case
+// insensitive), best matches first, at most limit of them. An empty query
+// returns the first limit paths unchanged.
+func Match(query string, paths []string, limit int) []string {
+	query = strings.ToLower(strings.ReplaceAll(query, " ", ""))
+	if query == "" {
+		if len(paths) > limit {
+			return paths[:limit]
+		}
+		return paths
+	}
+
+	type scored struct {
+		path  string
+		score int
+	}
+	var matches []scored
+	for _, p := range paths {
+		if score, ok := fuzzyScore(query, p); ok {
+			matches = append(matches, scored{p, score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return len(matches[i].path) < len(matches[j].path)
+	})
+
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	result := make([]string, len(matches))
+	for i, m := range matches {
+		result[i] = m.path
+	}
+	return result
+}
+
+// fuzzyScore matches query as a subsequence of candidate. Consecutive runs
+// and matches at the start of a path segment or word score higher, as do
+// matches in the file name rather than the directories.
+func fuzzyScore(query, candidate string) (int, bool) {
+	lower := strings.ToLower(candidate)
+	nameStart := strings.LastIndex(candidate, "/") + 1
+
+	score := 0
+	qi := 0
+	prev := -2
+	for i := 0; i < len(lower) && qi < len(query); i++ {
+		if lower[i] != query[qi] {
+			continue
+		}
+
+		score++
+		if i == prev+1 {
+			score += 3
+		}
+		if i == 0 || isBoundary(rune(candidate[i-1])) {
+			score += 2
+		}
+		if i >= nameStart {
+			score++
+		}
+		prev = i
+		qi++
+	}
+
+	if qi < len(query) {
+		return 0, false
+	}
+	return score, true
+}
+
+func isBoundary(r rune) bool {
+	return r == '/' || r == '_' || r == '-' || r == '.' || unicode.IsSpace(r)
+}
@@ -0,0 +1,49 @@
+package gitctx
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Summary returns a compact description of the git repository containing dir:
+// current branch, number of dirty files, and the last commit. It returns ""
+// when dir is not inside a git work tree or git is unavailable.
+func Summary(ctx context.Context, dir string) string {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	branch, err := git(ctx, dir, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return ""
+	}
+
+	status, err := git(ctx, dir, "status", "--porcelain")
+	if err != nil {
+		return ""
+	}
+	dirty := 0
+	if status != "" {
+		dirty = len(strings.Split(status, "\n"))
+	}
+
+	// A freshly initialized repo has no commits yet
+	lastCommit, err := git(ctx, dir, "log", "-1", "--format=%h %s")
+	if err != nil || lastCommit == "" {
+		lastCommit = "(no commits)"
+	}
+
+	return fmt.Sprintf("# Git context\n- Branch: %s\n- Dirty files: %d\n- Last commit: %s", branch, dirty, lastCommit)
+}
+
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -3,6 +3,8 @@ package logo
 import (
 	"strings"
 
+	"kilo/internal/theme"
+
 	"github.com/charmbracelet/lipgloss"
 )
 
@@ -13,9 +15,7 @@ func Render(width int) string {
 		"█ █ █ █▄▄ ▀▄▀",
 	}
 
-	hotPink := lipgloss.Color("#FF10F0")
-	cyan := lipgloss.Color("#00FFFF")
-	purple := lipgloss.Color("#B026FF")
+	colors := theme.Active()
 
 	// Apply gradient styling
 	var styledLines []string
@@ -23,18 +23,18 @@ func Render(width int) string {
 		var style lipgloss.Style
 		if i == 0 {
 			style = lipgloss.NewStyle().
-				Foreground(hotPink).
+				Foreground(colors.Primary).
 				Bold(true)
 		} else {
 			style = lipgloss.NewStyle().
-				Foreground(cyan).
+				Foreground(colors.Secondary).
 				Bold(true)
 		}
 		styledLines = append(styledLines, style.Render(line))
 	}
 
 	// Add decorative lines with purple
-	lineStyle := lipgloss.NewStyle().Foreground(purple)
+	lineStyle := lipgloss.NewStyle().Foreground(colors.Accent)
 	decorLine := lineStyle.Render(strings.Repeat("▬", lipgloss.Width(logo[0])))
 
 	// Combine everything
@@ -52,9 +52,8 @@ func Render(width int) string {
 func RenderWithTagline(tagline string) string {
 	logo := Render(0)
 
-	// Neon orange for the tagline
 	taglineStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#FF6D00")).
+		Foreground(theme.Active().Warning).
 		Italic(true).
 		Bold(true)
 
@@ -0,0 +1,194 @@
+package proc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCaptureLimit caps how many bytes of subprocess output a tool reads
+const DefaultCaptureLimit = 1 << 20
+
+// OutputLimitNote is appended to the output of a command killed by the cap
+const OutputLimitNote = "[output exceeded limit, command terminated]"
+
+// NoOutputNote stands in for the output of a command that succeeded without
+// printing anything; an empty result reads like a failure
+const NoOutputNote = "(command succeeded with no output, exit code 0)"
+
+type captureLimitKey struct{}
+
+// WithCaptureLimit sets the maximum bytes of command output read by tools on ctx
+func WithCaptureLimit(ctx context.Context, limit int) context.Context {
+	return context.WithValue(ctx, captureLimitKey{}, limit)
+}
+
+// CaptureLimit returns the output cap on ctx, or DefaultCaptureLimit
+func CaptureLimit(ctx context.Context) int {
+	if limit, ok := ctx.Value(captureLimitKey{}).(int); ok && limit > 0 {
+		return limit
+	}
+	return DefaultCaptureLimit
+}
+
+// DefaultCommandTimeout bounds how long a tool's command may run
+const DefaultCommandTimeout = 30 * time.Second
+
+type commandTimeoutKey struct{}
+
+// WithCommandTimeout sets how long tool commands on ctx may run
+func WithCommandTimeout(ctx context.Context, timeout time.Duration) context.Context {
+	return context.WithValue(ctx, commandTimeoutKey{}, timeout)
+}
+
+// CommandTimeout returns the command timeout on ctx, or DefaultCommandTimeout
+func CommandTimeout(ctx context.Context) time.Duration {
+	if timeout, ok := ctx.Value(commandTimeoutKey{}).(time.Duration); ok && timeout > 0 {
+		return timeout
+	}
+	return DefaultCommandTimeout
+}
+
+// TimeoutError reports a command killed for exceeding its timeout
+type TimeoutError struct {
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s", e.Timeout)
+}
+
+// CommandContext derives a context that expires after the command timeout
+// on ctx. When it does, context.Cause reports a *TimeoutError, which tells it
+// apart from the caller's own deadline.
+func CommandContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := CommandTimeout(ctx)
+	return context.WithTimeoutCause(ctx, timeout, &TimeoutError{Timeout: timeout})
+}
+
+// CommandError describes a failed command, leading with the timeout when
+// that's what killed it
+func CommandError(ctx context.Context, err error, output string) error {
+	if output == "" {
+		output = "(none)"
+	}
+	var timeout *TimeoutError
+	if errors.As(context.Cause(ctx), &timeout) {
+		return fmt.Errorf("%w\nOutput: %s", timeout, output)
+	}
+	return fmt.Errorf("command failed: %w\nOutput: %s", err, output)
+}
+
+// RunCapped runs cmd and returns its combined stdout and stderr, also copying
+// it to sink if non-nil. Unlike CombinedOutput, it stops reading at the
+// capture limit on ctx and kills the command, so a runaway process can't
+// exhaust memory; the output read so far is returned with OutputLimitNote
+// and no error.
+func RunCapped(ctx context.Context, cmd *exec.Cmd, sink io.Writer) (string, error) {
+	c := &capture{limit: CaptureLimit(ctx), sink: sink, cmd: cmd}
+	out := &captureStream{capture: c}
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	err := c.run(ctx)
+	output := strings.TrimSpace(out.buf.String())
+	if c.exceeded {
+		return output + "\n" + OutputLimitNote, nil
+	}
+	return output, err
+}
+
+// CommandOutput is what a command printed, with stdout and stderr kept
+// apart, and how it exited
+type CommandOutput struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int  // -1 when the command was killed by a signal or never ran
+	Exceeded bool // Stopped at the capture limit
+}
+
+// RunCappedSplit is RunCapped with stdout and stderr captured separately.
+// The streams share the capture limit and are both copied to sink, in the
+// order they're written. A non-zero exit is still reported as an error,
+// alongside everything the command printed.
+func RunCappedSplit(ctx context.Context, cmd *exec.Cmd, sink io.Writer) (CommandOutput, error) {
+	c := &capture{limit: CaptureLimit(ctx), sink: sink, cmd: cmd}
+	stdout := &captureStream{capture: c}
+	stderr := &captureStream{capture: c}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err := c.run(ctx)
+	out := CommandOutput{
+		Stdout:   strings.TrimSpace(stdout.buf.String()),
+		Stderr:   strings.TrimSpace(stderr.buf.String()),
+		ExitCode: -1,
+		Exceeded: c.exceeded,
+	}
+	if cmd.ProcessState != nil {
+		out.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	if c.exceeded {
+		return out, nil
+	}
+	return out, err
+}
+
+// capture buffers a command's output up to limit bytes in total, then
+// kills it and drops the rest
+type capture struct {
+	mu       sync.Mutex
+	limit    int
+	used     int
+	sink     io.Writer
+	cmd      *exec.Cmd
+	exceeded bool
+}
+
+// run records and runs the command
+func (c *capture) run(ctx context.Context) error {
+	RecordCommand(ctx, ResolvedCommand(c.cmd))
+	if c.cmd.WaitDelay == 0 {
+		// Children that outlive a killed shell would otherwise keep the
+		// output pipe open and block Run
+		c.cmd.WaitDelay = time.Second
+	}
+	return c.cmd.Run()
+}
+
+// captureStream is one of a command's output streams
+type captureStream struct {
+	*capture
+	buf bytes.Buffer
+}
+
+func (w *captureStream) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.exceeded {
+		return len(data), nil
+	}
+
+	keep := data
+	if remaining := w.limit - w.used; len(keep) > remaining {
+		keep = keep[:remaining]
+		w.exceeded = true
+		// Writes only happen after Start, so Process is set
+		w.cmd.Process.Kill()
+	}
+
+	w.used += len(keep)
+	w.buf.Write(keep)
+	if w.sink != nil {
+		w.sink.Write(keep)
+	}
+	// Report the full length so the copy goroutine keeps draining the pipe
+	return len(data), nil
+}
@@ -0,0 +1,60 @@
+package proc
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"kilo/internal/toolenv"
+)
+
+// CommandFunc receives the fully-resolved command line a tool is about to run
+type CommandFunc func(command string)
+
+type commandKey struct{}
+
+// WithCommandRecorder attaches a listener for commands run by tools to ctx
+func WithCommandRecorder(ctx context.Context, fn CommandFunc) context.Context {
+	return context.WithValue(ctx, commandKey{}, fn)
+}
+
+// RecordCommand reports a resolved command to the listener on ctx, if any
+func RecordCommand(ctx context.Context, command string) {
+	if fn, ok := ctx.Value(commandKey{}).(CommandFunc); ok {
+		fn(command)
+	}
+}
+
+// ResolvedCommand renders cmd as a shell command line showing exactly what
+// runs: the working directory, any environment values that differ from
+// Kilo's own, and the quoted arguments. The line is shown and saved with the
+// session, so credential values are redacted (see toolenv.Sensitive).
+func ResolvedCommand(cmd *exec.Cmd) string {
+	var parts []string
+	if cmd.Dir != "" {
+		parts = append(parts, "cd", shellQuote(cmd.Dir), "&&")
+	}
+	for _, kv := range cmd.Env {
+		key, value, _ := strings.Cut(kv, "=")
+		if current, ok := os.LookupEnv(key); !ok || current != value {
+			parts = append(parts, key+"="+shellQuote(toolenv.Redact(key, value)))
+		}
+	}
+	for _, arg := range cmd.Args {
+		parts = append(parts, shellQuote(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+// safeShellWord matches arguments that need no quoting
+var safeShellWord = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// shellQuote single-quotes s unless it is made only of safe characters
+func shellQuote(s string) string {
+	if safeShellWord.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,317 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default retention caps, overridable with KILO_MAX_SESSIONS / KILO_MAX_HISTORY,
+// where 0 means no cap
+const (
+	DefaultMaxSessions       = 100
+	DefaultMaxHistoryEntries = 1000
+)
+
+// DataDir returns where Kilo keeps sessions and history. In order of
+// precedence: $KILO_DATA_DIR, $XDG_DATA_HOME/kilo, ~/.kilo.
+func DataDir() (string, error) {
+	if dir := os.Getenv("KILO_DATA_DIR"); dir != "" {
+		return dir, nil
+	}
+	if xdg := os.Getenv("XDG_DATA_HOME"); xdg != "" {
+		return filepath.Join(xdg, "kilo"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine home directory: %w", err)
+	}
+	return filepath.Join(home, ".kilo"), nil
+}
+
+// ConfigDir returns where Kilo looks for configuration. In order of
+// precedence: $KILO_CONFIG_DIR, $XDG_CONFIG_HOME/kilo, ~/.config/kilo.
+func ConfigDir() (string, error) {
+	if dir := os.Getenv("KILO_CONFIG_DIR"); dir != "" {
+		return dir, nil
+	}
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "kilo"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("cannot determine home directory: %w", err)
+	}
+	return filepath.Join(home, ".config", "kilo"), nil
+}
+
+// SessionsDir returns the directory holding saved conversations
+func SessionsDir() (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "history"), nil
+}
+
+// SessionPath returns where the session called name is saved. Only the
+// base name is used, so a save never lands outside SessionsDir whatever
+// name it's given (".json" is optional).
+func SessionPath(name string) (string, error) {
+	dir, err := SessionsDir()
+	if err != nil {
+		return "", err
+	}
+	name = filepath.Base(name)
+	if filepath.Ext(name) != ".json" {
+		name += ".json"
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// FindSession resolves a session to load: an existing file path is used
+// as-is, otherwise name is looked up in SessionsDir like SessionPath
+func FindSession(name string) (string, error) {
+	if info, err := os.Stat(name); err == nil && info.Mode().IsRegular() {
+		return name, nil
+	}
+	return SessionPath(name)
+}
+
+// SavedSession is a conversation file in SessionsDir
+type SavedSession struct {
+	Name     string // File name without ".json", as /load takes it
+	Path     string
+	Modified time.Time
+}
+
+// ListSessions returns the sessions in SessionsDir, most recently modified
+// first. A missing directory has no sessions.
+func ListSessions() ([]SavedSession, error) {
+	dir, err := SessionsDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+
+	var sessions []SavedSession
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		sessions = append(sessions, SavedSession{
+			Name:     strings.TrimSuffix(entry.Name(), ".json"),
+			Path:     filepath.Join(dir, entry.Name()),
+			Modified: info.ModTime(),
+		})
+	}
+	sort.SliceStable(sessions, func(i, j int) bool { return sessions[i].Modified.After(sessions[j].Modified) })
+	return sessions, nil
+}
+
+// LatestSession returns the most recently modified session in SessionsDir
+func LatestSession() (string, error) {
+	sessions, err := ListSessions()
+	if err != nil {
+		return "", err
+	}
+	if len(sessions) == 0 {
+		dir, _ := SessionsDir()
+		return "", fmt.Errorf("no saved sessions in %s", dir)
+	}
+	return sessions[0].Path, nil
+}
+
+// AutosaveName returns a session name for a conversation started at t
+func AutosaveName(t time.Time) string {
+	return "autosave-" + t.Format("20060102-150405")
+}
+
+// InputHistoryPath returns the file holding previously submitted prompts
+func InputHistoryPath() (string, error) {
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "input_history"), nil
+}
+
+// LoadInputHistory returns the previously submitted prompts, oldest first.
+// A missing file is an empty history.
+func LoadInputHistory() ([]string, error) {
+	path, err := InputHistoryPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	// One JSON string per line, so multi-line prompts stay one entry
+	var entries []string
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		var entry string
+		if json.Unmarshal(line, &entry) == nil && entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// AppendInputHistory records a submitted prompt
+func AppendInputHistory(entry string) error {
+	path, err := InputHistoryPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// Limits caps how much Kilo retains on disk. A cap of 0 keeps everything.
+type Limits struct {
+	MaxSessions       int
+	MaxHistoryEntries int
+}
+
+// LoadLimits reads retention caps from the environment, falling back to defaults
+func LoadLimits() Limits {
+	return Limits{
+		MaxSessions:       envInt("KILO_MAX_SESSIONS", DefaultMaxSessions),
+		MaxHistoryEntries: envInt("KILO_MAX_HISTORY", DefaultMaxHistoryEntries),
+	}
+}
+
+// Prune deletes the oldest sessions and input history entries beyond limits.
+// Missing files and directories are not an error.
+func Prune(limits Limits) error {
+	sessions, err := SessionsDir()
+	if err != nil {
+		return err
+	}
+	if limits.MaxSessions > 0 {
+		if err := PruneFiles(sessions, limits.MaxSessions); err != nil {
+			return err
+		}
+	}
+	if limits.MaxHistoryEntries == 0 {
+		return nil
+	}
+
+	history, err := InputHistoryPath()
+	if err != nil {
+		return err
+	}
+	return TruncateLines(history, limits.MaxHistoryEntries)
+}
+
+// PruneFiles keeps the newest keep sessions (".json" files) in dir, by
+// modification time. Anything else in dir is left alone.
+func PruneFiles(dir string, keep int) error {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+
+	type file struct {
+		path    string
+		modTime int64
+	}
+	var files []file
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, file{filepath.Join(dir, entry.Name()), info.ModTime().UnixNano()})
+	}
+	if len(files) <= keep {
+		return nil
+	}
+
+	// Newest first, so everything past keep is the oldest
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime > files[j].modTime })
+	for _, f := range files[keep:] {
+		if err := os.Remove(f.path); err != nil {
+			return fmt.Errorf("failed to prune %s: %w", f.path, err)
+		}
+	}
+	return nil
+}
+
+// TruncateLines keeps only the last keep lines of the file at path
+func TruncateLines(path string, keep int) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var lines [][]byte
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, append([]byte(nil), scanner.Bytes()...))
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if len(lines) <= keep {
+		return nil
+	}
+
+	kept := append(bytes.Join(lines[len(lines)-keep:], []byte("\n")), '\n')
+	if keep == 0 {
+		kept = nil
+	}
+	return os.WriteFile(path, kept, 0o600)
+}
+
+func envInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value < 0 {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPrune(t *testing.T) {
+	data := t.TempDir()
+	t.Setenv("KILO_DATA_DIR", data)
+	dir := filepath.Join(data, "history")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for i, name := range []string{"old.json", "newer.json", "newest.json", "notes.txt"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("{}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		modTime := now.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	remaining := func() []string {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		return names
+	}
+
+	// 0 is no cap
+	if err := Prune(Limits{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := remaining(); len(got) != 4 {
+		t.Errorf("Prune with no caps left %v", got)
+	}
+
+	// Only sessions count toward the cap, and only sessions are removed
+	if err := Prune(Limits{MaxSessions: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fmt.Sprint(remaining()), "[newer.json newest.json notes.txt]"; got != want {
+		t.Errorf("Prune kept %s, want %s", got, want)
+	}
+}
+
+func TestSessionPathStaysInSessionsDir(t *testing.T) {
+	data := t.TempDir()
+	t.Setenv("KILO_DATA_DIR", data)
+	dir := filepath.Join(data, "history")
+
+	// A real file by that name must not be picked for a save
+	existing := filepath.Join(t.TempDir(), "README.md")
+	if err := os.WriteFile(existing, []byte("# project"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"work", "work.json"},
+		{"work.json", "work.json"},
+		{"README.md", "README.md.json"},
+		{existing, "README.md.json"},
+		{"../../etc/passwd", "passwd.json"},
+		{"notes/today", "today.json"},
+	}
+	for _, tt := range tests {
+		got, err := SessionPath(tt.name)
+		if err != nil {
+			t.Fatalf("SessionPath(%q): %v", tt.name, err)
+		}
+		if want := filepath.Join(dir, tt.want); got != want {
+			t.Errorf("SessionPath(%q) = %s, want %s", tt.name, got, want)
+		}
+	}
+}
+
+func TestFindSession(t *testing.T) {
+	data := t.TempDir()
+	t.Setenv("KILO_DATA_DIR", data)
+
+	saved := filepath.Join(t.TempDir(), "shared.json")
+	if err := os.WriteFile(saved, []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{saved, saved},
+		{"work", filepath.Join(data, "history", "work.json")},
+		// A directory is never a session file
+		{t.TempDir(), ""},
+	}
+	for _, tt := range tests {
+		got, err := FindSession(tt.name)
+		if err != nil {
+			t.Fatalf("FindSession(%q): %v", tt.name, err)
+		}
+		if tt.want == "" {
+			tt.want = filepath.Join(data, "history", filepath.Base(tt.name)+".json")
+		}
+		if got != tt.want {
+			t.Errorf("FindSession(%q) = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestListSessions(t *testing.T) {
+	data := t.TempDir()
+	t.Setenv("KILO_DATA_DIR", data)
+
+	// A missing directory has no sessions
+	if sessions, err := ListSessions(); err != nil || len(sessions) != 0 {
+		t.Fatalf("ListSessions() = %v, %v", sessions, err)
+	}
+
+	dir := filepath.Join(data, "history")
+	if err := os.MkdirAll(filepath.Join(dir, "nested.json"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for i, name := range []string{"old.json", "newest.json", "middle.json", "notes.txt"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("{}"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		modified := now.Add(-time.Duration(3-i) * time.Hour)
+		if name == "newest.json" {
+			modified = now
+		}
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sessions, err := ListSessions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, s := range sessions {
+		names = append(names, s.Name)
+	}
+	if got, want := fmt.Sprint(names), "[newest middle old]"; got != want {
+		t.Errorf("sessions = %s, want %s", got, want)
+	}
+	if latest, err := LatestSession(); err != nil || latest != filepath.Join(dir, "newest.json") {
+		t.Errorf("LatestSession() = %s, %v", latest, err)
+	}
+}
@@ -0,0 +1,134 @@
+package theme
+
+import (
+	"sort"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// Theme is the palette the TUI and logo draw with, by role rather than hue
+type Theme struct {
+	Primary   lipgloss.TerminalColor // Assistant name, input border, selection
+	Secondary lipgloss.TerminalColor // User name, status bar, titles
+	Accent    lipgloss.TerminalColor // Conversation border, thinking status
+	Warning   lipgloss.TerminalColor // System messages, prompts that need attention
+	Muted     lipgloss.TerminalColor // Help, tool output labels, placeholders
+	Text      lipgloss.TerminalColor // Message text
+	Added     lipgloss.TerminalColor // Diff additions
+	Removed   lipgloss.TerminalColor // Diff removals
+	Inverse   lipgloss.TerminalColor // Text drawn on Added or Removed
+	Danger    lipgloss.TerminalColor // Limits about to be hit, e.g. a full context window
+
+	Markdown string // Glamour standard style for responses
+	Code     string // Chroma style for highlighted tool output
+}
+
+// themes maps names to palettes. "dark" is the name neon had before there
+// were more than two themes, kept so existing config files still load.
+var themes = map[string]Theme{
+	"neon": {
+		Primary:   lipgloss.Color("#FF10F0"),
+		Secondary: lipgloss.Color("#00FFFF"),
+		Accent:    lipgloss.Color("#B026FF"),
+		Warning:   lipgloss.Color("#FF6D00"),
+		Muted:     lipgloss.Color("#666666"),
+		Text:      lipgloss.Color("#FAFAFA"),
+		Added:     lipgloss.Color("#39FF14"),
+		Removed:   lipgloss.Color("#FF3131"),
+		Inverse:   lipgloss.Color("#000000"),
+		Danger:    lipgloss.Color("#FF3131"),
+		Markdown:  "dark",
+		Code:      "monokai",
+	},
+	"light": {
+		Primary:   lipgloss.Color("#C2009F"),
+		Secondary: lipgloss.Color("#00778A"),
+		Accent:    lipgloss.Color("#7B1FA2"),
+		Warning:   lipgloss.Color("#C44E00"),
+		Muted:     lipgloss.Color("#808080"),
+		Text:      lipgloss.Color("#1A1A1A"),
+		Added:     lipgloss.Color("#1E7F34"),
+		Removed:   lipgloss.Color("#C62828"),
+		Inverse:   lipgloss.Color("#FFFFFF"),
+		Danger:    lipgloss.Color("#C62828"),
+		Markdown:  "light",
+		Code:      "github",
+	},
+	"solarized": {
+		Primary:   lipgloss.Color("#D33682"),
+		Secondary: lipgloss.Color("#2AA198"),
+		Accent:    lipgloss.Color("#6C71C4"),
+		Warning:   lipgloss.Color("#CB4B16"),
+		Muted:     lipgloss.Color("#586E75"),
+		Text:      lipgloss.Color("#93A1A1"),
+		Added:     lipgloss.Color("#859900"),
+		Removed:   lipgloss.Color("#DC322F"),
+		Inverse:   lipgloss.Color("#002B36"),
+		Danger:    lipgloss.Color("#DC322F"),
+		Markdown:  "dark",
+		Code:      "solarized-dark",
+	},
+	// Bold and italics only, for terminals where colors are hard to read
+	"mono": {
+		Primary:   lipgloss.NoColor{},
+		Secondary: lipgloss.NoColor{},
+		Accent:    lipgloss.NoColor{},
+		Warning:   lipgloss.NoColor{},
+		Muted:     lipgloss.NoColor{},
+		Text:      lipgloss.NoColor{},
+		Added:     lipgloss.NoColor{},
+		Removed:   lipgloss.NoColor{},
+		Inverse:   lipgloss.NoColor{},
+		Danger:    lipgloss.NoColor{},
+		Markdown:  "notty",
+		Code:      "bw",
+	},
+}
+
+func init() {
+	themes["dark"] = themes["neon"]
+}
+
+// Default is the theme used unless another is selected
+const Default = "neon"
+
+// active is chosen once at startup, before anything is rendered
+var active = themes[Default]
+
+// Names lists the selectable themes
+func Names() []string {
+	var names []string
+	for name := range themes {
+		if name != "dark" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Valid reports whether name is a theme
+func Valid(name string) bool {
+	_, ok := themes[name]
+	return ok
+}
+
+// Set switches to the named theme; unknown names are ignored
+func Set(name string) {
+	if t, ok := themes[name]; ok {
+		active = t
+	}
+}
+
+// Active returns the theme in use
+func Active() Theme {
+	return active
+}
+
+// DisableColor turns off styling entirely, for NO_COLOR: text is drawn
+// without colors, bold, or italics, whatever the theme
+func DisableColor() {
+	active = themes["mono"]
+	lipgloss.SetColorProfile(termenv.Ascii)
+}
@@ -0,0 +1,111 @@
+package toolenv
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuild(t *testing.T) {
+	environ := []string{
+		"PATH=/usr/bin:/bin",
+		"HOME=/home/kilo",
+		"LD_LIBRARY_PATH=/usr/local/lib",
+		"ANTHROPIC_API_KEY=sk-ant-secret",
+	}
+	allow := []string{"PATH", "HOME"}
+
+	tests := []struct {
+		name  string
+		extra []string
+		want  []string
+	}{
+		{
+			name: "allowlisted only",
+			want: []string{"PATH=/usr/bin:/bin", "HOME=/home/kilo"},
+		},
+		{
+			name:  "override replaces",
+			extra: []string{"HOME=/tmp/home", "GITHUB_TOKEN=ghp_x"},
+			want:  []string{"PATH=/usr/bin:/bin", "HOME=/tmp/home", "GITHUB_TOKEN=ghp_x"},
+		},
+		{
+			name:  "extends an allowlisted variable",
+			extra: []string{"PATH=/opt/tools/bin:$PATH"},
+			want:  []string{"PATH=/opt/tools/bin:/usr/bin:/bin", "HOME=/home/kilo"},
+		},
+		{
+			name:  "extends an inherited variable that isn't allowlisted",
+			extra: []string{"LD_LIBRARY_PATH=/opt/lib:${LD_LIBRARY_PATH}"},
+			want:  []string{"PATH=/usr/bin:/bin", "HOME=/home/kilo", "LD_LIBRARY_PATH=/opt/lib:/usr/local/lib"},
+		},
+		{
+			name:  "unset variable extends to empty",
+			extra: []string{"PYTHONPATH=/opt/py:$PYTHONPATH"},
+			want:  []string{"PATH=/usr/bin:/bin", "HOME=/home/kilo", "PYTHONPATH=/opt/py:"},
+		},
+		{
+			name:  "later override extends an earlier one",
+			extra: []string{"PATH=/a:$PATH", "PATH=/b:$PATH"},
+			want:  []string{"PATH=/b:/a:/usr/bin:/bin", "HOME=/home/kilo"},
+		},
+		{
+			name:  "other references are kept as written",
+			extra: []string{"DB_PASSWORD=pa$$w0rd$HOME${", "PATH=$PATHX:$PATH"},
+			want:  []string{"PATH=$PATHX:/usr/bin:/bin", "HOME=/home/kilo", "DB_PASSWORD=pa$$w0rd$HOME${"},
+		},
+		{
+			name:  "withheld variables can't be pulled in by name",
+			extra: []string{"TOKEN=$ANTHROPIC_API_KEY"},
+			want:  []string{"PATH=/usr/bin:/bin", "HOME=/home/kilo", "TOKEN=$ANTHROPIC_API_KEY"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Build(environ, allow, tt.extra); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got  %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name, value, want string
+	}{
+		{"GITHUB_TOKEN", "ghp_abc", "[redacted]"},
+		{"AWS_SECRET_ACCESS_KEY", "abc", "[redacted]"},
+		{"db_password", "hunter2", "[redacted]"},
+		{"API_KEY", "", ""},
+		{"PATH", "/usr/bin", "/usr/bin"},
+		{"DATABASE_URL", "postgres://kilo:hunter2@db:5432/app", "postgres://kilo:[redacted]@db:5432/app"},
+		{"PROXY", "http://proxy:8080", "http://proxy:8080"},
+	}
+	for _, tt := range tests {
+		if got := Redact(tt.name, tt.value); got != tt.want {
+			t.Errorf("Redact(%q, %q) = %q, want %q", tt.name, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestPrefixed(t *testing.T) {
+	environ := []string{"KILO_TOOLENV_AWS_PROFILE=dev", "KILO_TOOLENV_=x", "AWS_PROFILE=prod"}
+	want := []string{"AWS_PROFILE=dev"}
+	if got := Prefixed(environ, "KILO_TOOLENV_"); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestInherit(t *testing.T) {
+	environ := []string{
+		"PATH=/usr/bin",
+		"LD_LIBRARY_PATH=/usr/local/lib",
+		"AWS_PROFILE=prod",
+		"ANTHROPIC_API_KEY=sk-ant-secret",
+	}
+	got := Inherit(environ, []string{"AWS_PROFILE=dev", "LD_LIBRARY_PATH=/opt/lib:$LD_LIBRARY_PATH", "GITHUB_TOKEN=ghp_x"})
+	want := []string{"PATH=/usr/bin", "LD_LIBRARY_PATH=/opt/lib:/usr/local/lib", "AWS_PROFILE=dev", "GITHUB_TOKEN=ghp_x"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+}
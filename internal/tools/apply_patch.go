@@ -0,0 +1,191 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"kilo/internal/ai"
+	"kilo/internal/diff"
+	"kilo/internal/files"
+)
+
+// ApplyPatchTool returns the apply_patch tool definition
+func ApplyPatchTool() ai.Tool {
+	return ai.Tool{
+		Name:        "apply_patch",
+		Description: "Apply a unified diff (as from diff -u or git diff) to files in the working directory. The patch may cover several files, and may create (--- /dev/null) or delete (+++ /dev/null) them. Context and removed lines must match the files exactly; hunks may sit at a different line than their header says. Either every file is patched or none is. Set dry_run to check that the patch applies without writing. Returns the resulting diff.",
+		Parameters: map[string]any{
+			"patch": map[string]any{
+				"type":        "string",
+				"description": "The unified diff, with ---/+++ headers and @@ hunks; paths are relative to the working directory, with or without git's a/ and b/ prefixes",
+			},
+			"dry_run": map[string]any{
+				"type":        "boolean",
+				"description": "Check the patch applies and return the diff without changing any file (default: false)",
+			},
+		},
+		Required:       []string{"patch"},
+		Dangerous:      true,
+		Serial:         true,
+		MaxOutputChars: 4 * DefaultReadLimit,
+	}
+}
+
+// patchedFile is the outcome of a FilePatch, computed before anything is
+// written
+type patchedFile struct {
+	path    string // Absolute path written, or removed when deleting
+	oldPath string // Absolute path removed when renaming, else ""
+	display string
+	old     string
+	updated string
+	mode    fs.FileMode
+	deleted bool
+}
+
+// ExecuteApplyPatch applies a unified diff. Every file's hunks are checked
+// before any file is written, so a patch that doesn't apply changes nothing.
+func ExecuteApplyPatch(ctx context.Context, input string) (string, error) {
+	var params struct {
+		Patch  string `json:"patch"`
+		DryRun bool   `json:"dry_run"`
+	}
+
+	if err := json.Unmarshal([]byte(input), &params); err != nil {
+		return "", fmt.Errorf("invalid input: %w", err)
+	}
+	if strings.TrimSpace(params.Patch) == "" {
+		return "", fmt.Errorf("patch is required")
+	}
+
+	patches, err := diff.Parse(params.Patch)
+	if err != nil {
+		return "", fmt.Errorf("invalid patch: %w", err)
+	}
+
+	var patched []patchedFile
+	for _, patch := range patches {
+		file, err := patchFile(ctx, patch)
+		if err != nil {
+			return "", err
+		}
+		patched = append(patched, file)
+	}
+
+	if !params.DryRun {
+		for _, file := range patched {
+			if err := writePatched(file); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	var output strings.Builder
+	if params.DryRun {
+		fmt.Fprintf(&output, "Dry run, nothing written: the patch applies cleanly to %d files\n", len(patched))
+	} else {
+		fmt.Fprintf(&output, "Patched %d files\n", len(patched))
+	}
+	for _, file := range patched {
+		oldName, newName := "a/"+file.display, "b/"+file.display
+		switch {
+		case file.deleted:
+			newName = diff.DevNull
+		case file.old == "" && file.oldPath == "":
+			oldName = diff.DevNull
+		}
+		output.WriteString(diff.Unified(oldName, newName, file.old, file.updated, diffContext))
+	}
+	return output.String(), nil
+}
+
+// patchFile applies one file's hunks in memory
+func patchFile(ctx context.Context, patch diff.FilePatch) (patchedFile, error) {
+	creating := patch.OldName == diff.DevNull
+	deleting := patch.NewName == diff.DevNull
+	if creating && deleting {
+		return patchedFile{}, fmt.Errorf("patch has /dev/null as both files")
+	}
+
+	name := patch.NewName
+	if deleting {
+		name = patch.OldName
+	}
+	path, err := resolveToolPath(ctx, name)
+	if err != nil {
+		return patchedFile{}, err
+	}
+	file := patchedFile{path: path, display: name, mode: 0o644, deleted: deleting}
+
+	// The file the hunks apply to, which a rename reads from its old name
+	source := path
+	if !creating && !deleting && patch.OldName != patch.NewName {
+		if source, err = resolveToolPath(ctx, patch.OldName); err != nil {
+			return patchedFile{}, err
+		}
+		file.oldPath = source
+		file.display = patch.OldName + " -> " + patch.NewName
+		if _, err := os.Stat(path); err == nil {
+			return patchedFile{}, fmt.Errorf("can't rename %s to %s: %s already exists", patch.OldName, patch.NewName, patch.NewName)
+		}
+	}
+
+	if creating {
+		if _, err := os.Stat(path); err == nil {
+			return patchedFile{}, fmt.Errorf("the patch creates %s, but it already exists", name)
+		}
+	} else {
+		info, err := os.Stat(source)
+		if err != nil {
+			return patchedFile{}, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if info.IsDir() {
+			return patchedFile{}, fmt.Errorf("%s is a directory", name)
+		}
+		if !files.IsText(source) {
+			return patchedFile{}, fmt.Errorf("%s looks like a binary file", name)
+		}
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return patchedFile{}, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		file.old = string(data)
+		file.mode = info.Mode().Perm()
+	}
+
+	if file.updated, err = diff.Apply(file.old, patch.Hunks); err != nil {
+		return patchedFile{}, fmt.Errorf("%s: %w; read the file again and regenerate the patch", name, err)
+	}
+	if deleting && file.updated != "" {
+		return patchedFile{}, fmt.Errorf("the patch deletes %s, but its hunks don't remove every line", name)
+	}
+	return file, nil
+}
+
+// writePatched writes, renames, or removes a patched file
+func writePatched(file patchedFile) error {
+	if file.deleted {
+		if err := os.Remove(file.path); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", file.display, err)
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(file.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", file.display, err)
+	}
+	if err := os.WriteFile(file.path, []byte(file.updated), file.mode); err != nil {
+		return fmt.Errorf("failed to write %s: %w", file.display, err)
+	}
+	if file.oldPath != "" {
+		if err := os.Remove(file.oldPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove %s after renaming it: %w", file.display, err)
+		}
+	}
+	return nil
+}
@@ -1,14 +1,17 @@
 package tools
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os/exec"
 	"strings"
-	"time"
 
 	"kilo/internal/ai"
+	"kilo/internal/proc"
+	"kilo/internal/toolenv"
 )
 
 // BashTool returns the bash tool definition
@@ -22,7 +25,11 @@ func BashTool() ai.Tool {
 				"description": "The bash command to execute (e.g., 'ls -la', 'date', 'pwd', 'top -l 1'). Use flags to limit output for commands that run continuously.",
 			},
 		},
-		Required: []string{"command"},
+		Required:     []string{"command"},
+		Requires:     []string{"bash"},
+		CommandParam: "command",
+		Dangerous:    true,
+		Serial:       true,
 	}
 }
 
@@ -35,14 +42,52 @@ func ExecuteBash(ctx context.Context, input string) (string, error) {
 	if err := json.Unmarshal([]byte(input), &params); err != nil {
 		return "", fmt.Errorf("invalid input: %w", err)
 	}
-	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	if err := screenConfigFrom(ctx).check(params.Command); err != nil {
+		return "", err
+	}
+	ctx, cancel := proc.CommandContext(ctx)
 	defer cancel()
 
 	cmd := exec.CommandContext(ctx, "bash", "-c", params.Command)
-	output, err := cmd.CombinedOutput()
+	cmd.Env = toolenv.FromContext(ctx)
+	cmd.Dir = workDirFrom(ctx)
+
+	// Stream output to the UI while the command runs
+	out, err := proc.RunCappedSplit(ctx, cmd, newProgressBuffer(ctx))
+	output := formatCommandOutput(out)
+	var exitErr *exec.ExitError
+	var timeout *proc.TimeoutError
+	if errors.As(err, &exitErr) && out.ExitCode > 0 && !errors.As(context.Cause(ctx), &timeout) {
+		// Whatever it printed is usually what explains the failure
+		return "", fmt.Errorf("command failed with exit code %d\n%s", out.ExitCode, cmp.Or(output, "(no output)"))
+	}
 	if err != nil {
-		return "", fmt.Errorf("command failed: %w\nOutput: %s", err, string(output))
+		return "", proc.CommandError(ctx, err, output)
+	}
+	switch {
+	case out.Exceeded:
+		// Killed at the limit, so there's no exit code to report
+		return output, nil
+	case output == "":
+		return proc.NoOutputNote, nil
 	}
+	return output + "\n[exit code 0]", nil
+}
 
-	return strings.TrimSpace(string(output)), nil
+// formatCommandOutput lays out a command's output streams, labelling them
+// when stderr has something to say so the two can be told apart
+func formatCommandOutput(out proc.CommandOutput) string {
+	var parts []string
+	switch {
+	case out.Stderr == "":
+		parts = append(parts, out.Stdout)
+	case out.Stdout == "":
+		parts = append(parts, "[stderr]\n"+out.Stderr)
+	default:
+		parts = append(parts, "[stdout]\n"+out.Stdout, "[stderr]\n"+out.Stderr)
+	}
+	if out.Exceeded {
+		parts = append(parts, proc.OutputLimitNote)
+	}
+	return strings.TrimSpace(strings.Join(parts, "\n\n"))
 }
@@ -0,0 +1,169 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"kilo/internal/ai"
+)
+
+// sparkLevels are the block characters used for sparklines, lowest first
+var sparkLevels = []rune("▁▂▃▄▅▆▇█")
+
+const (
+	defaultChartWidth = 40
+	maxChartWidth     = 120
+	maxChartPoints    = 500
+)
+
+// ChartTool returns the chart tool definition
+func ChartTool() ai.Tool {
+	return ai.Tool{
+		Name:        "chart",
+		Description: "Render numeric data as a text sparkline or horizontal bar chart so trends are visible in the terminal. Use this after gathering a series of measurements (e.g. GPU utilization samples).",
+		Parameters: map[string]any{
+			"values": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "number"},
+				"description": "The data points to plot, in order",
+			},
+			"labels": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": "Optional label per value (bar charts only)",
+			},
+			"type": map[string]any{
+				"type":        "string",
+				"enum":        []string{"sparkline", "bar"},
+				"description": "Chart style (default: sparkline)",
+			},
+			"title": map[string]any{
+				"type":        "string",
+				"description": "Optional title shown above the chart",
+			},
+			"width": map[string]any{
+				"type":        "integer",
+				"description": "Maximum bar length in characters for bar charts (default: 40)",
+			},
+		},
+		Required:   []string{"values"},
+		Idempotent: true,
+	}
+}
+
+// ExecuteChart renders the requested chart
+func ExecuteChart(ctx context.Context, input string) (string, error) {
+	var params struct {
+		Values []float64 `json:"values"`
+		Labels []string  `json:"labels"`
+		Type   string    `json:"type"`
+		Title  string    `json:"title"`
+		Width  int       `json:"width"`
+	}
+
+	if err := json.Unmarshal([]byte(input), &params); err != nil {
+		return "", fmt.Errorf("invalid input: %w", err)
+	}
+	if len(params.Values) == 0 {
+		return "", fmt.Errorf("no data points to chart")
+	}
+	if len(params.Values) > maxChartPoints {
+		return "", fmt.Errorf("too many data points: %d (max %d)", len(params.Values), maxChartPoints)
+	}
+	for i, v := range params.Values {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return "", fmt.Errorf("value %d is not a finite number", i)
+		}
+	}
+
+	var chart string
+	switch params.Type {
+	case "", "sparkline":
+		chart = renderSparkline(params.Values)
+	case "bar":
+		width := params.Width
+		if width <= 0 {
+			width = defaultChartWidth
+		}
+		chart = renderBarChart(params.Values, params.Labels, min(width, maxChartWidth))
+	default:
+		return "", fmt.Errorf("unknown chart type %q (use \"sparkline\" or \"bar\")", params.Type)
+	}
+
+	if params.Title != "" {
+		chart = params.Title + "\n" + chart
+	}
+	return chart, nil
+}
+
+// renderSparkline scales values between their min and max onto block characters
+func renderSparkline(values []float64) string {
+	lo, hi := minMax(values)
+
+	var line strings.Builder
+	for _, v := range values {
+		level := len(sparkLevels) - 1
+		if hi > lo {
+			level = int((v - lo) / (hi - lo) * float64(len(sparkLevels)-1))
+		}
+		line.WriteRune(sparkLevels[level])
+	}
+
+	return fmt.Sprintf("%s  min %s  max %s  last %s",
+		line.String(), formatNumber(lo), formatNumber(hi), formatNumber(values[len(values)-1]))
+}
+
+// renderBarChart draws one bar per value, scaled to the largest magnitude
+func renderBarChart(values []float64, labels []string, width int) string {
+	labelWidth := 0
+	for i := range values {
+		labelWidth = max(labelWidth, len(barLabel(labels, i)))
+	}
+
+	lo, hi := minMax(values)
+	scale := math.Max(math.Abs(hi), math.Abs(lo))
+
+	var chart strings.Builder
+	for i, v := range values {
+		length := 0
+		if scale > 0 {
+			length = int(math.Round(math.Abs(v) / scale * float64(width)))
+		}
+		bar := strings.Repeat("█", length)
+		if v < 0 {
+			bar = strings.Repeat("░", length)
+		}
+		fmt.Fprintf(&chart, "%-*s │%s %s\n", labelWidth, barLabel(labels, i), bar, formatNumber(v))
+	}
+	if lo < 0 {
+		chart.WriteString("(░ = negative)\n")
+	}
+
+	return strings.TrimRight(chart.String(), "\n")
+}
+
+func barLabel(labels []string, i int) string {
+	if i < len(labels) && labels[i] != "" {
+		return labels[i]
+	}
+	return fmt.Sprintf("#%d", i+1)
+}
+
+func minMax(values []float64) (float64, float64) {
+	lo, hi := values[0], values[0]
+	for _, v := range values[1:] {
+		lo = math.Min(lo, v)
+		hi = math.Max(hi, v)
+	}
+	return lo, hi
+}
+
+func formatNumber(v float64) string {
+	if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+		return fmt.Sprintf("%.0f", v)
+	}
+	return fmt.Sprintf("%.2f", v)
+}
@@ -0,0 +1,63 @@
+package tools
+
+import (
+	"context"
+	"sync"
+
+	"kilo/internal/ai"
+)
+
+// DefaultConcurrency is how many tool calls ExecuteAll runs at once unless
+// configured otherwise
+const DefaultConcurrency = 4
+
+// WithConcurrency sets how many tool calls ExecuteAll runs at once; 1 runs
+// them one after another
+func WithConcurrency(n int) Option {
+	return func(e *Executor) {
+		e.workers = n
+	}
+}
+
+// Concurrency returns how many tool calls ExecuteAll runs at once
+func (e *Executor) Concurrency() int {
+	if e.workers > 0 {
+		return e.workers
+	}
+	return DefaultConcurrency
+}
+
+// ExecuteAll runs several tool calls, as many at once as the worker pool
+// allows, and returns their results in the order of calls. A call to a
+// Serial tool runs alone: after the calls before it finish, and before any
+// after it start. Cancelling ctx stops the calls still running; calls not
+// yet started report the cancellation.
+func (e *Executor) ExecuteAll(ctx context.Context, calls []ai.ToolCall) []ai.ToolResult {
+	results := make([]ai.ToolResult, len(calls))
+	workers := make(chan struct{}, e.Concurrency())
+	var running sync.WaitGroup
+
+	for i, call := range calls {
+		if !e.Concurrent(call.Name) {
+			running.Wait()
+			results[i] = e.Execute(ctx, call)
+			continue
+		}
+
+		select {
+		case workers <- struct{}{}:
+		case <-ctx.Done():
+			results[i] = ai.ToolResult{Content: "Error: " + ctx.Err().Error(), IsError: true}
+			continue
+		}
+		running.Add(1)
+		go func() {
+			defer running.Done()
+			defer func() { <-workers }()
+			results[i] = e.Execute(ctx, call)
+		}()
+	}
+
+	running.Wait()
+	return results
+}
@@ -0,0 +1,92 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"kilo/internal/ai"
+	"kilo/internal/diff"
+)
+
+// EditFileTool returns the edit_file tool definition
+func EditFileTool() ai.Tool {
+	return ai.Tool{
+		Name:        "edit_file",
+		Description: "Replace one exact occurrence of old_string in a file with new_string. old_string must match the file exactly, including whitespace and indentation, and must occur exactly once; include surrounding lines to make it unique. Read the file first with read_file. Returns a unified diff of the change.",
+		Parameters: map[string]any{
+			"path": map[string]any{
+				"type":        "string",
+				"description": "File to edit, relative to the working directory",
+			},
+			"old_string": map[string]any{
+				"type":        "string",
+				"description": "The exact text to replace",
+			},
+			"new_string": map[string]any{
+				"type":        "string",
+				"description": "The replacement text",
+			},
+		},
+		Required:       []string{"path", "old_string", "new_string"},
+		PathParam:      "path",
+		Dangerous:      true,
+		Serial:         true,
+		MaxOutputChars: 4 * DefaultReadLimit,
+	}
+}
+
+// ExecuteEditFile performs an exact-match replacement in a file
+func ExecuteEditFile(ctx context.Context, input string) (string, error) {
+	var params struct {
+		Path      string `json:"path"`
+		OldString string `json:"old_string"`
+		NewString string `json:"new_string"`
+	}
+
+	if err := json.Unmarshal([]byte(input), &params); err != nil {
+		return "", fmt.Errorf("invalid input: %w", err)
+	}
+	if params.Path == "" {
+		return "", fmt.Errorf("path is required")
+	}
+	if params.OldString == "" {
+		return "", fmt.Errorf("old_string is required; use write_file to create a file")
+	}
+	if params.OldString == params.NewString {
+		return "", fmt.Errorf("old_string and new_string are identical")
+	}
+
+	path, err := resolveToolPath(ctx, params.Path)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", params.Path, err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", params.Path, err)
+	}
+	old := string(data)
+
+	switch count := strings.Count(old, params.OldString); count {
+	case 0:
+		return "", fmt.Errorf("old_string not found in %s; check whitespace and indentation against read_file output", params.Path)
+	case 1:
+	default:
+		return "", fmt.Errorf("old_string occurs %d times in %s; include more surrounding text so it matches once", count, params.Path)
+	}
+
+	updated := strings.Replace(old, params.OldString, params.NewString, 1)
+	if err := os.WriteFile(path, []byte(updated), info.Mode().Perm()); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", params.Path, err)
+	}
+
+	patch := diff.Unified("a/"+params.Path, "b/"+params.Path, old, updated, diffContext)
+	return fmt.Sprintf("Edited %s\n%s", params.Path, patch), nil
+}
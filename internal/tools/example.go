@@ -1,12 +1,14 @@
-package ai
+package tools
 
 import (
 	"context"
 	"fmt"
 	"os"
+
+	"kilo/internal/ai"
 )
 
-// Example demonstrates how to use the Anthropic client
+// Example demonstrates how to use the Anthropic client with Kilo's tools
 func Example() {
 	// Get API key from environment
 	apiKey := os.Getenv("ANTHROPIC_API_KEY")
@@ -16,12 +18,12 @@ func Example() {
 	}
 
 	// Create client
-	client := NewClient(apiKey)
+	client := ai.NewClient(apiKey)
 	ctx := context.Background()
 
 	// Example 1: Simple message without tools
 	fmt.Println("=== Example 1: Simple Message ===")
-	messages := []Message{
+	messages := []ai.Message{
 		{Role: "user", Content: "What is the capital of France?"},
 	}
 
@@ -34,10 +36,10 @@ func Example() {
 
 	// Example 2: Message with tools
 	fmt.Println("=== Example 2: Message with Tools ===")
-	executor := NewToolExecutor()
+	executor := New()
 	tools := executor.GetAvailableTools()
 
-	messagesWithTools := []Message{
+	messagesWithTools := []ai.Message{
 		{Role: "user", Content: "What time is it right now?"},
 	}
 
@@ -54,13 +56,9 @@ func Example() {
 			fmt.Printf("  Tool: %s (ID: %s)\n", toolCall.Name, toolCall.ID)
 			fmt.Printf("  Input: %s\n", toolCall.Input)
 
-			// Execute the tool
-			result, err := executor.Execute(ctx, toolCall)
-			if err != nil {
-				fmt.Printf("  Error executing tool: %v\n", err)
-				continue
-			}
-			fmt.Printf("  Result: %s\n", result)
+			// Execute the tool; failures are reported in the result
+			result := executor.Execute(ctx, toolCall)
+			fmt.Printf("  Result: %s\n", result.Content)
 		}
 	} else {
 		fmt.Printf("Response: %s\n", responseWithTools.Content)
@@ -68,7 +66,7 @@ func Example() {
 
 	// Example 3: Bash command execution
 	fmt.Println("\n=== Example 3: Bash Command ===")
-	bashMessages := []Message{
+	bashMessages := []ai.Message{
 		{Role: "user", Content: "List the files in the current directory using ls -la"},
 	}
 
@@ -81,12 +79,8 @@ func Example() {
 	if len(bashResponse.ToolCalls) > 0 {
 		for _, toolCall := range bashResponse.ToolCalls {
 			fmt.Printf("Executing: %s\n", toolCall.Name)
-			result, err := executor.Execute(ctx, toolCall)
-			if err != nil {
-				fmt.Printf("Error: %v\n", err)
-				continue
-			}
-			fmt.Printf("Output:\n%s\n", result)
+			result := executor.Execute(ctx, toolCall)
+			fmt.Printf("Output:\n%s\n", result.Content)
 		}
 	}
 }
@@ -0,0 +1,292 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"kilo/internal/ai"
+	"kilo/internal/proc"
+	"kilo/internal/toolenv"
+)
+
+// gitReadCommands are the subcommands git runs without allow_write
+var gitReadCommands = []string{"status", "diff", "log", "show", "branch"}
+
+// gitWriteCommands change the repository or its remotes, and only run with
+// allow_write after the user approves
+var gitWriteCommands = []string{
+	"add", "commit", "checkout", "switch", "restore", "reset", "stash",
+	"merge", "rebase", "cherry-pick", "revert", "tag", "fetch", "pull", "push",
+}
+
+// gitBranchWriteFlags make git branch create, delete, rename, or reconfigure
+// branches rather than list them
+var gitBranchWriteFlags = []string{
+	"-d", "-D", "--delete", "-m", "-M", "--move", "-c", "-C", "--copy",
+	"-f", "--force", "-u", "--set-upstream-to", "--unset-upstream", "--edit-description",
+}
+
+// gitBranchListFlags take a pattern or commit, so an argument after them
+// still only lists branches
+var gitBranchListFlags = []string{"-l", "--list", "--contains", "--no-contains", "--merged", "--no-merged", "--points-at"}
+
+// GitTool returns the git tool definition
+func GitTool() ai.Tool {
+	return ai.Tool{
+		Name:        "git",
+		Description: "Inspect the git repository in the working directory. Runs status (parsed into staged, unstaged, untracked, and conflicted files), diff, log, show, or branch with optional extra arguments, e.g. {\"subcommand\": \"log\", \"args\": [\"-5\", \"--oneline\"]}. Every result starts with the current branch and whether the tree is clean. Commands that change the repository (commit, reset, push, ...) are refused unless allow_write is true, and then the user must approve them.",
+		Parameters: map[string]any{
+			"subcommand": map[string]any{
+				"type":        "string",
+				"enum":        append(slices.Clone(gitReadCommands), gitWriteCommands...),
+				"description": "The git subcommand to run",
+			},
+			"args": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": "Extra arguments, e.g. [\"--stat\", \"HEAD~3\"] or [\"--\", \"path/to/file\"]",
+			},
+			"allow_write": map[string]any{
+				"type":        "boolean",
+				"description": "Permit a subcommand that changes the repository (default: false). Only set this when the user asked for the change.",
+			},
+		},
+		Required: []string{"subcommand"},
+		Requires: []string{"git"},
+		ConfirmReason: func(input string) string {
+			var params gitParams
+			json.Unmarshal([]byte(input), &params)
+			if params.AllowWrite && params.writes() {
+				return "changes the git repository (git " + params.Subcommand + ")"
+			}
+			return ""
+		},
+	}
+}
+
+type gitParams struct {
+	Subcommand string   `json:"subcommand"`
+	Args       []string `json:"args"`
+	AllowWrite bool     `json:"allow_write"`
+}
+
+// writes reports whether the call could change the repository or write
+// files, judged by the subcommand and its arguments
+func (p gitParams) writes() bool {
+	if !slices.Contains(gitReadCommands, p.Subcommand) {
+		return true
+	}
+	for _, arg := range p.Args {
+		// diff, log, and show can write their output to a file
+		if arg == "--output" || strings.HasPrefix(arg, "--output=") {
+			return true
+		}
+	}
+	if p.Subcommand != "branch" {
+		return false
+	}
+
+	listing := false
+	for _, arg := range p.Args {
+		switch {
+		case slices.Contains(gitBranchWriteFlags, arg):
+			return true
+		case slices.Contains(gitBranchListFlags, arg):
+			listing = true
+		case !strings.HasPrefix(arg, "-") && !listing:
+			// "git branch name" creates a branch
+			return true
+		}
+	}
+	return false
+}
+
+// ExecuteGit runs a git subcommand, leading with the branch and tree state
+func ExecuteGit(ctx context.Context, input string) (string, error) {
+	var params gitParams
+	if err := json.Unmarshal([]byte(input), &params); err != nil {
+		return "", fmt.Errorf("invalid input: %w", err)
+	}
+	if !slices.Contains(gitReadCommands, params.Subcommand) && !slices.Contains(gitWriteCommands, params.Subcommand) {
+		return "", fmt.Errorf("unsupported subcommand %q (use one of %s)", params.Subcommand, strings.Join(gitReadCommands, ", "))
+	}
+	if params.writes() && !params.AllowWrite {
+		return "", fmt.Errorf("git %s %s can change the repository or write files; set allow_write only if the user asked for it", params.Subcommand, strings.Join(params.Args, " "))
+	}
+
+	ctx, cancel := proc.CommandContext(ctx)
+	defer cancel()
+
+	status, err := gitOutput(ctx, "status", "--porcelain", "-z")
+	if err != nil {
+		return "", fmt.Errorf("not inside a git repository: %w", err)
+	}
+	changes := parseGitStatus(status)
+	header := fmt.Sprintf("Branch: %s (%s)", gitBranch(ctx), changes.summary())
+
+	if params.Subcommand == "status" && len(params.Args) == 0 {
+		return header + "\n\n" + changes.String(), nil
+	}
+
+	// No pager or colors, and fail instead of waiting for credentials or a
+	// commit message in an editor
+	args := append([]string{"--no-pager", "-c", "color.ui=false", params.Subcommand}, params.Args...)
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Env = append(toolenv.FromContext(ctx), "GIT_TERMINAL_PROMPT=0", "GIT_EDITOR=true")
+	cmd.Dir = workDirFrom(ctx)
+	output, err := proc.RunCapped(ctx, cmd, newProgressBuffer(ctx))
+	if err != nil {
+		return "", proc.CommandError(ctx, err, output)
+	}
+	if output == "" {
+		output = proc.NoOutputNote
+	}
+	return header + "\n\n" + output, nil
+}
+
+// gitOutput runs a git query for the header, outside the recorded commands
+func gitOutput(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Env = toolenv.FromContext(ctx)
+	cmd.Dir = workDirFrom(ctx)
+	output, err := cmd.Output()
+	return string(output), err
+}
+
+// gitBranch names the checked-out branch, or the commit of a detached HEAD
+func gitBranch(ctx context.Context) string {
+	// symbolic-ref also works before the first commit
+	if branch, err := gitOutput(ctx, "symbolic-ref", "--short", "-q", "HEAD"); err == nil {
+		return strings.TrimSpace(branch)
+	}
+	if commit, err := gitOutput(ctx, "rev-parse", "--short", "HEAD"); err == nil {
+		return "detached at " + strings.TrimSpace(commit)
+	}
+	return "unknown"
+}
+
+// gitChange is one path from git status
+type gitChange struct {
+	path   string // With "old -> new" for renames and copies
+	status string // e.g. "modified"
+}
+
+// gitChanges groups the working tree state as git status shows it
+type gitChanges struct {
+	staged, unstaged, untracked, conflicted []gitChange
+}
+
+// gitStatusNames describes the porcelain status letters
+var gitStatusNames = map[byte]string{
+	'M': "modified",
+	'T': "type changed",
+	'A': "added",
+	'D': "deleted",
+	'R': "renamed",
+	'C': "copied",
+}
+
+// parseGitStatus reads the output of git status --porcelain -z: entries of
+// "XY path", where X is the index state and Y the working tree state, each
+// followed by the original path for renames and copies
+func parseGitStatus(output string) gitChanges {
+	var changes gitChanges
+	entries := strings.Split(strings.TrimSuffix(output, "\x00"), "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		x, y, path := entry[0], entry[1], entry[3:]
+		if (x == 'R' || x == 'C') && i+1 < len(entries) {
+			i++
+			path = entries[i] + " -> " + path
+		}
+
+		switch {
+		case x == '?' && y == '?':
+			changes.untracked = append(changes.untracked, gitChange{path: path})
+		case x == '!' && y == '!':
+			// Ignored files are only listed when asked for
+		case x == 'U' || y == 'U' || (x == 'A' && y == 'A') || (x == 'D' && y == 'D'):
+			changes.conflicted = append(changes.conflicted, gitChange{path: path, status: "both " + conflictKind(x, y)})
+		default:
+			if name, ok := gitStatusNames[x]; ok {
+				changes.staged = append(changes.staged, gitChange{path: path, status: name})
+			}
+			if name, ok := gitStatusNames[y]; ok {
+				changes.unstaged = append(changes.unstaged, gitChange{path: path, status: name})
+			}
+		}
+	}
+	return changes
+}
+
+// conflictKind describes an unmerged path's two sides
+func conflictKind(x, y byte) string {
+	switch {
+	case x == 'A' && y == 'A':
+		return "added"
+	case x == 'D' && y == 'D':
+		return "deleted"
+	case x == 'U' && y == 'U':
+		return "modified"
+	}
+	return "changed"
+}
+
+// summary is the tree state for the header, e.g. "dirty: 2 staged, 1 untracked"
+func (c gitChanges) summary() string {
+	var parts []string
+	for _, group := range []struct {
+		label   string
+		changes []gitChange
+	}{
+		{"conflicted", c.conflicted},
+		{"staged", c.staged},
+		{"unstaged", c.unstaged},
+		{"untracked", c.untracked},
+	} {
+		if len(group.changes) > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", len(group.changes), group.label))
+		}
+	}
+	if len(parts) == 0 {
+		return "clean"
+	}
+	return "dirty: " + strings.Join(parts, ", ")
+}
+
+// String lists the changes by group, like git status
+func (c gitChanges) String() string {
+	var output strings.Builder
+	for _, group := range []struct {
+		title   string
+		changes []gitChange
+	}{
+		{"Conflicts", c.conflicted},
+		{"Staged", c.staged},
+		{"Not staged", c.unstaged},
+		{"Untracked", c.untracked},
+	} {
+		if len(group.changes) == 0 {
+			continue
+		}
+		fmt.Fprintf(&output, "%s:\n", group.title)
+		for _, change := range group.changes {
+			if change.status == "" {
+				fmt.Fprintf(&output, "  %s\n", change.path)
+			} else {
+				fmt.Fprintf(&output, "  %s: %s\n", change.status, change.path)
+			}
+		}
+	}
+	if output.Len() == 0 {
+		return "Nothing to commit, working tree clean"
+	}
+	return strings.TrimRight(output.String(), "\n")
+}
@@ -0,0 +1,127 @@
+package tools
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"kilo/internal/ai"
+	"kilo/internal/proc"
+	"kilo/internal/toolenv"
+)
+
+// gpuQueryFields are the nvidia-smi --query-gpu fields, in CSV column order
+var gpuQueryFields = []string{
+	"index",
+	"name",
+	"utilization.gpu",
+	"utilization.memory",
+	"memory.used",
+	"memory.total",
+	"temperature.gpu",
+	"power.draw",
+	"power.limit",
+}
+
+// GPUMetrics holds structured metrics for a single GPU.
+// Fields the driver reports as unsupported or N/A are nil.
+type GPUMetrics struct {
+	Index           int      `json:"index"`
+	Name            string   `json:"name"`
+	UtilizationPct  *float64 `json:"utilization_pct"`
+	MemoryUtilPct   *float64 `json:"memory_utilization_pct"`
+	MemoryUsedMiB   *float64 `json:"memory_used_mib"`
+	MemoryTotalMiB  *float64 `json:"memory_total_mib"`
+	TemperatureC    *float64 `json:"temperature_c"`
+	PowerDrawWatts  *float64 `json:"power_draw_watts"`
+	PowerLimitWatts *float64 `json:"power_limit_watts"`
+}
+
+// GPUMetricsTool returns the structured GPU metrics tool definition
+func GPUMetricsTool() ai.Tool {
+	return ai.Tool{
+		Name:        "gpu_metrics",
+		Description: "Get structured per-GPU metrics (utilization, memory, temperature, power) as JSON. Prefer this over nvidia_smi when you need numbers rather than the full text report.",
+		Parameters:  map[string]any{},
+		Required:    []string{},
+		Requires:    []string{"nvidia-smi"},
+	}
+}
+
+// ExecuteGPUMetrics queries nvidia-smi in CSV mode and returns per-GPU metrics as JSON
+func ExecuteGPUMetrics(ctx context.Context, input string) (string, error) {
+	if !NvidiaSmiAvailable() {
+		return "", errNoNvidiaSmi
+	}
+	ctx, cancel := proc.CommandContext(ctx)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "nvidia-smi",
+		"--query-gpu="+strings.Join(gpuQueryFields, ","),
+		"--format=csv,noheader,nounits",
+	)
+	cmd.Env = toolenv.FromContext(ctx)
+	output, err := proc.RunCapped(ctx, cmd, nil)
+	if err != nil {
+		return "", proc.CommandError(ctx, err, output)
+	}
+
+	metrics, err := parseGPUMetrics(output)
+	if err != nil {
+		return "", err
+	}
+
+	result, err := json.MarshalIndent(metrics, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode metrics: %w", err)
+	}
+
+	return string(result), nil
+}
+
+// parseGPUMetrics parses nvidia-smi CSV output (noheader, nounits) into metrics
+func parseGPUMetrics(output string) ([]GPUMetrics, error) {
+	reader := csv.NewReader(strings.NewReader(strings.TrimSpace(output)))
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = len(gpuQueryFields)
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse nvidia-smi output: %w", err)
+	}
+
+	metrics := make([]GPUMetrics, 0, len(records))
+	for _, record := range records {
+		index, err := strconv.Atoi(strings.TrimSpace(record[0]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid GPU index %q: %w", record[0], err)
+		}
+
+		metrics = append(metrics, GPUMetrics{
+			Index:           index,
+			Name:            strings.TrimSpace(record[1]),
+			UtilizationPct:  parseGPUValue(record[2]),
+			MemoryUtilPct:   parseGPUValue(record[3]),
+			MemoryUsedMiB:   parseGPUValue(record[4]),
+			MemoryTotalMiB:  parseGPUValue(record[5]),
+			TemperatureC:    parseGPUValue(record[6]),
+			PowerDrawWatts:  parseGPUValue(record[7]),
+			PowerLimitWatts: parseGPUValue(record[8]),
+		})
+	}
+
+	return metrics, nil
+}
+
+// parseGPUValue converts a numeric CSV field, treating "[N/A]" and friends as missing
+func parseGPUValue(field string) *float64 {
+	value, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+	if err != nil {
+		return nil
+	}
+	return &value
+}
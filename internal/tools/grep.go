@@ -0,0 +1,199 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"kilo/internal/ai"
+	"kilo/internal/files"
+	"kilo/internal/proc"
+)
+
+const (
+	// maxGrepMatches caps the matching lines one grep call returns
+	maxGrepMatches = 100
+	// maxGrepLineLen shortens long matching lines, e.g. in minified files
+	maxGrepLineLen = 300
+	// maxGrepFileSize skips files too large to be source code
+	maxGrepFileSize = 1 << 20
+)
+
+// grepSkipDirs are dependency and build directories searched only when
+// named as the path, since they're rarely what Claude is looking for
+var grepSkipDirs = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+	".venv":        true,
+	"venv":         true,
+	"__pycache__":  true,
+	".tox":         true,
+	".next":        true,
+	"dist":         true,
+	"target":       true,
+}
+
+// GrepTool returns the grep tool definition
+func GrepTool() ai.Tool {
+	return ai.Tool{
+		Name:        "grep",
+		Description: "Search file contents for a regular expression (Go RE2 syntax), returning matching lines as path:line: text. Use this instead of grep or rg in bash. Searches the working directory recursively unless path is given, honoring .gitignore and skipping .git, dependency directories like node_modules and vendor, and binary files. Results stop after a fixed number of matches; narrow the pattern or path if they do.",
+		Parameters: map[string]any{
+			"pattern": map[string]any{
+				"type":        "string",
+				"description": "Regular expression to search for",
+			},
+			"path": map[string]any{
+				"type":        "string",
+				"description": "File or directory to search, relative to the working directory (default: .)",
+			},
+			"ignore_case": map[string]any{
+				"type":        "boolean",
+				"description": "Match case-insensitively (default: false)",
+			},
+		},
+		Required:       []string{"pattern"},
+		Idempotent:     true,
+		MaxOutputChars: maxGrepMatches * (maxGrepLineLen + 100),
+	}
+}
+
+// ExecuteGrep searches files under a path for lines matching a pattern
+func ExecuteGrep(ctx context.Context, input string) (string, error) {
+	var params struct {
+		Pattern    string `json:"pattern"`
+		Path       string `json:"path"`
+		IgnoreCase bool   `json:"ignore_case"`
+	}
+
+	if err := json.Unmarshal([]byte(input), &params); err != nil {
+		return "", fmt.Errorf("invalid input: %w", err)
+	}
+	if params.Pattern == "" {
+		return "", fmt.Errorf("pattern is required")
+	}
+	if params.Path == "" {
+		params.Path = "."
+	}
+
+	expr := params.Pattern
+	if params.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return "", fmt.Errorf("invalid pattern: %w", err)
+	}
+
+	root, err := resolveToolPath(ctx, params.Path)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return "", fmt.Errorf("failed to search %s: %w", params.Path, err)
+	}
+
+	ctx, cancel := proc.CommandContext(ctx)
+	defer cancel()
+
+	var output strings.Builder
+	matches, matchedFiles := 0, 0
+	truncated := false
+
+	// search appends a file's matches, reporting false once the cap is hit
+	search := func(path, display string) bool {
+		found, ok := grepFile(path, display, re, maxGrepMatches-matches, &output)
+		if found > 0 {
+			matches += found
+			matchedFiles++
+		}
+		if !ok {
+			truncated = true
+		}
+		return ok
+	}
+
+	if !info.IsDir() {
+		search(root, params.Path)
+	} else {
+		err = files.Walk(root, func(rel string, d fs.DirEntry) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if grepSkipDirs[d.Name()] {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			if info, err := d.Info(); err != nil || info.Size() > maxGrepFileSize {
+				return nil
+			}
+			path := filepath.Join(root, filepath.FromSlash(rel))
+			if !files.IsText(path) {
+				return nil
+			}
+			if !search(path, filepath.ToSlash(filepath.Join(params.Path, rel))) {
+				return fs.SkipAll
+			}
+			return nil
+		})
+		var timeout *proc.TimeoutError
+		if errors.As(context.Cause(ctx), &timeout) {
+			return "", fmt.Errorf("search %w; narrow the path", timeout)
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to search %s: %w", params.Path, err)
+		}
+	}
+
+	if matches == 0 {
+		return fmt.Sprintf("No matches for %s in %s", params.Pattern, params.Path), nil
+	}
+	if truncated {
+		fmt.Fprintf(&output, "... [stopped after %d matches; narrow the pattern or path to see the rest]", maxGrepMatches)
+	} else {
+		fmt.Fprintf(&output, "%d matches in %d files", matches, matchedFiles)
+	}
+	return output.String(), nil
+}
+
+// grepFile writes up to limit matching lines of the file at path, returning
+// how many it wrote and false if it stopped at the limit
+func grepFile(path, display string, re *regexp.Regexp, limit int, output *strings.Builder) (int, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, true
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxGrepFileSize)
+	found := 0
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if !re.MatchString(line) {
+			continue
+		}
+		if found == limit {
+			return found, false
+		}
+		if len(line) > maxGrepLineLen {
+			line = strings.ToValidUTF8(line[:maxGrepLineLen], "") + "…"
+		}
+		fmt.Fprintf(output, "%s:%d: %s\n", display, lineNo, line)
+		found++
+	}
+	return found, true
+}
@@ -0,0 +1,435 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"kilo/internal/storage"
+)
+
+// PathPolicy decides what happens to shell commands that modify critical paths
+type PathPolicy int
+
+const (
+	PathPolicyConfirm PathPolicy = iota // Ask the user before running (default)
+	PathPolicyBlock                     // Refuse to run
+	PathPolicyOff                       // No checks
+)
+
+// ParsePathPolicy parses "confirm", "block", or "off"; "" means confirm
+func ParsePathPolicy(s string) (PathPolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "confirm":
+		return PathPolicyConfirm, nil
+	case "block":
+		return PathPolicyBlock, nil
+	case "off":
+		return PathPolicyOff, nil
+	}
+	return PathPolicyConfirm, fmt.Errorf("unknown critical path policy %q (want confirm, block, or off)", s)
+}
+
+// criticalPath is a location whose modification can break the system or
+// leak credentials
+type criticalPath struct {
+	path    string
+	reason  string
+	subtree bool // Everything below path is critical too
+}
+
+// criticalPaths lists the protected locations. "~" is the home directory.
+func criticalPaths() []criticalPath {
+	paths := []criticalPath{
+		{"/", "the root filesystem", false},
+		{"/etc", "system configuration", true},
+		{"/boot", "the boot loader and kernels", true},
+		{"/bin", "system binaries", true},
+		{"/sbin", "system binaries", true},
+		{"/usr", "system programs and libraries", true},
+		{"/lib", "system libraries", true},
+		{"/lib64", "system libraries", true},
+		{"/var/lib", "system state such as package databases", true},
+		{"/dev", "device files", true},
+		{"/proc", "kernel interfaces", true},
+		{"/sys", "kernel interfaces", true},
+		{"~", "your home directory", false},
+		{"~/.ssh", "SSH keys and configuration", true},
+		{"~/.gnupg", "GPG keys", true},
+	}
+	if dir, err := storage.ConfigDir(); err == nil {
+		paths = append(paths, criticalPath{dir, "Kilo's configuration", true})
+	}
+	return paths
+}
+
+// harmlessDevices are device files that are routinely written to
+var harmlessDevices = map[string]bool{
+	"/dev/null": true, "/dev/stdout": true, "/dev/stderr": true, "/dev/tty": true,
+}
+
+// PathFinding describes one operation that targets a critical path
+type PathFinding struct {
+	Operation string // The command or redirect, e.g. "rm" or ">"
+	Path      string // The argument as written in the command
+	Reason    string // What lives there
+}
+
+func (f PathFinding) String() string {
+	return fmt.Sprintf("%s on %s (%s)", f.Operation, f.Path, f.Reason)
+}
+
+// describeFindings joins findings into one line for the user
+func describeFindings(findings []PathFinding) string {
+	parts := make([]string, len(findings))
+	for i, f := range findings {
+		parts[i] = f.String()
+	}
+	return strings.Join(parts, "; ")
+}
+
+// modifyingCommands maps commands that change the filesystem to which of
+// their arguments are written to
+var modifyingCommands = map[string]argSelector{
+	"rm": allArgs, "rmdir": allArgs, "unlink": allArgs, "shred": allArgs,
+	"mv": allArgs, "truncate": allArgs, "tee": allArgs, "touch": allArgs,
+	"mkdir": allArgs,
+	"cp":    lastArg, "ln": lastArg, "install": lastArg, "rsync": lastArg,
+	"chmod": skipFirstArg, "chown": skipFirstArg, "chgrp": skipFirstArg,
+	"dd":   ddOutput,
+	"sed":  sedInPlace,
+	"find": findDelete,
+}
+
+// commandWrappers run the command that follows them
+var commandWrappers = map[string]bool{
+	"sudo": true, "doas": true, "env": true, "nohup": true, "nice": true,
+	"time": true, "command": true, "exec": true, "xargs": true,
+}
+
+// argSelector picks the arguments a command writes to. args excludes the
+// command name; flags are still present.
+type argSelector func(args []string) []string
+
+func operands(args []string) []string {
+	var result []string
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			result = append(result, arg)
+		}
+	}
+	return result
+}
+
+func allArgs(args []string) []string {
+	return operands(args)
+}
+
+func lastArg(args []string) []string {
+	ops := operands(args)
+	if len(ops) == 0 {
+		return nil
+	}
+	return ops[len(ops)-1:]
+}
+
+func skipFirstArg(args []string) []string {
+	ops := operands(args)
+	if len(ops) == 0 {
+		return nil
+	}
+	return ops[1:]
+}
+
+func ddOutput(args []string) []string {
+	for _, arg := range args {
+		if target, ok := strings.CutPrefix(arg, "of="); ok {
+			return []string{target}
+		}
+	}
+	return nil
+}
+
+func sedInPlace(args []string) []string {
+	inPlace, hasScript := false, false
+	for _, arg := range args {
+		if arg == "-i" || strings.HasPrefix(arg, "-i") || strings.HasPrefix(arg, "--in-place") {
+			inPlace = true
+		}
+		if arg == "-e" || arg == "-f" {
+			hasScript = true
+		}
+	}
+	if !inPlace {
+		return nil
+	}
+	ops := operands(args)
+	if !hasScript && len(ops) > 0 {
+		ops = ops[1:] // The first operand is the script
+	}
+	return ops
+}
+
+func findDelete(args []string) []string {
+	deletes := false
+	for _, arg := range args {
+		if arg == "-delete" || arg == "-exec" || arg == "-execdir" {
+			deletes = true
+		}
+	}
+	if !deletes {
+		return nil
+	}
+	// Starting points come before the first expression
+	var starts []string
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") || arg == "(" || arg == "!" {
+			break
+		}
+		starts = append(starts, arg)
+	}
+	return starts
+}
+
+// CheckCriticalPaths is a best-effort scan of a shell command for operations
+// that modify critical system paths, however the command is phrased: through
+// sudo, in a pipeline, via a redirect, with ~ or $HOME. Reads are not flagged.
+// It can be fooled by indirection (variables, eval, scripts) and is a safety
+// net, not a sandbox.
+func CheckCriticalPaths(command string) []PathFinding {
+	critical := criticalPaths()
+	var findings []PathFinding
+
+	check := func(operation, arg string) {
+		if reason, ok := matchCritical(critical, arg); ok {
+			findings = append(findings, PathFinding{Operation: operation, Path: arg, Reason: reason})
+		}
+	}
+
+	for _, words := range splitCommands(command) {
+		// Redirects write to their target whatever the command is
+		var args []string
+		for i := 0; i < len(words); i++ {
+			word := words[i]
+			if op, target, ok := redirect(word); ok {
+				if target == "" && i+1 < len(words) {
+					i++
+					target = words[i]
+				}
+				if op != "<" {
+					check(op, target)
+				}
+				continue
+			}
+			args = append(args, word)
+		}
+
+		args = stripWrappers(args)
+		if len(args) == 0 {
+			continue
+		}
+
+		name := path.Base(args[0])
+		selector, ok := modifyingCommands[name]
+		if strings.HasPrefix(name, "mkfs") {
+			selector, ok = allArgs, true
+		}
+		if !ok {
+			continue
+		}
+		for _, target := range selector(args[1:]) {
+			check(name, target)
+		}
+		for _, arg := range args[1:] {
+			if arg == "--no-preserve-root" {
+				findings = append(findings, PathFinding{Operation: name, Path: arg, Reason: "disables rm's protection of /"})
+			}
+		}
+	}
+
+	return findings
+}
+
+// CheckCriticalPath reports whether a tool that writes to path (relative to
+// the working directory dir) would modify a critical path
+func CheckCriticalPath(operation, p, dir string) []PathFinding {
+	target := expandHome(p)
+	if !path.IsAbs(target) {
+		target = path.Join(dir, target)
+	}
+	if reason, ok := matchCritical(criticalPaths(), target); ok {
+		return []PathFinding{{Operation: operation, Path: p, Reason: reason}}
+	}
+	return nil
+}
+
+// matchCritical reports whether arg names a critical path or lies inside one
+func matchCritical(critical []criticalPath, arg string) (string, bool) {
+	target := expandHome(arg)
+	// A glob like /etc/* or /* affects its directory
+	for strings.HasSuffix(target, "/*") {
+		target = strings.TrimSuffix(target, "/*")
+		if target == "" {
+			target = "/"
+		}
+	}
+	if !path.IsAbs(target) {
+		return "", false
+	}
+	target = path.Clean(target)
+	if harmlessDevices[target] || strings.HasPrefix(target, "/dev/fd/") || strings.HasPrefix(target, "/dev/shm/") {
+		return "", false
+	}
+
+	for _, c := range critical {
+		protected := path.Clean(expandHome(c.path))
+		if target == protected {
+			return c.reason, true
+		}
+		if c.subtree && strings.HasPrefix(target, protected+"/") {
+			return c.reason, true
+		}
+	}
+	return "", false
+}
+
+// expandHome resolves ~, $HOME, and ${HOME} the way the shell would
+func expandHome(p string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return p
+	}
+	for _, prefix := range []string{"~", "$HOME", "${HOME}"} {
+		if p == prefix {
+			return home
+		}
+		if rest, ok := strings.CutPrefix(p, prefix+"/"); ok {
+			return home + "/" + rest
+		}
+	}
+	return p
+}
+
+// redirect recognizes output and input redirection words such as ">",
+// ">>", "2>", "&>", and ">/path". target is "" when it's the next word.
+func redirect(word string) (op, target string, ok bool) {
+	trimmed := strings.TrimLeft(word, "0123456789&")
+	for _, candidate := range []string{">>", ">|", ">", "<"} {
+		if rest, found := strings.CutPrefix(trimmed, candidate); found {
+			// >&2 duplicates a descriptor rather than naming a file
+			if strings.HasPrefix(rest, "&") {
+				return "", "", false
+			}
+			return candidate, rest, true
+		}
+	}
+	return "", "", false
+}
+
+// stripWrappers skips variable assignments and wrappers such as sudo to
+// find the command that actually runs
+func stripWrappers(args []string) []string {
+	for len(args) > 0 && (commandWrappers[path.Base(args[0])] || isAssignment(args[0])) {
+		args = args[1:]
+		// Wrapper options, e.g. sudo -u root
+		for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+			args = args[1:]
+		}
+	}
+	return args
+}
+
+// isAssignment reports whether word is a NAME=value prefix
+func isAssignment(word string) bool {
+	name, _, ok := strings.Cut(word, "=")
+	if !ok || name == "" {
+		return false
+	}
+	for i, r := range name {
+		if !(r == '_' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || i > 0 && r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// splitCommands splits a command line into simple commands at ;, &&, ||, |,
+// &, newlines, and parentheses, then into words, honoring quotes and
+// backslash escapes. Redirect operators are kept attached as their own words.
+func splitCommands(line string) [][]string {
+	var commands [][]string
+	var words []string
+	var word strings.Builder
+	inWord := false
+
+	endWord := func() {
+		if inWord {
+			words = append(words, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+	endCommand := func() {
+		endWord()
+		if len(words) > 0 {
+			commands = append(commands, words)
+			words = nil
+		}
+	}
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\' && i+1 < len(runes):
+			i++
+			word.WriteRune(runes[i])
+			inWord = true
+		case r == '\'':
+			end := indexRune(runes, i+1, '\'')
+			word.WriteString(string(runes[i+1 : end]))
+			i = end
+			inWord = true
+		case r == '"':
+			end := indexRune(runes, i+1, '"')
+			word.WriteString(string(runes[i+1 : end]))
+			i = end
+			inWord = true
+		case r == ';' || r == '|' || r == '\n' || r == '(' || r == ')' || r == '`':
+			endCommand()
+		case r == '&':
+			// &> and >& are redirects; anything else separates commands
+			if i+1 < len(runes) && runes[i+1] == '>' || inWord && strings.HasSuffix(word.String(), ">") {
+				word.WriteRune(r)
+				inWord = true
+				continue
+			}
+			endCommand()
+		case r == '>' || r == '<':
+			// Start a new word unless this continues a redirect like 2> or >>
+			if inWord && strings.TrimLeft(word.String(), "0123456789&><") != "" {
+				endWord()
+			}
+			word.WriteRune(r)
+			inWord = true
+		case r == ' ' || r == '\t':
+			endWord()
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	endCommand()
+	return commands
+}
+
+// indexRune finds r at or after start, or returns len(runes) if missing
+func indexRune(runes []rune, start int, r rune) int {
+	for i := start; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return len(runes)
+}
@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitCommands(t *testing.T) {
+	tests := []struct {
+		line string
+		want [][]string
+	}{
+		{"ls -la", [][]string{{"ls", "-la"}}},
+		{"ls; rm -rf ~", [][]string{{"ls"}, {"rm", "-rf", "~"}}},
+		{"make && make test || echo failed", [][]string{{"make"}, {"make", "test"}, {"echo", "failed"}}},
+		{"cat log | grep error &", [][]string{{"cat", "log"}, {"grep", "error"}}},
+		{"echo a\necho b", [][]string{{"echo", "a"}, {"echo", "b"}}},
+		{"(cd /tmp && ls)", [][]string{{"cd", "/tmp"}, {"ls"}}},
+		{"echo `whoami`", [][]string{{"echo"}, {"whoami"}}},
+		{`echo "a; b" 'c | d'`, [][]string{{"echo", "a; b", "c | d"}}},
+		{`echo a\;b`, [][]string{{"echo", "a;b"}}},
+		{`grep "unterminated`, [][]string{{"grep", "unterminated"}}},
+		{"echo hi >out.txt", [][]string{{"echo", "hi", ">out.txt"}}},
+		{"make 2>&1 >> build.log", [][]string{{"make", "2>&1", ">>", "build.log"}}},
+		{"cmd &> all.log", [][]string{{"cmd", "&>", "all.log"}}},
+		{"sort <in.txt", [][]string{{"sort", "<in.txt"}}},
+		{"  ;; ", nil},
+	}
+	for _, tt := range tests {
+		if got := splitCommands(tt.line); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitCommands(%q)\n got  %q\n want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestCheckCriticalPaths(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string // Operation and path of each finding
+	}{
+		{command: "ls /etc"},
+		{command: "cat /etc/passwd | grep root"},
+		{command: "rm -rf ./build"},
+		{command: "echo hi > /dev/null"},
+		{command: "rm -rf /", want: []string{"rm /"}},
+		{command: "sudo rm -rf /etc/nginx", want: []string{"rm /etc/nginx"}},
+		{command: "echo x > /etc/hosts", want: []string{"> /etc/hosts"}},
+		{command: "ls; chmod 777 /usr/bin/env", want: []string{"chmod /usr/bin/env"}},
+		{command: "cp id_rsa ~/.ssh/authorized_keys", want: []string{"cp ~/.ssh/authorized_keys"}},
+		{command: "sed -i s/a/b/ /etc/fstab", want: []string{"sed /etc/fstab"}},
+		{command: "find /usr -name '*.so' -delete", want: []string{"find /usr"}},
+		{command: "rm -rf --no-preserve-root /", want: []string{"rm --no-preserve-root", "rm /"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, f := range CheckCriticalPaths(tt.command) {
+			got = append(got, f.Operation+" "+f.Path)
+		}
+		if !sameFindings(got, tt.want) {
+			t.Errorf("CheckCriticalPaths(%q) = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+}
+
+// sameFindings compares findings ignoring order
+func sameFindings(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	seen := make(map[string]int)
+	for _, f := range got {
+		seen[f]++
+	}
+	for _, f := range want {
+		if seen[f] == 0 {
+			return false
+		}
+		seen[f]--
+	}
+	return true
+}
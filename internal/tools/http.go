@@ -0,0 +1,291 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"kilo/internal/ai"
+	"kilo/internal/proc"
+)
+
+// DefaultHTTPLimit caps the bytes of response body http_request returns
+const DefaultHTTPLimit = 32 * 1024
+
+// maxRedirects bounds how many redirects one request follows
+const maxRedirects = 5
+
+// blockedHosts are refused unless explicitly allowed: names for the local
+// machine and cloud metadata services. Private and loopback addresses are
+// refused separately, after DNS resolution.
+var blockedHosts = []string{"localhost", "*.localhost", "metadata.google.internal"}
+
+// httpConfig carries the executor's settings to http_request
+type httpConfig struct {
+	limit int      // Bytes of body returned per call
+	allow []string // If set, the only hosts that may be requested
+	deny  []string // Hosts refused on top of blockedHosts
+}
+
+type httpConfigKey struct{}
+
+func withHTTPConfig(ctx context.Context, config httpConfig) context.Context {
+	return context.WithValue(ctx, httpConfigKey{}, config)
+}
+
+func httpConfigFrom(ctx context.Context) httpConfig {
+	config, _ := ctx.Value(httpConfigKey{}).(httpConfig)
+	if config.limit <= 0 {
+		config.limit = DefaultHTTPLimit
+	}
+	return config
+}
+
+// HTTPTool returns the http_request tool definition
+func HTTPTool() ai.Tool {
+	return ai.Tool{
+		Name:        "http_request",
+		Description: "Make an HTTP request and return the status, key headers, and body (cut off at a size limit). Use this instead of curl in bash, e.g. to check whether a service responds or to read an API or web page. Requests to localhost, private networks, and cloud metadata endpoints are blocked unless the user has allowed them.",
+		Parameters: map[string]any{
+			"url": map[string]any{
+				"type":        "string",
+				"description": "Absolute http:// or https:// URL",
+			},
+			"method": map[string]any{
+				"type":        "string",
+				"enum":        []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"},
+				"description": "HTTP method (default: GET)",
+			},
+			"headers": map[string]any{
+				"type":                 "object",
+				"additionalProperties": map[string]any{"type": "string"},
+				"description":          "Request headers, e.g. {\"Accept\": \"application/json\"}",
+			},
+			"body": map[string]any{
+				"type":        "string",
+				"description": "Request body, for POST, PUT, and PATCH",
+			},
+		},
+		Required:       []string{"url"},
+		MaxOutputChars: DefaultHTTPLimit + 1024,
+		// Anything but a read can change state on the server
+		ConfirmReason: func(input string) string {
+			var params struct {
+				Method string `json:"method"`
+			}
+			json.Unmarshal([]byte(input), &params)
+			switch method := strings.ToUpper(params.Method); method {
+			case "", http.MethodGet, http.MethodHead:
+				return ""
+			default:
+				return "sends a " + method + " request"
+			}
+		},
+	}
+}
+
+// ExecuteHTTP performs an HTTP request within the host policy
+func ExecuteHTTP(ctx context.Context, input string) (string, error) {
+	var params struct {
+		URL     string            `json:"url"`
+		Method  string            `json:"method"`
+		Headers map[string]string `json:"headers"`
+		Body    string            `json:"body"`
+	}
+
+	if err := json.Unmarshal([]byte(input), &params); err != nil {
+		return "", fmt.Errorf("invalid input: %w", err)
+	}
+	method := strings.ToUpper(params.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	target, err := url.Parse(params.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid url: %w", err)
+	}
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return "", fmt.Errorf("url must start with http:// or https://")
+	}
+	config := httpConfigFrom(ctx)
+	if err := config.checkHost(target.Hostname()); err != nil {
+		return "", err
+	}
+
+	ctx, cancel := proc.CommandContext(ctx)
+	defer cancel()
+
+	var body io.Reader
+	if params.Body != "" {
+		body = strings.NewReader(params.Body)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, target.String(), body)
+	if err != nil {
+		return "", fmt.Errorf("invalid request: %w", err)
+	}
+	for name, value := range params.Headers {
+		request.Header.Set(name, value)
+	}
+	proc.RecordCommand(ctx, method+" "+target.String())
+
+	response, err := config.client().Do(request)
+	if err != nil {
+		var timeout *proc.TimeoutError
+		if errors.As(context.Cause(ctx), &timeout) {
+			return "", timeout
+		}
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	defer response.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(response.Body, int64(config.limit)+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var output strings.Builder
+	fmt.Fprintf(&output, "%s %s\n", response.Proto, response.Status)
+	for _, name := range []string{"Content-Type", "Content-Length", "Location"} {
+		if value := response.Header.Get(name); value != "" {
+			fmt.Fprintf(&output, "%s: %s\n", name, value)
+		}
+	}
+	if response.Request.URL.String() != target.String() {
+		fmt.Fprintf(&output, "Redirected to: %s\n", response.Request.URL)
+	}
+	output.WriteString("\n")
+
+	truncated := len(data) > config.limit
+	if truncated {
+		data = data[:config.limit]
+	}
+	switch {
+	case bytes.IndexByte(data, 0) >= 0:
+		output.WriteString("[binary body not shown]")
+		return output.String(), nil
+	case truncated:
+		// Don't leave half a character at the cut
+		for len(data) > 0 && !utf8.Valid(data) {
+			data = data[:len(data)-1]
+		}
+		output.Write(data)
+		fmt.Fprintf(&output, "\n... [body truncated at %d bytes]", config.limit)
+	default:
+		output.Write(data)
+	}
+	return output.String(), nil
+}
+
+// client makes requests that follow redirects and resolve names only
+// within the host policy
+func (c httpConfig) client() *http.Client {
+	dialer := &net.Dialer{}
+	// No proxy: the policy can only be enforced on connections made here.
+	// A client per call, so no keep-alives to clean up.
+	transport := &http.Transport{
+		DisableKeepAlives: true,
+		// Checking the address actually dialed, rather than the name, stops
+		// a public name that resolves to a private address
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			host, port, err := net.SplitHostPort(addr)
+			if err != nil {
+				return nil, err
+			}
+			if c.allowed(host) {
+				return dialer.DialContext(ctx, network, addr)
+			}
+			ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+			if err != nil {
+				return nil, err
+			}
+			for _, ip := range ips {
+				if blockedIP(ip.IP) {
+					return nil, fmt.Errorf("%s resolves to %s, a local or private address; ask the user to allow the host if this is intended", host, ip.IP)
+				}
+				if matchHosts(c.deny, ip.IP.String()) {
+					return nil, fmt.Errorf("%s resolves to %s, which is blocked", host, ip.IP)
+				}
+			}
+			return dialer.DialContext(ctx, network, net.JoinHostPort(ips[0].IP.String(), port))
+		},
+	}
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(request *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return c.checkHost(request.URL.Hostname())
+		},
+	}
+}
+
+// checkHost refuses hosts outside the allowlist, on the denylist, or naming
+// the local machine or a metadata service
+func (c httpConfig) checkHost(host string) error {
+	if host == "" {
+		return fmt.Errorf("url has no host")
+	}
+	if c.allowed(host) {
+		return nil
+	}
+	if len(c.allow) > 0 {
+		return fmt.Errorf("host %s is not in the allowed hosts (%s)", host, strings.Join(c.allow, ", "))
+	}
+	if matchHosts(c.deny, host) || matchHosts(blockedHosts, host) {
+		return fmt.Errorf("host %s is blocked", host)
+	}
+	if ip := net.ParseIP(host); ip != nil && blockedIP(ip) {
+		return fmt.Errorf("%s is a local or private address; ask the user to allow it if this is intended", host)
+	}
+	return nil
+}
+
+// allowed reports whether host is explicitly allowed, which also exempts it
+// from the local and private address checks
+func (c httpConfig) allowed(host string) bool {
+	return matchHosts(c.allow, host) && !matchHosts(c.deny, host)
+}
+
+// matchHosts reports whether host matches any pattern: an exact name or
+// address, "*.example.com" for its subdomains, or a CIDR range
+func matchHosts(patterns []string, host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	ip := net.ParseIP(host)
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if _, network, err := net.ParseCIDR(pattern); err == nil {
+			if ip != nil && network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+			if strings.HasSuffix(host, suffix) {
+				return true
+			}
+			continue
+		}
+		if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// blockedIP reports whether ip is loopback, private, link-local (which
+// includes the 169.254.169.254 metadata endpoint), or otherwise not a public
+// unicast address
+func blockedIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified()
+}
@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheckHost(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  httpConfig
+		host    string
+		wantErr bool
+	}{
+		{name: "public name", host: "example.com"},
+		{name: "public address", host: "93.184.216.34"},
+		{name: "no host", host: "", wantErr: true},
+		{name: "localhost", host: "localhost", wantErr: true},
+		{name: "localhost subdomain", host: "app.localhost", wantErr: true},
+		{name: "loopback", host: "127.0.0.1", wantErr: true},
+		{name: "IPv6 loopback", host: "::1", wantErr: true},
+		{name: "private network", host: "10.1.2.3", wantErr: true},
+		{name: "private network 192.168", host: "192.168.0.1", wantErr: true},
+		{name: "metadata address", host: "169.254.169.254", wantErr: true},
+		{name: "metadata name", host: "metadata.google.internal", wantErr: true},
+		{name: "unspecified", host: "0.0.0.0", wantErr: true},
+		{name: "denied", config: httpConfig{deny: []string{"*.example.com"}}, host: "api.example.com", wantErr: true},
+		{name: "allowed loopback", config: httpConfig{allow: []string{"127.0.0.1"}}, host: "127.0.0.1"},
+		{name: "allowed range", config: httpConfig{allow: []string{"10.0.0.0/8"}}, host: "10.1.2.3"},
+		{name: "outside the allowlist", config: httpConfig{allow: []string{"example.com"}}, host: "example.org", wantErr: true},
+		{name: "deny beats allow", config: httpConfig{allow: []string{"*.example.com"}, deny: []string{"internal.example.com"}}, host: "internal.example.com", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.checkHost(tt.host)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkHost(%q) = %v, want error %v", tt.host, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExecuteHTTPBlocksLocalAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "secret")
+	}))
+	defer server.Close()
+	input := fmt.Sprintf(`{"url":%q}`, server.URL)
+
+	if got, err := ExecuteHTTP(context.Background(), input); err == nil {
+		t.Errorf("request to %s succeeded: %q", server.URL, got)
+	}
+
+	// Allowing the host lets the request through
+	host, _, _ := strings.Cut(strings.TrimPrefix(server.URL, "http://"), ":")
+	ctx := withHTTPConfig(context.Background(), httpConfig{allow: []string{host}})
+	got, err := ExecuteHTTP(ctx, input)
+	if err != nil {
+		t.Fatalf("allowed request failed: %v", err)
+	}
+	if !strings.Contains(got, "200 OK") || !strings.HasSuffix(got, "secret") {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestExecuteHTTPBlocksRedirectsToLocalHosts(t *testing.T) {
+	server := httptest.NewServer(http.RedirectHandler("http://localhost/admin", http.StatusFound))
+	defer server.Close()
+
+	host, _, _ := strings.Cut(strings.TrimPrefix(server.URL, "http://"), ":")
+	ctx := withHTTPConfig(context.Background(), httpConfig{allow: []string{host}})
+	_, err := ExecuteHTTP(ctx, fmt.Sprintf(`{"url":%q}`, server.URL))
+	if err == nil || !strings.Contains(err.Error(), "host localhost is not in the allowed hosts") {
+		t.Errorf("redirect to localhost: err = %v", err)
+	}
+}
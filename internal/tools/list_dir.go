@@ -0,0 +1,192 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"kilo/internal/ai"
+)
+
+const (
+	// maxListEntries caps the entries one list_dir call returns
+	maxListEntries = 500
+	// maxListCount stops counting the entries left out of huge trees
+	maxListCount = 10000
+	// defaultListDepth is how deep a recursive listing goes unless told
+	defaultListDepth = 3
+)
+
+// ListDirTool returns the list_dir tool definition
+func ListDirTool() ai.Tool {
+	return ai.Tool{
+		Name:        "list_dir",
+		Description: "List a directory's entries with their types and sizes, optionally recursively as an indented tree. Use this instead of ls or find in bash. Directories end in /, symlinks show their target. Hidden entries are skipped unless show_hidden is set. Paths are relative to the working directory and may not leave it. Large listings are cut off with a count of what was left out.",
+		Parameters: map[string]any{
+			"path": map[string]any{
+				"type":        "string",
+				"description": "Directory to list, relative to the working directory (default: .)",
+			},
+			"recursive": map[string]any{
+				"type":        "boolean",
+				"description": "Also list subdirectories (default: false)",
+			},
+			"max_depth": map[string]any{
+				"type":        "integer",
+				"description": fmt.Sprintf("Levels to descend when recursive (default: %d)", defaultListDepth),
+			},
+			"show_hidden": map[string]any{
+				"type":        "boolean",
+				"description": "Include entries starting with a dot (default: false)",
+			},
+		},
+		Idempotent: true,
+	}
+}
+
+// dirLister accumulates a listing, counting what didn't fit
+type dirLister struct {
+	output     strings.Builder
+	showHidden bool
+	maxDepth   int
+	listed     int
+	omitted    int
+}
+
+// ExecuteListDir lists a directory as a tree with types and sizes
+func ExecuteListDir(ctx context.Context, input string) (string, error) {
+	var params struct {
+		Path       string `json:"path"`
+		Recursive  bool   `json:"recursive"`
+		MaxDepth   int    `json:"max_depth"`
+		ShowHidden bool   `json:"show_hidden"`
+	}
+
+	if err := json.Unmarshal([]byte(input), &params); err != nil {
+		return "", fmt.Errorf("invalid input: %w", err)
+	}
+	if params.Path == "" {
+		params.Path = "."
+	}
+	depth := 1
+	if params.Recursive {
+		depth = defaultListDepth
+		if params.MaxDepth > 0 {
+			depth = params.MaxDepth
+		}
+	}
+
+	dir, err := resolveToolPath(ctx, params.Path)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to list %s: %w", params.Path, err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("%s is not a directory; use read_file to read it", params.Path)
+	}
+
+	lister := &dirLister{showHidden: params.ShowHidden, maxDepth: depth}
+	fmt.Fprintf(&lister.output, "%s/\n", strings.TrimSuffix(filepath.ToSlash(params.Path), "/"))
+	if err := lister.list(ctx, dir, 1); err != nil {
+		return "", err
+	}
+
+	if lister.listed == 0 {
+		lister.output.WriteString("  (empty)\n")
+	}
+	switch {
+	case lister.omitted >= maxListCount:
+		fmt.Fprintf(&lister.output, "... %d+ more entries not shown; list a subdirectory or reduce max_depth\n", lister.omitted)
+	case lister.omitted > 0:
+		fmt.Fprintf(&lister.output, "... %d more entries not shown; list a subdirectory or reduce max_depth\n", lister.omitted)
+	}
+	return strings.TrimRight(lister.output.String(), "\n"), nil
+}
+
+// list writes the entries of dir at the given depth, descending into
+// subdirectories up to maxDepth
+func (l *dirLister) list(ctx context.Context, dir string, depth int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if depth == 1 {
+			return fmt.Errorf("failed to list %s: %w", dir, err)
+		}
+		fmt.Fprintf(&l.output, "%s[unreadable: %v]\n", strings.Repeat("  ", depth), err)
+		return nil
+	}
+
+	for _, entry := range entries {
+		if !l.showHidden && strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		if l.listed >= maxListEntries {
+			// Keep counting so the note says how much is missing
+			if l.omitted >= maxListCount {
+				return nil
+			}
+			l.omitted++
+		} else {
+			l.listed++
+			fmt.Fprintf(&l.output, "%s%s\n", strings.Repeat("  ", depth), describeEntry(dir, entry))
+		}
+
+		if entry.IsDir() && depth < l.maxDepth {
+			if err := l.list(ctx, filepath.Join(dir, entry.Name()), depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// describeEntry formats one entry: name plus a trailing slash, symlink
+// target, special file type, or size
+func describeEntry(dir string, entry fs.DirEntry) string {
+	name := entry.Name()
+	switch mode := entry.Type(); {
+	case mode.IsDir():
+		return name + "/"
+	case mode&fs.ModeSymlink != 0:
+		target, err := os.Readlink(filepath.Join(dir, name))
+		if err != nil {
+			return name + " -> ?"
+		}
+		return name + " -> " + target
+	case mode&fs.ModeNamedPipe != 0:
+		return name + "  [pipe]"
+	case mode&fs.ModeSocket != 0:
+		return name + "  [socket]"
+	case mode&fs.ModeDevice != 0:
+		return name + "  [device]"
+	}
+	info, err := entry.Info()
+	if err != nil {
+		return name
+	}
+	return name + "  " + formatSize(info.Size())
+}
+
+// formatSize renders a byte count in binary units, e.g. "1.5 KiB"
+func formatSize(bytes int64) string {
+	if bytes < 1024 {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	size := float64(bytes)
+	for _, unit := range []string{"KiB", "MiB", "GiB", "TiB"} {
+		size /= 1024
+		if size < 1024 || unit == "TiB" {
+			return fmt.Sprintf("%.1f %s", size, unit)
+		}
+	}
+	return ""
+}
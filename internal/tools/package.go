@@ -0,0 +1,194 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"kilo/internal/ai"
+	"kilo/internal/proc"
+	"kilo/internal/toolenv"
+)
+
+// packageNamePattern restricts package names to characters managers accept,
+// so the name can't be mistaken for a flag
+var packageNamePattern = regexp.MustCompile(`^[A-Za-z0-9@][A-Za-z0-9@._+:-]*$`)
+
+// PackageInfo is the normalized answer to "is this package installed?"
+type PackageInfo struct {
+	Manager   string `json:"manager"`
+	Package   string `json:"package"`
+	Installed bool   `json:"installed"`
+	Version   string `json:"version,omitempty"`
+}
+
+// packageManager knows how to query one package manager. query returns the
+// installed version, or ok=false when the package isn't installed.
+type packageManager struct {
+	name   string
+	binary string
+	goos   string // Only considered on this OS, "" for any
+	query  func(ctx context.Context, binary, pkg string) (version string, ok bool, err error)
+}
+
+// packageManagers are tried in order; the first found on PATH is used
+var packageManagers = []packageManager{
+	{name: "brew", binary: "brew", goos: "darwin", query: queryBrew},
+	{name: "dpkg", binary: "dpkg-query", goos: "linux", query: queryDpkg},
+	{name: "rpm", binary: "rpm", goos: "linux", query: queryRpm},
+	{name: "pacman", binary: "pacman", goos: "linux", query: queryPacman},
+	{name: "apk", binary: "apk", goos: "linux", query: queryApk},
+}
+
+// PackageTool returns the system package lookup tool definition
+func PackageTool() ai.Tool {
+	return ai.Tool{
+		Name:        "package",
+		Description: "Check whether a system package is installed and which version, using the OS package manager (dpkg, rpm, pacman, apk, or brew, detected automatically). Returns normalized JSON. Prefer this over bash for 'is X installed / what version' questions.",
+		Parameters: map[string]any{
+			"name": map[string]any{
+				"type":        "string",
+				"description": "Package name as the package manager knows it (e.g., 'curl', 'openssl', 'python3')",
+			},
+		},
+		Required: []string{"name"},
+	}
+}
+
+// ExecutePackage looks the package up with the detected package manager
+func ExecutePackage(ctx context.Context, input string) (string, error) {
+	var params struct {
+		Name string `json:"name"`
+	}
+
+	if err := json.Unmarshal([]byte(input), &params); err != nil {
+		return "", fmt.Errorf("invalid input: %w", err)
+	}
+	if !packageNamePattern.MatchString(params.Name) {
+		return "", fmt.Errorf("invalid package name %q", params.Name)
+	}
+
+	manager, binary, found := detectPackageManager()
+	if !found {
+		return "", fmt.Errorf("package manager not detected (looked for dpkg, rpm, pacman, apk, and brew on %s)", runtime.GOOS)
+	}
+
+	ctx, cancel := proc.CommandContext(ctx)
+	defer cancel()
+
+	version, installed, err := manager.query(ctx, binary, params.Name)
+	if err != nil {
+		return "", fmt.Errorf("%s query failed: %w", manager.name, err)
+	}
+
+	result, err := json.MarshalIndent(PackageInfo{
+		Manager:   manager.name,
+		Package:   params.Name,
+		Installed: installed,
+		Version:   version,
+	}, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode result: %w", err)
+	}
+	return string(result), nil
+}
+
+// detectPackageManager returns the first supported manager on PATH
+func detectPackageManager() (packageManager, string, bool) {
+	for _, manager := range packageManagers {
+		if manager.goos != "" && manager.goos != runtime.GOOS {
+			continue
+		}
+		if path, err := exec.LookPath(manager.binary); err == nil {
+			return manager, path, true
+		}
+	}
+	return packageManager{}, "", false
+}
+
+// runPackageQuery runs a package manager command. A non-zero exit is how
+// every supported manager reports "not installed", so it is returned as
+// ok=false rather than an error.
+func runPackageQuery(ctx context.Context, binary string, args ...string) (output string, ok bool, err error) {
+	cmd := exec.CommandContext(ctx, binary, args...)
+	cmd.Env = toolenv.FromContext(ctx)
+	output, err = proc.RunCapped(ctx, cmd, nil)
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && ctx.Err() == nil {
+		return output, false, nil
+	}
+	if err != nil {
+		return output, false, err
+	}
+	return output, true, nil
+}
+
+func queryDpkg(ctx context.Context, binary, pkg string) (string, bool, error) {
+	output, ok, err := runPackageQuery(ctx, binary, "-W", "-f=${db:Status-Status}\t${Version}\n", pkg)
+	if !ok || err != nil {
+		return "", false, err
+	}
+	// Removed packages can linger with status "config-files"
+	for _, line := range strings.Split(output, "\n") {
+		status, version, _ := strings.Cut(line, "\t")
+		if status == "installed" {
+			return version, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+func queryRpm(ctx context.Context, binary, pkg string) (string, bool, error) {
+	output, ok, err := runPackageQuery(ctx, binary, "-q", "--qf", "%{VERSION}-%{RELEASE}\n", pkg)
+	if !ok || err != nil {
+		return "", false, err
+	}
+	return firstLine(output), true, nil
+}
+
+func queryPacman(ctx context.Context, binary, pkg string) (string, bool, error) {
+	// Prints "name version"
+	output, ok, err := runPackageQuery(ctx, binary, "-Q", pkg)
+	if !ok || err != nil {
+		return "", false, err
+	}
+	fields := strings.Fields(firstLine(output))
+	if len(fields) < 2 {
+		return "", true, nil
+	}
+	return fields[1], true, nil
+}
+
+func queryApk(ctx context.Context, binary, pkg string) (string, bool, error) {
+	// Prints "name-version-rrelease" for installed packages only
+	output, ok, err := runPackageQuery(ctx, binary, "info", "-e", "-v", pkg)
+	if !ok || err != nil || output == "" {
+		return "", false, err
+	}
+	return strings.TrimPrefix(firstLine(output), pkg+"-"), true, nil
+}
+
+func queryBrew(ctx context.Context, binary, pkg string) (string, bool, error) {
+	// Prints "name version [version...]"; nothing and exit 1 when absent
+	output, ok, err := runPackageQuery(ctx, binary, "list", "--versions", pkg)
+	if !ok || err != nil || output == "" {
+		return "", false, err
+	}
+	fields := strings.Fields(firstLine(output))
+	if len(fields) < 2 {
+		return "", true, nil
+	}
+	return strings.Join(fields[1:], ", "), true, nil
+}
+
+// firstLine returns s up to its first newline
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return strings.TrimSpace(line)
+}
@@ -0,0 +1,119 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"kilo/internal/ai"
+	"kilo/internal/toolenv"
+)
+
+// ProcessEnvTool returns the process environment tool definition
+func ProcessEnvTool() ai.Tool {
+	return ai.Tool{
+		Name:        "process_env",
+		Description: "Show the environment variables and working directory of a running process by PID, to check what configuration a service actually has. Returns variable names only unless show_values is set; values that look like secrets are always redacted. Linux only.",
+		Parameters: map[string]any{
+			"pid": map[string]any{
+				"type":        "integer",
+				"description": "Process ID to inspect",
+			},
+			"show_values": map[string]any{
+				"type":        "boolean",
+				"description": "Include variable values (secrets redacted). Requires the user's approval.",
+			},
+		},
+		Required:   []string{"pid"},
+		Idempotent: true,
+		// Even redacted, values can leak configuration the user didn't
+		// mean to share, so revealing them is always confirmed
+		ConfirmReason: func(input string) string {
+			var params struct {
+				ShowValues bool `json:"show_values"`
+			}
+			if json.Unmarshal([]byte(input), &params) != nil || params.ShowValues {
+				return "reveals environment variable values"
+			}
+			return ""
+		},
+	}
+}
+
+// ExecuteProcessEnv reads a process's environment and working directory from procfs
+func ExecuteProcessEnv(ctx context.Context, input string) (string, error) {
+	var params struct {
+		PID        int  `json:"pid"`
+		ShowValues bool `json:"show_values"`
+	}
+
+	if err := json.Unmarshal([]byte(input), &params); err != nil {
+		return "", fmt.Errorf("invalid input: %w", err)
+	}
+	if params.PID <= 0 {
+		return "", fmt.Errorf("pid must be a positive integer")
+	}
+	if runtime.GOOS != "linux" {
+		return "", fmt.Errorf("process_env is not supported on %s (it reads /proc, which is Linux only)", runtime.GOOS)
+	}
+
+	dir := filepath.Join("/proc", strconv.Itoa(params.PID))
+	environ, err := os.ReadFile(filepath.Join(dir, "environ"))
+	if err != nil {
+		return "", procError(params.PID, err)
+	}
+
+	var output strings.Builder
+	comm, _ := os.ReadFile(filepath.Join(dir, "comm"))
+	fmt.Fprintf(&output, "PID %d (%s)\n", params.PID, strings.TrimSpace(string(comm)))
+
+	if cwd, err := os.Readlink(filepath.Join(dir, "cwd")); err == nil {
+		fmt.Fprintf(&output, "Working directory: %s\n", cwd)
+	} else {
+		fmt.Fprintf(&output, "Working directory: unavailable (%v)\n", procError(params.PID, err))
+	}
+
+	vars := parseEnviron(environ)
+	fmt.Fprintf(&output, "Environment (%d variables):\n", len(vars))
+	for _, kv := range vars {
+		name, value, _ := strings.Cut(kv, "=")
+		if params.ShowValues {
+			fmt.Fprintf(&output, "%s=%s\n", name, toolenv.Redact(name, value))
+		} else {
+			fmt.Fprintf(&output, "%s\n", name)
+		}
+	}
+
+	return strings.TrimSpace(output.String()), nil
+}
+
+// parseEnviron splits a NUL-separated /proc environ file, sorted by name
+func parseEnviron(data []byte) []string {
+	var vars []string
+	for _, kv := range strings.Split(string(data), "\x00") {
+		if kv != "" {
+			vars = append(vars, kv)
+		}
+	}
+	sort.Strings(vars)
+	return vars
+}
+
+// procError explains why a process couldn't be inspected
+func procError(pid int, err error) error {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("no process with PID %d", pid)
+	case errors.Is(err, fs.ErrPermission):
+		return fmt.Errorf("permission denied reading process %d (it likely belongs to another user)", pid)
+	}
+	return fmt.Errorf("failed to inspect process %d: %w", pid, err)
+}
@@ -0,0 +1,38 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+
+	"kilo/internal/ai"
+)
+
+// progressInterval throttles how often partial output is reported
+const progressInterval = 100 * time.Millisecond
+
+// progressBuffer collects command output and periodically reports everything
+// captured so far, so the UI can show long-running commands as they go
+type progressBuffer struct {
+	ctx        context.Context
+	mu         sync.Mutex
+	buf        bytes.Buffer
+	lastReport time.Time
+}
+
+func newProgressBuffer(ctx context.Context) *progressBuffer {
+	return &progressBuffer{ctx: ctx}
+}
+
+func (p *progressBuffer) Write(data []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	n, err := p.buf.Write(data)
+	if time.Since(p.lastReport) >= progressInterval {
+		p.lastReport = time.Now()
+		ai.ReportProgress(p.ctx, p.buf.String())
+	}
+	return n, err
+}
@@ -0,0 +1,79 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"kilo/internal/ai"
+	"kilo/internal/proc"
+	"kilo/internal/toolenv"
+)
+
+// PythonTool returns the python tool definition
+func PythonTool() ai.Tool {
+	return ai.Tool{
+		Name:        "python",
+		Description: "Run a short Python 3 script and return what it prints (stdout and stderr). Use this for calculations, parsing, or data analysis that would be awkward in bash. Each call runs in a fresh interpreter in the working directory, with only the standard library guaranteed; print the results you need.",
+		Parameters: map[string]any{
+			"code": map[string]any{
+				"type":        "string",
+				"description": "The Python source to run",
+			},
+		},
+		Required:  []string{"code"},
+		Requires:  []string{"python3"},
+		Dangerous: true,
+		Serial:    true,
+	}
+}
+
+// ExecutePython runs code with python3 from a temporary file
+func ExecutePython(ctx context.Context, input string) (string, error) {
+	var params struct {
+		Code string `json:"code"`
+	}
+
+	if err := json.Unmarshal([]byte(input), &params); err != nil {
+		return "", fmt.Errorf("invalid input: %w", err)
+	}
+	if params.Code == "" {
+		return "", fmt.Errorf("code is required")
+	}
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		return "", fmt.Errorf("python3 not found; install Python 3 or use another tool")
+	}
+
+	// A file rather than -c keeps line numbers in tracebacks meaningful
+	script, err := os.CreateTemp("", "kilo-*.py")
+	if err != nil {
+		return "", fmt.Errorf("failed to create script: %w", err)
+	}
+	defer os.Remove(script.Name())
+	_, err = script.WriteString(params.Code)
+	if closeErr := script.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write script: %w", err)
+	}
+
+	ctx, cancel := proc.CommandContext(ctx)
+	defer cancel()
+
+	// Unbuffered, so output streams to the UI as it's printed
+	cmd := exec.CommandContext(ctx, python, "-u", script.Name())
+	cmd.Env = toolenv.FromContext(ctx)
+	cmd.Dir = workDirFrom(ctx)
+	output, err := proc.RunCapped(ctx, cmd, newProgressBuffer(ctx))
+	if err != nil {
+		return "", proc.CommandError(ctx, err, output)
+	}
+	if output == "" {
+		return proc.NoOutputNote, nil
+	}
+	return output, nil
+}
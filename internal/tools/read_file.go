@@ -0,0 +1,200 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"kilo/internal/ai"
+	"kilo/internal/files"
+)
+
+// DefaultReadLimit caps the bytes of file content read_file returns
+const DefaultReadLimit = 32 * 1024
+
+// fileConfig carries the executor's settings to the file tools
+type fileConfig struct {
+	limit    int  // Bytes of content read_file returns per call
+	anywhere bool // Allow paths outside the working directory
+}
+
+type fileConfigKey struct{}
+
+func withFileConfig(ctx context.Context, config fileConfig) context.Context {
+	return context.WithValue(ctx, fileConfigKey{}, config)
+}
+
+func fileConfigFrom(ctx context.Context) fileConfig {
+	config, _ := ctx.Value(fileConfigKey{}).(fileConfig)
+	if config.limit <= 0 {
+		config.limit = DefaultReadLimit
+	}
+	return config
+}
+
+// ReadFileTool returns the read_file tool definition
+func ReadFileTool() ai.Tool {
+	return ai.Tool{
+		Name:        "read_file",
+		Description: "Read a text file, optionally a range of lines, with line numbers prefixed. Use this instead of cat in bash. Paths are relative to the working directory and may not leave it. Long files are cut off at a size limit; read further with start_line.",
+		Parameters: map[string]any{
+			"path": map[string]any{
+				"type":        "string",
+				"description": "File to read, relative to the working directory",
+			},
+			"start_line": map[string]any{
+				"type":        "integer",
+				"description": "First line to return, starting at 1 (default: 1)",
+			},
+			"end_line": map[string]any{
+				"type":        "integer",
+				"description": "Last line to return, inclusive (default: end of file)",
+			},
+		},
+		Required:   []string{"path"},
+		Idempotent: true,
+		// The executor's global cap is smaller than the read limit; the tool
+		// enforces its own limit with a note on where to continue
+		MaxOutputChars: 4 * DefaultReadLimit,
+	}
+}
+
+// ExecuteReadFile returns the requested lines of a file, numbered
+func ExecuteReadFile(ctx context.Context, input string) (string, error) {
+	var params struct {
+		Path      string `json:"path"`
+		StartLine int    `json:"start_line"`
+		EndLine   int    `json:"end_line"`
+	}
+
+	if err := json.Unmarshal([]byte(input), &params); err != nil {
+		return "", fmt.Errorf("invalid input: %w", err)
+	}
+	if params.Path == "" {
+		return "", fmt.Errorf("path is required")
+	}
+	if params.StartLine <= 0 {
+		params.StartLine = 1
+	}
+	if params.EndLine != 0 && params.EndLine < params.StartLine {
+		return "", fmt.Errorf("end_line %d is before start_line %d", params.EndLine, params.StartLine)
+	}
+
+	config := fileConfigFrom(ctx)
+	path, err := resolveToolPath(ctx, params.Path)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", params.Path, err)
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err == nil && info.IsDir() {
+		return "", fmt.Errorf("%s is a directory", params.Path)
+	}
+	// Catch binaries up front; readLines also rejects NULs further in
+	if !files.IsText(path) {
+		return "", fmt.Errorf("%s looks like a binary file", params.Path)
+	}
+
+	return readLines(bufio.NewReader(f), params.Path, params.StartLine, params.EndLine, config.limit)
+}
+
+// resolveToolPath makes path absolute against the tools' working directory
+// and, unless the file tools may go anywhere, checks that it stays inside
+// that directory once symlinks are followed. The path need not exist yet.
+func resolveToolPath(ctx context.Context, path string) (string, error) {
+	cwd, err := workDir(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(cwd, path)
+	}
+	path = filepath.Clean(path)
+	if fileConfigFrom(ctx).anywhere {
+		return path, nil
+	}
+
+	resolved := resolveExisting(path)
+	root := resolveExisting(cwd)
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside the working directory %s", path, cwd)
+	}
+	return resolved, nil
+}
+
+// resolveExisting follows symlinks in the longest existing prefix of path
+// and appends the rest unchanged
+func resolveExisting(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	parent := filepath.Dir(path)
+	if parent == path {
+		return path
+	}
+	return filepath.Join(resolveExisting(parent), filepath.Base(path))
+}
+
+// readLines numbers lines start..end (0 for the end of the file), stopping
+// once limit bytes of content have been collected
+func readLines(r *bufio.Reader, name string, start, end, limit int) (string, error) {
+	var output strings.Builder
+	used := 0
+	lineNo := 0
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if line == "" && err == io.EOF {
+			break
+		}
+		lineNo++
+
+		if strings.IndexByte(line, 0) >= 0 {
+			return "", fmt.Errorf("%s looks like a binary file", name)
+		}
+		if lineNo >= start && (end == 0 || lineNo <= end) {
+			if used+len(line) > limit {
+				if used > 0 {
+					fmt.Fprintf(&output, "[stopped at the %d byte limit; continue with start_line=%d]\n", limit, lineNo)
+					return output.String(), nil
+				}
+				// A single line longer than the limit is cut short, at a
+				// character boundary so the result stays valid UTF-8
+				cut := limit
+				for cut > 0 && !utf8.RuneStart(line[cut]) {
+					cut--
+				}
+				fmt.Fprintf(&output, "%6d\t%s\n", lineNo, line[:cut])
+				fmt.Fprintf(&output, "[line %d cut off at the %d byte limit]\n", lineNo, limit)
+				return output.String(), nil
+			}
+			used += len(line)
+			fmt.Fprintf(&output, "%6d\t%s\n", lineNo, strings.TrimRight(line, "\r\n"))
+		}
+		if err == io.EOF || (end != 0 && lineNo >= end) {
+			break
+		}
+	}
+
+	if lineNo == 0 {
+		return "(empty file)", nil
+	}
+	if lineNo < start {
+		return "", fmt.Errorf("start_line %d is past the end of %s (%d lines)", start, name, lineNo)
+	}
+	return output.String(), nil
+}
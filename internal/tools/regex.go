@@ -0,0 +1,97 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"kilo/internal/ai"
+)
+
+// maxRegexMatches bounds how many matches match_all reports
+const maxRegexMatches = 100
+
+// RegexTool returns the regex tester tool definition
+func RegexTool() ai.Tool {
+	return ai.Tool{
+		Name:        "regex",
+		Description: "Test a regular expression against sample text and report whether it matches, with captured groups. Uses Go's RE2 syntax: no lookahead/lookbehind and no backreferences, and matching is always linear time. Use this instead of guessing when helping debug a regex.",
+		Parameters: map[string]any{
+			"pattern": map[string]any{
+				"type":        "string",
+				"description": "The regular expression (RE2 syntax, e.g. '(\\w+)@example\\.com'). Use (?i) for case-insensitive, (?m) for multi-line",
+			},
+			"text": map[string]any{
+				"type":        "string",
+				"description": "The sample input to match against",
+			},
+			"match_all": map[string]any{
+				"type":        "boolean",
+				"description": "List every match instead of only the first (default: false)",
+			},
+		},
+		Required:   []string{"pattern", "text"},
+		Idempotent: true,
+	}
+}
+
+// ExecuteRegex compiles the pattern and reports matches and capture groups
+func ExecuteRegex(ctx context.Context, input string) (string, error) {
+	var params struct {
+		Pattern  string `json:"pattern"`
+		Text     string `json:"text"`
+		MatchAll bool   `json:"match_all"`
+	}
+
+	if err := json.Unmarshal([]byte(input), &params); err != nil {
+		return "", fmt.Errorf("invalid input: %w", err)
+	}
+
+	re, err := regexp.Compile(params.Pattern)
+	if err != nil {
+		// The syntax error names the offending construct, e.g. "invalid or
+		// unsupported Perl syntax: `(?=`" for lookahead
+		return "", fmt.Errorf("invalid pattern (Go RE2 syntax, no lookaround or backreferences): %w", err)
+	}
+
+	limit := 1
+	if params.MatchAll {
+		limit = maxRegexMatches
+	}
+	matches := re.FindAllStringSubmatchIndex(params.Text, limit)
+	if len(matches) == 0 {
+		return "No match", nil
+	}
+
+	var output strings.Builder
+	if params.MatchAll {
+		fmt.Fprintf(&output, "%d match(es)", len(matches))
+		if len(matches) == maxRegexMatches {
+			fmt.Fprintf(&output, " (stopped at %d)", maxRegexMatches)
+		}
+		output.WriteString("\n")
+	} else {
+		output.WriteString("Matched\n")
+	}
+
+	names := re.SubexpNames()
+	for i, match := range matches {
+		fmt.Fprintf(&output, "\nMatch %d at %d-%d: %q\n", i+1, match[0], match[1], params.Text[match[0]:match[1]])
+		for group := 1; group < len(names); group++ {
+			start, end := match[2*group], match[2*group+1]
+			label := fmt.Sprintf("  Group %d", group)
+			if names[group] != "" {
+				label += fmt.Sprintf(" (%s)", names[group])
+			}
+			if start < 0 {
+				fmt.Fprintf(&output, "%s: (did not participate)\n", label)
+				continue
+			}
+			fmt.Fprintf(&output, "%s: %q\n", label, params.Text[start:end])
+		}
+	}
+
+	return strings.TrimSpace(output.String()), nil
+}
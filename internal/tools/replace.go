@@ -0,0 +1,286 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"kilo/internal/ai"
+	"kilo/internal/diff"
+	"kilo/internal/files"
+	"kilo/internal/proc"
+)
+
+const (
+	// maxReplaceFileSize skips files too large to be source code; together
+	// with RE2's linear matching it bounds the work one file can cost
+	maxReplaceFileSize = 1 << 20
+
+	// maxReplaceFiles bounds how many files one call may change
+	maxReplaceFiles = 200
+)
+
+// ReplaceTool returns the replace tool definition
+func ReplaceTool() ai.Tool {
+	return ai.Tool{
+		Name:        "replace",
+		Description: "Apply a regular expression substitution to a file, or to every file matching a glob like \"internal/**/*.go\", for bulk edits such as renaming an identifier. Uses Go's RE2 syntax (no lookaround or backreferences in the pattern); the replacement may use $1 or ${name} for captured groups. Set dry_run to preview the unified diff without writing. For a single exact edit, prefer edit_file.",
+		Parameters: map[string]any{
+			"pattern": map[string]any{
+				"type":        "string",
+				"description": "The regular expression to replace, e.g. '\\bOldName\\b'. Use (?m) for ^ and $ at line boundaries",
+			},
+			"replacement": map[string]any{
+				"type":        "string",
+				"description": "The replacement text; $1, ${1}, or ${name} insert captured groups and $$ a literal $",
+			},
+			"path": map[string]any{
+				"type":        "string",
+				"description": "File to edit, or a glob relative to the working directory; ** matches any number of directories",
+			},
+			"count": map[string]any{
+				"type":        "integer",
+				"description": "Replace at most this many matches in each file, first to last (default: all)",
+			},
+			"dry_run": map[string]any{
+				"type":        "boolean",
+				"description": "Return the diff without changing any file (default: false)",
+			},
+		},
+		Required:       []string{"pattern", "replacement", "path"},
+		PathParam:      "path",
+		Dangerous:      true,
+		Serial:         true,
+		MaxOutputChars: 4 * DefaultReadLimit,
+	}
+}
+
+// replacedFile is a file whose content the substitution changes
+type replacedFile struct {
+	path    string // Absolute
+	display string // As shown to Claude, relative to the working directory
+	old     string
+	updated string
+	mode    fs.FileMode
+	count   int
+}
+
+// ExecuteReplace applies a regex substitution to the files path names. Every
+// file is checked before any is written, so an error leaves all untouched.
+func ExecuteReplace(ctx context.Context, input string) (string, error) {
+	var params struct {
+		Pattern     string `json:"pattern"`
+		Replacement string `json:"replacement"`
+		Path        string `json:"path"`
+		Count       int    `json:"count"`
+		DryRun      bool   `json:"dry_run"`
+	}
+
+	if err := json.Unmarshal([]byte(input), &params); err != nil {
+		return "", fmt.Errorf("invalid input: %w", err)
+	}
+	if params.Pattern == "" {
+		return "", fmt.Errorf("pattern is required")
+	}
+	if params.Path == "" {
+		return "", fmt.Errorf("path is required")
+	}
+	if params.Count < 0 {
+		return "", fmt.Errorf("count must be positive, got %d", params.Count)
+	}
+	re, err := regexp.Compile(params.Pattern)
+	if err != nil {
+		return "", fmt.Errorf("invalid pattern (Go RE2 syntax, no lookaround or backreferences): %w", err)
+	}
+
+	ctx, cancel := proc.CommandContext(ctx)
+	defer cancel()
+
+	paths, err := replaceTargets(ctx, params.Path)
+	var timeout *proc.TimeoutError
+	if errors.As(context.Cause(ctx), &timeout) {
+		return "", fmt.Errorf("listing files %w; narrow the glob", timeout)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var changed []replacedFile
+	for _, target := range paths {
+		if errors.As(context.Cause(ctx), &timeout) {
+			return "", fmt.Errorf("replace %w; narrow the glob", timeout)
+		}
+		file, err := replaceInFile(target[0], target[1], re, params.Replacement, params.Count)
+		if err != nil {
+			return "", err
+		}
+		if file.count == 0 {
+			continue
+		}
+		if len(changed) == maxReplaceFiles {
+			return "", fmt.Errorf("the substitution would change more than %d files; narrow the glob", maxReplaceFiles)
+		}
+		changed = append(changed, file)
+	}
+	if len(changed) == 0 {
+		return fmt.Sprintf("No matches for %s in %s", params.Pattern, params.Path), nil
+	}
+
+	if !params.DryRun {
+		for _, file := range changed {
+			if err := os.WriteFile(file.path, []byte(file.updated), file.mode); err != nil {
+				return "", fmt.Errorf("failed to write %s: %w", file.display, err)
+			}
+		}
+	}
+
+	total := 0
+	var patches strings.Builder
+	for _, file := range changed {
+		total += file.count
+		patches.WriteString(diff.Unified("a/"+file.display, "b/"+file.display, file.old, file.updated, diffContext))
+	}
+	verb := "Replaced"
+	if params.DryRun {
+		verb = "Dry run, nothing written: would replace"
+	}
+	return fmt.Sprintf("%s %d matches in %d files\n%s", verb, total, len(changed), patches.String()), nil
+}
+
+// replaceTargets resolves path to the files to edit, as pairs of absolute
+// path and display name. A path without glob characters is a single file.
+func replaceTargets(ctx context.Context, pattern string) ([][2]string, error) {
+	if !strings.ContainsAny(pattern, "*?[") {
+		abs, err := resolveToolPath(ctx, pattern)
+		if err != nil {
+			return nil, err
+		}
+		return [][2]string{{abs, pattern}}, nil
+	}
+
+	// Walk from the directories before the first glob segment
+	pattern = filepath.ToSlash(pattern)
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid glob %s: %w", pattern, err)
+	}
+	segments := strings.Split(pattern, "/")
+	fixed := 0
+	for fixed < len(segments)-1 && !strings.ContainsAny(segments[fixed], "*?[") {
+		fixed++
+	}
+	base := strings.Join(segments[:fixed], "/")
+	switch {
+	case base == "" && strings.HasPrefix(pattern, "/"):
+		base = "/"
+	case base == "":
+		base = "."
+	}
+	rest := segments[fixed:]
+	root, err := resolveToolPath(ctx, base)
+	if err != nil {
+		return nil, err
+	}
+
+	var targets [][2]string
+	err = files.Walk(root, func(rel string, d fs.DirEntry) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if grepSkipDirs[d.Name()] {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !matchGlob(rest, strings.Split(rel, "/")) {
+			return nil
+		}
+		if info, err := d.Info(); err != nil || info.Size() > maxReplaceFileSize {
+			return nil
+		}
+		abs := filepath.Join(root, filepath.FromSlash(rel))
+		if !files.IsText(abs) {
+			return nil
+		}
+		targets = append(targets, [2]string{abs, path.Join(base, rel)})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files for %s: %w", pattern, err)
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no text files match %s", pattern)
+	}
+	return targets, nil
+}
+
+// matchGlob matches path segments against glob segments, where "**" stands
+// for any number of directories and other segments follow path.Match
+func matchGlob(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchGlob(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	ok, _ := path.Match(pattern[0], segments[0])
+	return ok && matchGlob(pattern[1:], segments[1:])
+}
+
+// replaceInFile computes the substitution for one file without writing it.
+// limit <= 0 replaces every match.
+func replaceInFile(path, display string, re *regexp.Regexp, replacement string, limit int) (replacedFile, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return replacedFile{}, fmt.Errorf("failed to read %s: %w", display, err)
+	}
+	if info.IsDir() {
+		return replacedFile{}, fmt.Errorf("%s is a directory; use a glob such as %s/**/*.go", display, display)
+	}
+	if info.Size() > maxReplaceFileSize {
+		return replacedFile{}, fmt.Errorf("%s is larger than %d bytes", display, maxReplaceFileSize)
+	}
+	if !files.IsText(path) {
+		return replacedFile{}, fmt.Errorf("%s looks like a binary file", display)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return replacedFile{}, fmt.Errorf("failed to read %s: %w", display, err)
+	}
+
+	if limit <= 0 {
+		limit = -1
+	}
+	old := string(data)
+	matches := re.FindAllStringSubmatchIndex(old, limit)
+	var updated []byte
+	last := 0
+	for _, match := range matches {
+		updated = append(updated, old[last:match[0]]...)
+		updated = re.ExpandString(updated, replacement, old, match)
+		last = match[1]
+	}
+	updated = append(updated, old[last:]...)
+
+	file := replacedFile{path: path, display: display, old: old, updated: string(updated), mode: info.Mode().Perm()}
+	// Matches that replace text with itself change nothing
+	if file.updated != old {
+		file.count = len(matches)
+	}
+	return file, nil
+}
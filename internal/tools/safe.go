@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"strings"
+
+	"kilo/internal/ai"
+)
+
+// DefaultSafeCommands are the shell commands that run without confirmation
+// unless WithSafeCommands replaces them: reads with no side effects
+var DefaultSafeCommands = []string{
+	"ls", "pwd", "cat", "head", "wc", "whoami", "uname", "which",
+	"git status", "git diff", "git log",
+}
+
+// WithSafeCommands replaces DefaultSafeCommands: calls whose shell command
+// consists only of these commands, each given as its leading words such as
+// "ls" or "git status", may skip confirmation. No arguments disables it.
+func WithSafeCommands(prefixes ...string) Option {
+	return func(e *Executor) {
+		e.safe = append([]string{}, prefixes...)
+	}
+}
+
+// SafeCommands returns the commands that may skip confirmation
+func (e *Executor) SafeCommands() []string {
+	return e.safe
+}
+
+// IsSafeCommand reports whether call runs a shell command made up only of
+// safe commands, so it needn't be confirmed
+func (e *Executor) IsSafeCommand(call ai.ToolCall) bool {
+	command := e.Command(call)
+	return command != "" && MatchesSafeCommands(command, e.safe)
+}
+
+// MatchesSafeCommands reports whether every simple command in a shell
+// command line starts with the words of one of prefixes. Prefixes match
+// whole words, so "ls" allows "ls -la" but not "lsof", and each command
+// after ;, &&, or | must match on its own, so "ls; rm -rf ~" does not.
+// Anything whose effects can't be read off the words is refused: command
+// substitution, output redirects, and variable assignments.
+func MatchesSafeCommands(command string, prefixes []string) bool {
+	if len(prefixes) == 0 || strings.Contains(command, "$(") || strings.ContainsAny(command, "`") ||
+		strings.Contains(command, "<(") || strings.Contains(command, ">(") {
+		return false
+	}
+
+	var allowed [][]string
+	for _, prefix := range prefixes {
+		if words := strings.Fields(prefix); len(words) > 0 {
+			allowed = append(allowed, words)
+		}
+	}
+
+	commands := splitCommands(command)
+	if len(commands) == 0 {
+		return false
+	}
+	for _, words := range commands {
+		for _, word := range words {
+			if op, _, ok := redirect(word); ok && op != "<" {
+				return false
+			}
+			// git diff and git log can write their output to a file
+			if strings.HasPrefix(word, "--output") {
+				return false
+			}
+		}
+		if isAssignment(words[0]) || !matchesPrefix(words, allowed) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesPrefix reports whether words start with one of the prefixes
+func matchesPrefix(words []string, prefixes [][]string) bool {
+	for _, prefix := range prefixes {
+		if len(words) < len(prefix) {
+			continue
+		}
+		match := true
+		for i, word := range prefix {
+			if words[i] != word {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// ScreenRule is one pattern of shell command that bash refuses to run
+type ScreenRule struct {
+	Name   string                    // Short label, e.g. "fork bomb"
+	Reason string                    // Why it's refused, shown to Claude
+	Match  func(command string) bool // Reports whether the command is refused
+}
+
+// DefaultScreenRules are the commands refused unless configured otherwise:
+// recursive removal of / or the home directory, fork bombs, and dd onto a
+// disk
+func DefaultScreenRules() []ScreenRule {
+	return []ScreenRule{
+		{
+			Name:   "recursive remove of root or home",
+			Reason: "it would delete the root filesystem or your home directory",
+			Match:  removesRootOrHome,
+		},
+		{
+			Name:   "fork bomb",
+			Reason: "it spawns processes until the system runs out of resources",
+			Match:  isForkBomb,
+		},
+		{
+			Name:   "dd to a block device",
+			Reason: "it overwrites a disk or partition directly",
+			Match:  ddToDevice,
+		},
+	}
+}
+
+// ScreenCommand checks a shell command against DefaultScreenRules, returning
+// a refusal for a match. Like CheckCriticalPaths it's a safety net that
+// indirection can get past, not a sandbox.
+func ScreenCommand(cmd string) error {
+	return screenCommand(cmd, DefaultScreenRules())
+}
+
+func screenCommand(cmd string, rules []ScreenRule) error {
+	for _, rule := range rules {
+		if rule.Match(cmd) {
+			return fmt.Errorf("refused to run this command (%s): %s. Find a narrower way to do what the user asked, or ask them to run it themselves", rule.Name, rule.Reason)
+		}
+	}
+	return nil
+}
+
+// screenConfig holds the executor's rules. The zero value screens with
+// DefaultScreenRules.
+type screenConfig struct {
+	rules []ScreenRule
+	set   bool // rules replace the defaults, even when empty
+}
+
+func (c screenConfig) check(cmd string) error {
+	if !c.set {
+		return ScreenCommand(cmd)
+	}
+	return screenCommand(cmd, c.rules)
+}
+
+type screenConfigKey struct{}
+
+func withScreenConfig(ctx context.Context, config screenConfig) context.Context {
+	return context.WithValue(ctx, screenConfigKey{}, config)
+}
+
+func screenConfigFrom(ctx context.Context) screenConfig {
+	config, _ := ctx.Value(screenConfigKey{}).(screenConfig)
+	return config
+}
+
+// WithScreenRules replaces the rules bash commands are screened with.
+// Pass DefaultScreenRules() along with your own to extend them.
+func WithScreenRules(rules ...ScreenRule) Option {
+	return func(e *Executor) {
+		e.screen = screenConfig{rules: rules, set: true}
+	}
+}
+
+// WithoutScreening lets bash run any command; only confirmation and the
+// critical path policy still apply
+func WithoutScreening() Option {
+	return WithScreenRules()
+}
+
+// removesRootOrHome matches rm -r (however the flags are written) of /, /*,
+// or the home directory
+func removesRootOrHome(cmd string) bool {
+	home := path.Clean(expandHome("~"))
+	for _, words := range splitCommands(cmd) {
+		args := stripWrappers(words)
+		if len(args) == 0 || path.Base(args[0]) != "rm" {
+			continue
+		}
+		recursive := false
+		for _, arg := range args[1:] {
+			if arg == "--recursive" || strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--") && strings.ContainsAny(arg, "rR") {
+				recursive = true
+			}
+		}
+		if !recursive {
+			continue
+		}
+		for _, target := range operands(args[1:]) {
+			target = path.Clean(strings.TrimSuffix(expandHome(target), "*"))
+			if target == "/" || target == home {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// forkBomb matches a function that pipes into itself in the background,
+// like :(){ :|:& };:
+var forkBomb = regexp.MustCompile(`([\w:.]+)\s*\(\)\s*\{\s*([\w:.]+)\s*\|\s*([\w:.]+)\s*&`)
+
+// isForkBomb reports whether cmd defines a function that calls itself twice
+// through a background pipe
+func isForkBomb(cmd string) bool {
+	for _, m := range forkBomb.FindAllStringSubmatch(cmd, -1) {
+		if m[1] == m[2] && m[2] == m[3] {
+			return true
+		}
+	}
+	return false
+}
+
+// ddToDevice matches dd whose output file is a device other than the
+// harmless ones like /dev/null
+func ddToDevice(cmd string) bool {
+	for _, words := range splitCommands(cmd) {
+		args := stripWrappers(words)
+		if len(args) == 0 || path.Base(args[0]) != "dd" {
+			continue
+		}
+		for _, target := range ddOutput(args[1:]) {
+			target = path.Clean(target)
+			if strings.HasPrefix(target, "/dev/") && !harmlessDevices[target] &&
+				!strings.HasPrefix(target, "/dev/fd/") && !strings.HasPrefix(target, "/dev/shm/") {
+				return true
+			}
+		}
+	}
+	return false
+}
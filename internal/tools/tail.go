@@ -0,0 +1,300 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"kilo/internal/ai"
+	"kilo/internal/files"
+	"kilo/internal/proc"
+)
+
+const (
+	defaultTailLines = 20
+	maxTailLines     = 1000
+
+	// maxTailFollow caps follow_seconds; the command timeout may cut it
+	// shorter still
+	maxTailFollow = 120 * time.Second
+
+	// tailPollInterval is how often a followed file is checked for growth
+	tailPollInterval = 250 * time.Millisecond
+
+	// maxTailBytes caps everything the tool returns, initial lines included
+	maxTailBytes = DefaultReadLimit
+)
+
+// TailTool returns the tail tool definition
+func TailTool() ai.Tool {
+	return ai.Tool{
+		Name:        "tail",
+		Description: "Show the last lines of a file, such as a log, and optionally watch it for follow_seconds, returning the lines appended meanwhile. Use this instead of tail -f in bash, which never returns. Copes with the file being truncated or rotated while it's watched.",
+		Parameters: map[string]any{
+			"path": map[string]any{
+				"type":        "string",
+				"description": "File to read, relative to the working directory",
+			},
+			"lines": map[string]any{
+				"type":        "integer",
+				"description": fmt.Sprintf("How many of the last lines to show, 0 for none (default: %d, at most %d)", defaultTailLines, maxTailLines),
+			},
+			"follow_seconds": map[string]any{
+				"type":        "integer",
+				"description": fmt.Sprintf("Keep watching for this many seconds and return the new lines (default: 0, at most %d)", int(maxTailFollow.Seconds())),
+			},
+		},
+		Required:       []string{"path"},
+		MaxOutputChars: maxTailBytes + 1024,
+	}
+}
+
+// ExecuteTail returns the end of a file, then follows it for the requested
+// time. The follow stops early, keeping what it has, when the command
+// timeout or the byte cap is reached.
+func ExecuteTail(ctx context.Context, input string) (string, error) {
+	var params struct {
+		Path          string `json:"path"`
+		Lines         *int   `json:"lines"`
+		FollowSeconds int    `json:"follow_seconds"`
+	}
+
+	if err := json.Unmarshal([]byte(input), &params); err != nil {
+		return "", fmt.Errorf("invalid input: %w", err)
+	}
+	if params.Path == "" {
+		return "", fmt.Errorf("path is required")
+	}
+	lines := defaultTailLines
+	if params.Lines != nil {
+		lines = *params.Lines
+	}
+	if lines < 0 || lines > maxTailLines {
+		return "", fmt.Errorf("lines must be between 0 and %d, got %d", maxTailLines, lines)
+	}
+	if params.FollowSeconds < 0 {
+		return "", fmt.Errorf("follow_seconds must be positive, got %d", params.FollowSeconds)
+	}
+	follow := min(time.Duration(params.FollowSeconds)*time.Second, maxTailFollow)
+
+	path, err := resolveToolPath(ctx, params.Path)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", params.Path, err)
+	}
+	defer func() { f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", params.Path, err)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s is a directory", params.Path)
+	}
+	if !files.IsText(path) {
+		return "", fmt.Errorf("%s looks like a binary file", params.Path)
+	}
+
+	out := &tailOutput{}
+	last, err := lastLines(f, info.Size(), lines)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", params.Path, err)
+	}
+	out.write(last)
+	if follow == 0 {
+		if out.buf.Len() == 0 {
+			return "(empty file)", nil
+		}
+		return out.String(), nil
+	}
+
+	// Follow from the current end, within both the requested time and the
+	// command timeout
+	ctx, cancel := proc.CommandContext(ctx)
+	defer cancel()
+	ctx, stop := context.WithTimeout(ctx, follow)
+	defer stop()
+
+	started := time.Now()
+	offset := info.Size()
+	added := 0
+	var partial []byte
+	ticker := time.NewTicker(tailPollInterval)
+	defer ticker.Stop()
+
+	for !out.full() {
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		// A new file at the path means the old one was rotated away; read
+		// what's left of the old one first
+		if current, err := os.Stat(path); err == nil && !os.SameFile(info, current) {
+			if rest, err := readFrom(f, offset); err == nil {
+				added += out.appendLines(&partial, rest)
+			}
+			if next, err := os.Open(path); err == nil {
+				f.Close()
+				f, info, offset = next, current, 0
+				added += out.note(&partial, "file rotated, following the new one")
+			}
+		}
+
+		current, err := f.Stat()
+		if err != nil {
+			continue
+		}
+		if current.Size() < offset {
+			offset = 0
+			added += out.note(&partial, "file truncated, following from the start")
+		}
+		if current.Size() == offset {
+			continue
+		}
+		data, err := readFrom(f, offset)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", params.Path, err)
+		}
+		offset += int64(len(data))
+		added += out.appendLines(&partial, data)
+		ai.ReportProgress(ctx, out.String())
+	}
+	if len(partial) > 0 {
+		out.write(string(partial) + "\n")
+		added++
+	}
+
+	elapsed := time.Since(started).Round(time.Second)
+	var timeout *proc.TimeoutError
+	switch {
+	case out.full():
+		out.footer(fmt.Sprintf("[stopped after %s at the %d byte limit]", elapsed, maxTailBytes))
+	case errors.As(context.Cause(ctx), &timeout):
+		out.footer(fmt.Sprintf("[stopped after %s, %s; %d new lines]", elapsed, timeout, added))
+	default:
+		out.footer(fmt.Sprintf("[followed for %s; %d new lines]", elapsed, added))
+	}
+	return out.String(), nil
+}
+
+// tailOutput collects the tool's output up to maxTailBytes
+type tailOutput struct {
+	buf       strings.Builder
+	truncated bool
+}
+
+func (o *tailOutput) full() bool {
+	return o.truncated
+}
+
+// write appends text, cutting it at the last whole line within the cap
+func (o *tailOutput) write(text string) {
+	if o.truncated {
+		return
+	}
+	room := maxTailBytes - o.buf.Len()
+	if len(text) > room {
+		text = text[:room]
+		if i := strings.LastIndexByte(text, '\n'); i >= 0 {
+			text = text[:i+1]
+		} else {
+			text = ""
+		}
+		o.truncated = true
+	}
+	o.buf.WriteString(text)
+}
+
+// appendLines writes the complete lines of data, carrying an unfinished
+// last line over in partial, and returns how many lines it wrote
+func (o *tailOutput) appendLines(partial *[]byte, data []byte) int {
+	*partial = append(*partial, data...)
+	end := bytes.LastIndexByte(*partial, '\n')
+	if end < 0 {
+		return 0
+	}
+	complete := (*partial)[:end+1]
+	*partial = append([]byte(nil), (*partial)[end+1:]...)
+	o.write(string(bytes.ReplaceAll(complete, []byte{0}, nil)))
+	return bytes.Count(complete, []byte{'\n'})
+}
+
+// note marks an event in the output, flushing any unfinished line first,
+// and returns how many lines it flushed
+func (o *tailOutput) note(partial *[]byte, text string) int {
+	flushed := 0
+	if len(*partial) > 0 {
+		o.write(string(*partial) + "\n")
+		*partial = nil
+		flushed = 1
+	}
+	o.write("[" + text + "]\n")
+	return flushed
+}
+
+// footer appends a closing line, which the byte cap doesn't apply to
+func (o *tailOutput) footer(text string) {
+	o.buf.WriteString(text + "\n")
+}
+
+func (o *tailOutput) String() string {
+	return o.buf.String()
+}
+
+// lastLines returns the last n lines of a file of the given size, reading
+// backwards in blocks and no further than the output cap
+func lastLines(f *os.File, size int64, n int) (string, error) {
+	if n == 0 || size == 0 {
+		return "", nil
+	}
+	const block = 8 * 1024
+	var data []byte
+	offset := size
+	for offset > 0 && len(data) < maxTailBytes {
+		read := min(int64(block), offset)
+		offset -= read
+		chunk := make([]byte, read)
+		if _, err := f.ReadAt(chunk, offset); err != nil && err != io.EOF {
+			return "", err
+		}
+		data = append(chunk, data...)
+		// One more newline than lines wanted, ignoring a trailing one,
+		// means the n lines are all in data
+		if bytes.Count(bytes.TrimSuffix(data, []byte{'\n'}), []byte{'\n'}) >= n {
+			break
+		}
+	}
+
+	all := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if offset > 0 {
+		// Reading stopped partway into a line
+		all = all[1:]
+	}
+	if len(all) > n {
+		all = all[len(all)-n:]
+	}
+	if len(all) == 0 {
+		return "", nil
+	}
+	return strings.Join(all, "\n") + "\n", nil
+}
+
+// readFrom reads a file from offset to its current end
+func readFrom(f *os.File, offset int64) ([]byte, error) {
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(io.LimitReader(f, maxTailBytes))
+}
@@ -0,0 +1,109 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"kilo/internal/proc"
+)
+
+// writeLog creates app.log in a new directory with lines "line 1".."line n"
+// and returns a context whose tools work in that directory
+func writeLog(t *testing.T, n int) (context.Context, string) {
+	t.Helper()
+	dir := t.TempDir()
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, "line %d\n", i)
+	}
+	path := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return withWorkDir(context.Background(), dir), path
+}
+
+func TestExecuteTailLines(t *testing.T) {
+	ctx, _ := writeLog(t, 50)
+
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: `{"path":"app.log","lines":3}`, want: "line 48\nline 49\nline 50\n"},
+		{input: `{"path":"app.log","lines":1}`, want: "line 50\n"},
+		{input: `{"path":"app.log","lines":0}`, want: "(empty file)"},
+		{input: `{"path":"app.log","lines":500}`, want: "line 1\n"},
+		{input: `{"path":"app.log"}`, want: "line 31\n"},
+		{input: `{"path":"app.log","lines":-1}`, wantErr: true},
+		{input: `{"path":"app.log","lines":1001}`, wantErr: true},
+		{input: `{"path":"missing.log"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ExecuteTail(ctx, tt.input)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ExecuteTail(%s) = %q, want an error", tt.input, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ExecuteTail(%s): %v", tt.input, err)
+			continue
+		}
+		if !strings.HasPrefix(got, tt.want) {
+			t.Errorf("ExecuteTail(%s) = %q, want it to start with %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestExecuteTailFollow(t *testing.T) {
+	ctx, path := writeLog(t, 5)
+	ctx = proc.WithCommandTimeout(ctx, 10*time.Second)
+
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+		if err != nil {
+			return
+		}
+		defer f.Close()
+		f.WriteString("appended 1\nappended 2\n")
+	}()
+
+	started := time.Now()
+	got, err := ExecuteTail(ctx, `{"path":"app.log","lines":1,"follow_seconds":1}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(started); elapsed > 3*time.Second {
+		t.Errorf("followed for %s, want about 1s", elapsed)
+	}
+	want := "line 5\nappended 1\nappended 2\n[followed for 1s; 2 new lines]\n"
+	if got != want {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+}
+
+func TestExecuteTailFollowStopsAtCommandTimeout(t *testing.T) {
+	ctx, _ := writeLog(t, 5)
+	ctx = proc.WithCommandTimeout(ctx, 500*time.Millisecond)
+
+	started := time.Now()
+	got, err := ExecuteTail(ctx, `{"path":"app.log","lines":1,"follow_seconds":60}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(started); elapsed > 3*time.Second {
+		t.Errorf("followed for %s despite the 500ms command timeout", elapsed)
+	}
+	if !strings.HasPrefix(got, "line 5\n") || !strings.Contains(got, "timed out after 500ms; 0 new lines]") {
+		t.Errorf("got %q", got)
+	}
+}
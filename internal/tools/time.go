@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	// Embedded zone data, so timezone works on systems without zoneinfo
+	_ "time/tzdata"
+
+	"kilo/internal/ai"
+)
+
+// timeFormats are the layouts get_time's format parameter accepts
+var timeFormats = map[string]string{
+	"date":    "Mon Jan _2 15:04:05 MST 2006", // date(1)'s default
+	"rfc3339": time.RFC3339,
+	"iso8601": "2006-01-02T15:04:05.000Z07:00",
+	"human":   "Monday, January 2, 2006 at 3:04 PM MST",
+}
+
+// GetTimeTool returns the get_time tool definition
+func GetTimeTool() ai.Tool {
+	formats := []string{"unix"}
+	for name := range timeFormats {
+		formats = append(formats, name)
+	}
+	slices.Sort(formats)
+
+	return ai.Tool{
+		Name:        "get_time",
+		Description: "Get the current date, time, and time zone, locally or in another time zone",
+		Parameters: map[string]any{
+			"timezone": map[string]any{
+				"type":        "string",
+				"description": "IANA time zone name, e.g. \"America/New_York\", \"Europe/Berlin\", or \"UTC\" (default: the local time zone)",
+			},
+			"format": map[string]any{
+				"type":        "string",
+				"enum":        formats,
+				"description": "Output format (default: date, like the date command; unix is seconds since the epoch)",
+			},
+		},
+		Required:   []string{},
+		Idempotent: true,
+	}
+}
+
+// ExecuteGetTime returns the current time, in date(1)'s default format
+// unless another is asked for
+func ExecuteGetTime(ctx context.Context, input string) (string, error) {
+	var params struct {
+		Timezone string `json:"timezone"`
+		Format   string `json:"format"`
+	}
+	if input != "" {
+		if err := json.Unmarshal([]byte(input), &params); err != nil {
+			return "", fmt.Errorf("invalid input: %w", err)
+		}
+	}
+
+	now := time.Now()
+	if params.Timezone != "" {
+		location, err := time.LoadLocation(params.Timezone)
+		// LoadLocation also takes "Local" and file paths, which aren't zone names
+		if err != nil || params.Timezone == "Local" || strings.HasPrefix(params.Timezone, "/") {
+			return "", fmt.Errorf("unknown timezone %q; use an IANA name such as \"America/New_York\" or \"UTC\"", params.Timezone)
+		}
+		now = now.In(location)
+	}
+
+	switch params.Format {
+	case "", "date":
+		return now.Format(timeFormats["date"]) + "\n", nil
+	case "unix":
+		return strconv.FormatInt(now.Unix(), 10) + "\n", nil
+	}
+	layout, ok := timeFormats[params.Format]
+	if !ok {
+		return "", fmt.Errorf("unknown format %q (use date, rfc3339, iso8601, human, or unix)", params.Format)
+	}
+	return now.Format(layout) + "\n", nil
+}
@@ -2,35 +2,419 @@ package tools
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"kilo/internal/ai"
+	"kilo/internal/proc"
+	"kilo/internal/toolenv"
 )
 
+// DefaultMaxOutputChars caps tool output sent to Claude unless a tool
+// declares its own cap or WithMaxOutputChars changes it
+const DefaultMaxOutputChars = 5000
+
+// NoResultNote stands in for the empty result of a successful tool call
+// that doesn't run a command; those that do report proc.NoOutputNote
+const NoResultNote = "(succeeded with no output)"
+
 // Executor wraps the tool executor with all registered tools
 type Executor struct {
 	executor *ai.ToolExecutor
+	envAllow []string // Variables passed through to tool subprocesses
+	envExtra []string // KEY=VALUE pairs set for tool subprocesses
+	inherit  bool     // Pass Kilo's whole environment rather than envAllow
+	maxBytes int      // Output read from a subprocess before it is killed
+	paths    PathPolicy
+	files    fileConfig
+	http     httpConfig
+	timeout  time.Duration            // How long a tool's command may run
+	timeouts map[string]time.Duration // Per-tool overrides of timeout
+	output   int                      // Output cap for tools without their own, 0 for the default
+	outputs  map[string]int           // Per-tool overrides of the output cap
+	dir      string                   // Where tools run, "" for Kilo's working directory
+	screen   screenConfig             // Commands bash refuses to run
+	workers  int                      // Tool calls ExecuteAll runs at once
+	safe     []string                 // Shell commands that may skip confirmation
+}
+
+// Option configures an Executor
+type Option func(*Executor)
+
+// WithEnvAllowlist passes additional variables from Kilo's environment
+// through to tool subprocesses, on top of toolenv.DefaultAllowlist
+func WithEnvAllowlist(names ...string) Option {
+	return func(e *Executor) {
+		e.envAllow = append(e.envAllow, names...)
+	}
+}
+
+// WithEnv sets KEY=VALUE variables for tool subprocesses, such as a token
+// a CLI needs, on top of the variables they inherit. Each overrides only its
+// own key, and may extend the inherited value: PATH=/opt/tools/bin:$PATH.
+// Credential values (see toolenv.Sensitive) are redacted wherever the
+// resolved command is shown or saved.
+func WithEnv(vars ...string) Option {
+	return func(e *Executor) {
+		e.envExtra = append(e.envExtra, vars...)
+	}
+}
+
+// WithToolEnv runs tool subprocesses with Kilo's own environment instead of
+// the minimal one, overriding only the KEY=VALUE variables in env, which may
+// extend the inherited value as with WithEnv. The toolenv.Withheld variables
+// are still left out, so tools never see Kilo's API key.
+func WithToolEnv(env []string) Option {
+	return func(e *Executor) {
+		e.inherit = true
+		e.envExtra = append(e.envExtra, env...)
+	}
+}
+
+// WithCaptureLimit caps the bytes of output read from a tool subprocess;
+// a command that exceeds it is terminated. Defaults to proc.DefaultCaptureLimit.
+func WithCaptureLimit(bytes int) Option {
+	return func(e *Executor) {
+		e.maxBytes = bytes
+	}
+}
+
+// WithPathPolicy sets how shell commands that modify critical system paths
+// (/etc, ~/.ssh, ...) are handled. Defaults to PathPolicyConfirm.
+func WithPathPolicy(policy PathPolicy) Option {
+	return func(e *Executor) {
+		e.paths = policy
+	}
+}
+
+// WithReadLimit caps the bytes of file content one read_file call returns.
+// Defaults to DefaultReadLimit.
+func WithReadLimit(bytes int) Option {
+	return func(e *Executor) {
+		e.files.limit = bytes
+	}
+}
+
+// WithFilesAnywhere lets the file tools (read_file, write_file, edit_file,
+// replace, apply_patch) use paths outside the working directory
+func WithFilesAnywhere() Option {
+	return func(e *Executor) {
+		e.files.anywhere = true
+	}
+}
+
+// WithHTTPLimit caps the bytes of response body one http_request call
+// returns. Defaults to DefaultHTTPLimit.
+func WithHTTPLimit(bytes int) Option {
+	return func(e *Executor) {
+		e.http.limit = bytes
+	}
+}
+
+// WithHTTPAllow restricts http_request to the given hosts: names,
+// "*.example.com" patterns, or CIDR ranges. Allowed hosts may be local or
+// private, e.g. to let Claude check a service on localhost.
+func WithHTTPAllow(hosts ...string) Option {
+	return func(e *Executor) {
+		e.http.allow = append(e.http.allow, hosts...)
+	}
+}
+
+// WithHTTPDeny blocks http_request from the given hosts, in the same forms
+// as WithHTTPAllow, on top of local and private addresses
+func WithHTTPDeny(hosts ...string) Option {
+	return func(e *Executor) {
+		e.http.deny = append(e.http.deny, hosts...)
+	}
+}
+
+// WithTimeout sets how long a tool's command may run before it is killed.
+// Defaults to proc.DefaultCommandTimeout.
+func WithTimeout(d time.Duration) Option {
+	return func(e *Executor) {
+		e.timeout = d
+	}
+}
+
+// WithToolTimeout overrides the timeout for the named tool
+func WithToolTimeout(name string, d time.Duration) Option {
+	return func(e *Executor) {
+		if e.timeouts == nil {
+			e.timeouts = make(map[string]time.Duration)
+		}
+		e.timeouts[name] = d
+	}
 }
 
-// New creates a new tool executor with all built-in tools registered
-func New() *Executor {
+// WithMaxOutputChars sets how much of a tool's output is sent to Claude for
+// tools that don't declare their own MaxOutputChars. Defaults to
+// DefaultMaxOutputChars.
+func WithMaxOutputChars(chars int) Option {
+	return func(e *Executor) {
+		e.output = chars
+	}
+}
+
+// WithToolMaxOutputChars overrides the output cap for the named tool, in
+// place of the cap it declares
+func WithToolMaxOutputChars(name string, chars int) Option {
+	return func(e *Executor) {
+		if e.outputs == nil {
+			e.outputs = make(map[string]int)
+		}
+		e.outputs[name] = chars
+	}
+}
+
+// New creates a new tool executor with all built-in tools registered.
+// Tool subprocesses get a minimal environment unless WithToolEnv is given;
+// see toolenv.DefaultAllowlist.
+func New(opts ...Option) *Executor {
 	executor := ai.NewToolExecutor()
 
-	// Register all tools
-	executor.RegisterTool("bash", ExecuteBash)
-	executor.RegisterTool("nvidia_smi", ExecuteNvidiaSmi)
+	// Each definition is registered with its handler, so the tools offered
+	// to Claude are exactly the tools that can run
+	executor.Register(BashTool(), ExecuteBash)
+	executor.Register(GetTimeTool(), ExecuteGetTime)
+	if NvidiaSmiAvailable() {
+		executor.Register(NvidiaSmiTool(), ExecuteNvidiaSmi)
+		executor.Register(GPUMetricsTool(), ExecuteGPUMetrics)
+	}
+	executor.Register(ChartTool(), ExecuteChart)
+	executor.Register(ProcessEnvTool(), ExecuteProcessEnv)
+	executor.Register(RegexTool(), ExecuteRegex)
+	executor.Register(PackageTool(), ExecutePackage)
+	executor.Register(ReadFileTool(), ExecuteReadFile)
+	executor.Register(TailTool(), ExecuteTail)
+	executor.Register(WriteFileTool(), ExecuteWriteFile)
+	executor.Register(EditFileTool(), ExecuteEditFile)
+	executor.Register(ReplaceTool(), ExecuteReplace)
+	executor.Register(ApplyPatchTool(), ExecuteApplyPatch)
+	executor.Register(HTTPTool(), ExecuteHTTP)
+	executor.Register(GrepTool(), ExecuteGrep)
+	executor.Register(ListDirTool(), ExecuteListDir)
+	executor.Register(GitTool(), ExecuteGit)
+	executor.Register(PythonTool(), ExecutePython)
 
-	return &Executor{executor: executor}
+	e := &Executor{
+		executor: executor,
+		envAllow: append([]string(nil), toolenv.DefaultAllowlist...),
+		safe:     append([]string(nil), DefaultSafeCommands...),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e
 }
 
-// Execute runs a tool
-func (e *Executor) Execute(ctx context.Context, toolCall ai.ToolCall) (string, error) {
-	return e.executor.Execute(ctx, toolCall)
+// environment returns the variables tool subprocesses run with
+func (e *Executor) environment() []string {
+	if e.inherit {
+		return toolenv.Inherit(os.Environ(), e.envExtra)
+	}
+	return toolenv.Build(os.Environ(), e.envAllow, e.envExtra)
+}
+
+// Execute runs a tool and caps its output. Failures are reported in the
+// result content so they can be fed back to Claude.
+func (e *Executor) Execute(ctx context.Context, toolCall ai.ToolCall) ai.ToolResult {
+	ctx = toolenv.WithEnv(ctx, e.environment())
+	if e.maxBytes > 0 {
+		ctx = proc.WithCaptureLimit(ctx, e.maxBytes)
+	}
+	ctx = withFileConfig(ctx, e.files)
+	ctx = withHTTPConfig(ctx, e.http)
+	ctx = withWorkDir(ctx, e.dir)
+	ctx = withScreenConfig(ctx, e.screen)
+	ctx = proc.WithCommandTimeout(ctx, e.Timeout(toolCall.Name))
+
+	// Record what actually ran, which may differ from the model's input
+	var commands []string
+	ctx = proc.WithCommandRecorder(ctx, func(command string) {
+		commands = append(commands, command)
+	})
+
+	if e.paths == PathPolicyBlock {
+		if findings := e.criticalPathFindings(toolCall); len(findings) > 0 {
+			return ai.ToolResult{Content: "Error: blocked because the command modifies critical paths: " + describeFindings(findings), IsError: true}
+		}
+	}
+
+	output, err := e.executor.Execute(ctx, toolCall)
+	command := strings.Join(commands, "\n")
+
+	var abort *ai.AbortError
+	if errors.As(err, &abort) {
+		result := capOutput(abort.Message, e.MaxOutputChars(toolCall.Name))
+		result.Aborted = true
+		result.Command = command
+		return result
+	}
+	if err != nil {
+		output = fmt.Sprintf("Error: %v", err)
+	} else if strings.TrimSpace(output) == "" {
+		// Claude can take an empty result for a failed call
+		output = NoResultNote
+		if e.Command(toolCall) != "" {
+			output = proc.NoOutputNote
+		}
+	}
+
+	result := capOutput(output, e.MaxOutputChars(toolCall.Name))
+	result.Command = command
+	result.IsError = err != nil
+	return result
+}
+
+// Timeout returns how long the named tool's command may run
+func (e *Executor) Timeout(name string) time.Duration {
+	if timeout, ok := e.timeouts[name]; ok {
+		return timeout
+	}
+	if e.timeout > 0 {
+		return e.timeout
+	}
+	return proc.DefaultCommandTimeout
+}
+
+// MaxOutputChars returns how much of the named tool's output is sent to
+// Claude: its override, else the cap the tool declares, else the global one
+func (e *Executor) MaxOutputChars(name string) int {
+	if chars, ok := e.outputs[name]; ok && chars > 0 {
+		return chars
+	}
+	if tool, ok := e.executor.Tool(name); ok && tool.MaxOutputChars > 0 {
+		return tool.MaxOutputChars
+	}
+	if e.output > 0 {
+		return e.output
+	}
+	return DefaultMaxOutputChars
+}
+
+// capOutput truncates output to limit characters, recording what was cut
+func capOutput(output string, limit int) ai.ToolResult {
+	result := ai.ToolResult{
+		Content:       output,
+		OriginalChars: len(output),
+		MaxChars:      limit,
+	}
+	if len(output) > limit {
+		result.Content = truncateMiddle(output, limit)
+		result.Truncated = true
+		result.Full = output
+	}
+	return result
+}
+
+// truncateMiddle cuts s to about limit bytes by keeping its start and end,
+// where a command's summary or error usually is, with a marker saying how
+// much of the middle was left out. Cuts fall on line breaks when one is
+// near, and never inside a UTF-8 character.
+func truncateMiddle(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	head := limit / 2
+	tail := len(s) - (limit - head)
+
+	if i := strings.LastIndexByte(s[:head], '\n'); i >= head/2 {
+		head = i + 1
+	}
+	for head > 0 && !utf8.RuneStart(s[head]) {
+		head--
+	}
+	if i := strings.IndexByte(s[tail:], '\n'); i >= 0 && i < (len(s)-tail)/2 {
+		tail += i + 1
+	}
+	for tail < len(s) && !utf8.RuneStart(s[tail]) {
+		tail++
+	}
+
+	start := s[:head]
+	if !strings.HasSuffix(start, "\n") {
+		start += "\n"
+	}
+	return start + fmt.Sprintf("... [output truncated: %d of %d bytes omitted from the middle] ...\n", tail-head, len(s)) + s[tail:]
 }
 
 // GetAvailableTools returns all available tools for Claude
 func (e *Executor) GetAvailableTools() []ai.Tool {
-	return []ai.Tool{
-		BashTool(),
-		NvidiaSmiTool(),
+	return e.executor.GetAvailableTools()
+}
+
+// ConfirmReason explains why this particular call always needs the user's
+// approval, regardless of the confirmation setting, or returns ""
+func (e *Executor) ConfirmReason(call ai.ToolCall) string {
+	if e.paths == PathPolicyConfirm {
+		if findings := e.criticalPathFindings(call); len(findings) > 0 {
+			return "modifies critical paths: " + describeFindings(findings)
+		}
+	}
+	if tool, ok := e.executor.Tool(call.Name); ok && tool.ConfirmReason != nil {
+		return tool.ConfirmReason(call.Input)
+	}
+	return ""
+}
+
+// criticalPathFindings vets the shell command of tools that run one
+func (e *Executor) criticalPathFindings(call ai.ToolCall) []PathFinding {
+	if e.paths == PathPolicyOff {
+		return nil
+	}
+	tool, ok := e.executor.Tool(call.Name)
+	if !ok || (tool.CommandParam == "" && tool.PathParam == "") {
+		return nil
 	}
+	var input map[string]any
+	if err := json.Unmarshal([]byte(call.Input), &input); err != nil {
+		return nil
+	}
+	if tool.PathParam != "" {
+		path, _ := input[tool.PathParam].(string)
+		return CheckCriticalPath(tool.Name, path, e.WorkingDir())
+	}
+	command, _ := input[tool.CommandParam].(string)
+	return CheckCriticalPaths(command)
+}
+
+// RequiresConfirmation reports whether calls to the named tool should be
+// approved by the user before they run
+func (e *Executor) RequiresConfirmation(name string) bool {
+	tool, ok := e.executor.Tool(name)
+	return ok && tool.RequiresConfirmation()
+}
+
+// Command returns the shell command a call would run, or "" if the tool
+// doesn't run one
+func (e *Executor) Command(call ai.ToolCall) string {
+	tool, ok := e.executor.Tool(call.Name)
+	if !ok || tool.CommandParam == "" {
+		return ""
+	}
+	var input map[string]any
+	if err := json.Unmarshal([]byte(call.Input), &input); err != nil {
+		return ""
+	}
+	command, _ := input[tool.CommandParam].(string)
+	return command
+}
+
+// Concurrent reports whether calls to the named tool may run alongside
+// other calls
+func (e *Executor) Concurrent(name string) bool {
+	tool, ok := e.executor.Tool(name)
+	return ok && !tool.Serial
+}
+
+// IsIdempotent reports whether the named tool is safe to re-run
+func (e *Executor) IsIdempotent(name string) bool {
+	tool, ok := e.executor.Tool(name)
+	return ok && tool.Idempotent
 }
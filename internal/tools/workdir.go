@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+type workDirKey struct{}
+
+func withWorkDir(ctx context.Context, dir string) context.Context {
+	return context.WithValue(ctx, workDirKey{}, dir)
+}
+
+// workDirFrom returns the working directory set on the executor, or "" for
+// Kilo's own. Commands run with it as cmd.Dir.
+func workDirFrom(ctx context.Context) string {
+	dir, _ := ctx.Value(workDirKey{}).(string)
+	return dir
+}
+
+// workDir returns the directory relative paths are resolved against
+func workDir(ctx context.Context) (string, error) {
+	if dir := workDirFrom(ctx); dir != "" {
+		return dir, nil
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get working directory: %w", err)
+	}
+	return cwd, nil
+}
+
+// ValidateWorkingDir expands ~, makes dir absolute, and checks that it is an
+// existing directory
+func ValidateWorkingDir(dir string) (string, error) {
+	abs, err := filepath.Abs(expandHome(dir))
+	if err != nil {
+		return "", fmt.Errorf("invalid working directory %s: %w", dir, err)
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return "", fmt.Errorf("invalid working directory: %w", err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("invalid working directory: %s is not a directory", abs)
+	}
+	return abs, nil
+}
+
+// WithWorkingDir runs tools in dir instead of Kilo's working directory:
+// commands start there, and the file tools resolve relative paths against
+// it and stay inside it. Check dir with ValidateWorkingDir first.
+func WithWorkingDir(dir string) Option {
+	return func(e *Executor) {
+		e.dir = dir
+	}
+}
+
+// WorkingDir returns the directory tools run in
+func (e *Executor) WorkingDir() string {
+	if e.dir != "" {
+		return e.dir
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "."
+	}
+	return cwd
+}
+
+// SetWorkingDir changes the directory tools run in, like cd: a relative dir
+// is resolved against the current one. It must not be called while a tool
+// is running.
+func (e *Executor) SetWorkingDir(dir string) error {
+	if dir = expandHome(dir); !filepath.IsAbs(dir) {
+		dir = filepath.Join(e.WorkingDir(), dir)
+	}
+	dir, err := ValidateWorkingDir(dir)
+	if err != nil {
+		return err
+	}
+	e.dir = dir
+	return nil
+}
@@ -0,0 +1,100 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"kilo/internal/ai"
+	"kilo/internal/diff"
+)
+
+// diffContext is how many unchanged lines surround each change in the
+// diffs the file tools return
+const diffContext = 3
+
+// WriteFileTool returns the write_file tool definition
+func WriteFileTool() ai.Tool {
+	return ai.Tool{
+		Name:        "write_file",
+		Description: "Create a file with the given content, creating parent directories as needed. Refuses to replace an existing file unless overwrite is true; to change part of a file use edit_file instead. Paths are relative to the working directory and may not leave it. Returns a unified diff of the change.",
+		Parameters: map[string]any{
+			"path": map[string]any{
+				"type":        "string",
+				"description": "File to write, relative to the working directory",
+			},
+			"content": map[string]any{
+				"type":        "string",
+				"description": "The complete new contents of the file",
+			},
+			"overwrite": map[string]any{
+				"type":        "boolean",
+				"description": "Replace the file if it already exists (default: false)",
+			},
+		},
+		Required:       []string{"path", "content"},
+		PathParam:      "path",
+		Dangerous:      true,
+		Serial:         true,
+		MaxOutputChars: 4 * DefaultReadLimit,
+	}
+}
+
+// ExecuteWriteFile creates or, with overwrite, replaces a file
+func ExecuteWriteFile(ctx context.Context, input string) (string, error) {
+	var params struct {
+		Path      string `json:"path"`
+		Content   string `json:"content"`
+		Overwrite bool   `json:"overwrite"`
+	}
+
+	if err := json.Unmarshal([]byte(input), &params); err != nil {
+		return "", fmt.Errorf("invalid input: %w", err)
+	}
+	if params.Path == "" {
+		return "", fmt.Errorf("path is required")
+	}
+
+	path, err := resolveToolPath(ctx, params.Path)
+	if err != nil {
+		return "", err
+	}
+
+	var old string
+	mode := os.FileMode(0o644)
+	info, err := os.Stat(path)
+	switch {
+	case err == nil && info.IsDir():
+		return "", fmt.Errorf("%s is a directory", params.Path)
+	case err == nil && !params.Overwrite:
+		return "", fmt.Errorf("%s already exists; set overwrite to true to replace it, or use edit_file", params.Path)
+	case err == nil:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", params.Path, err)
+		}
+		old = string(data)
+		mode = info.Mode().Perm()
+	case !os.IsNotExist(err):
+		return "", fmt.Errorf("failed to write %s: %w", params.Path, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create directory for %s: %w", params.Path, err)
+	}
+	if err := os.WriteFile(path, []byte(params.Content), mode); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", params.Path, err)
+	}
+
+	oldName := "a/" + params.Path
+	if info == nil {
+		oldName = "/dev/null"
+	}
+	patch := diff.Unified(oldName, "b/"+params.Path, old, params.Content, diffContext)
+	if patch == "" {
+		return fmt.Sprintf("Wrote %s (unchanged)", params.Path), nil
+	}
+	return fmt.Sprintf("Wrote %s\n%s", params.Path, patch), nil
+}
@@ -0,0 +1,668 @@
+package tui
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"kilo/internal/ai"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// defaultMaxToolIterations bounds how many rounds of tool calls one turn may
+// make, unless changed with WithMaxToolIterations
+const defaultMaxToolIterations = 5
+
+// defaultTurnBudget bounds the wall-clock time of one turn, excluding time
+// spent waiting on the user to confirm a tool call, unless changed with
+// WithTurnBudget
+const defaultTurnBudget = 60 * time.Second
+
+// minRequestTime is the least remaining budget worth starting another API
+// call with; below it the turn ends with what the tools found so far
+const minRequestTime = 5 * time.Second
+
+// turnWarning is how close to the budget the UI starts suggesting Esc
+const turnWarning = 15 * time.Second
+
+// turn tracks the agent loop for a single user message. The loop is driven by
+// messages: each API call and each tool execution is its own command, so the
+// loop can pause between steps (e.g. to ask the user for confirmation).
+type turn struct {
+	ctx       context.Context
+	cancel    context.CancelFunc
+	started   time.Time
+	paused    time.Duration // Time spent waiting on the user
+	pausedAt  time.Time
+	waiting   bool          // Currently paused
+	budget    time.Duration // Wall-clock time the turn may take
+	first     int           // Index in the conversation of the turn's first message
+	iteration int
+	pending   []ai.ToolCall  // Tool calls from the latest response not yet run
+	retry     string         // Retry or rate-limit wait in progress, if any
+	tokens    int            // Output tokens of the turn's completed API calls
+	trimmed   bool           // Older history was left out of the latest request
+	running   string         // The tool executing now and its input, e.g. "bash (ls -la)"
+	tools     sync.WaitGroup // Tool executions in flight, waited on at shutdown
+}
+
+type assistantTurnMsg struct {
+	turn     *turn
+	response *ai.Response
+}
+
+// streamDeltaMsg carries newly generated text of the response in progress
+type streamDeltaMsg struct {
+	text   string
+	stream *responseStream
+}
+
+// streamRetryMsg reports that the API call failed transiently and is being
+// retried
+type streamRetryMsg struct {
+	status ai.RetryStatus
+	stream *responseStream
+}
+
+// streamThrottleMsg reports that the API call is held back by rate limiting
+type streamThrottleMsg struct {
+	wait   time.Duration
+	stream *responseStream
+}
+
+// responseStream is an in-flight streamed API call
+type responseStream struct {
+	turn   *turn
+	events <-chan ai.StreamEvent
+	cancel context.CancelFunc // Releases the call's timeout once it ends
+}
+
+// turnTickMsg redraws the elapsed-time indicator while a turn runs
+type turnTickMsg struct {
+	turn *turn
+}
+
+type toolResultMsg struct {
+	turn   *turn
+	call   ai.ToolCall
+	result ai.ToolResult
+}
+
+// toolBatchMsg carries the results of tool calls that ran together, in the
+// order Claude made the calls
+type toolBatchMsg struct {
+	turn    *turn
+	calls   []ai.ToolCall
+	results []ai.ToolResult
+}
+
+type toolProgressMsg struct {
+	turn   *turn
+	callID string
+	output string
+	events <-chan tea.Msg
+}
+
+// liveOutput is the in-place region showing a running tool's output. Each
+// progress update replaces it rather than appending to the conversation.
+type liveOutput struct {
+	callID string
+	output string
+}
+
+// maxLiveLines bounds how much of a running tool's output is shown
+const maxLiveLines = 10
+
+// view returns the tail of the live output, applying carriage returns the
+// way a terminal would so progress bars overwrite themselves
+func (l *liveOutput) view() string {
+	lines := strings.Split(strings.TrimRight(l.output, "\n"), "\n")
+	for i, line := range lines {
+		if idx := strings.LastIndex(line, "\r"); idx >= 0 {
+			lines[i] = line[idx+1:]
+		}
+	}
+	if len(lines) > maxLiveLines {
+		lines = lines[len(lines)-maxLiveLines:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func newTurn(budget time.Duration, first int) *turn {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &turn{ctx: ctx, cancel: cancel, started: time.Now(), budget: budget, first: first}
+}
+
+// elapsed returns how much of the turn's time budget has been used
+func (t *turn) elapsed() time.Duration {
+	paused := t.paused
+	if t.waiting {
+		paused += time.Since(t.pausedAt)
+	}
+	return time.Since(t.started) - paused
+}
+
+// remaining returns how much of the turn's time budget is left
+func (t *turn) remaining() time.Duration {
+	return t.budget - t.elapsed()
+}
+
+func (t *turn) pause() {
+	t.pausedAt = time.Now()
+	t.waiting = true
+}
+
+func (t *turn) resume() {
+	t.paused += time.Since(t.pausedAt)
+	t.waiting = false
+}
+
+// startTurn begins the agent loop for the latest user message
+func (m *model) startTurn() tea.Cmd {
+	// The turn starts with the user message just added
+	m.turn = newTurn(m.turnBudget, len(m.messages)-1)
+	m.thinking = true
+	return tea.Batch(m.requestResponse(), turnTick(m.turn), m.spinner.Tick)
+}
+
+// turnTick schedules the next redraw of the elapsed-time indicator
+func turnTick(t *turn) tea.Cmd {
+	return tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return turnTickMsg{turn: t}
+	})
+}
+
+// handleTurnTick redraws the indicator until the turn it belongs to ends
+func (m model) handleTurnTick(msg turnTickMsg) (tea.Model, tea.Cmd) {
+	if msg.turn != m.turn {
+		return m, nil
+	}
+	// Keep the scroll position: the user may be reading earlier output
+	m.viewport.SetContent(m.conversationView())
+	return m, turnTick(m.turn)
+}
+
+// cancelTurn stops the in-flight turn at the user's request (Esc or Ctrl+R).
+// Results from commands still running are dropped when they arrive; tool
+// results already recorded are kept.
+func (m model) cancelTurn() (tea.Model, tea.Cmd) {
+	// Claude requires every tool call to have a result
+	if last := m.messages[len(m.messages)-1]; last.Role == "assistant" && last.ToolCallID != "" {
+		m.messages = append(m.messages, ai.Message{
+			Role:         "tool",
+			Content:      "Cancelled by the user before the tool finished.",
+			ToolCallID:   last.ToolCallID,
+			ToolCallName: last.ToolCallName,
+			ToolError:    true,
+		})
+	}
+	// Keep whatever part of the answer had already arrived
+	if m.streaming != "" {
+		m.messages = append(m.messages, ai.Message{
+			Role:    "assistant",
+			Content: m.streaming,
+		})
+	}
+	m.endTurn()
+	m.confirming = nil
+	m.editingInput = false
+	m.editor.Blur()
+	m.input.Focus()
+	m.messages = append(m.messages, ai.Message{
+		Role:    "system",
+		Content: "Cancelled.",
+	})
+	m.notice = "Request cancelled"
+	m.autosave()
+	// Let the user decide whether the follow-up still makes sense
+	if m.queued != "" && strings.TrimSpace(m.input.Value()) == "" {
+		m.input.SetValue(m.queued)
+		m.queued = ""
+	}
+	m.refreshViewport()
+	return m, nil
+}
+
+// thinkingStatus describes the turn's progress against its time budget
+func (m model) thinkingStatus() (status string, nearDeadline bool) {
+	name := m.client.AssistantName()
+	if m.turn == nil {
+		return name + " is thinking...", false
+	}
+	elapsed := m.turn.elapsed().Truncate(time.Second)
+	status = fmt.Sprintf("%s is working… %s/%s", name, elapsed, m.turn.budget)
+	if m.streaming != "" {
+		status += fmt.Sprintf(" · ~%s tokens", formatTokens(m.turn.tokens+ai.EstimateTokens(m.streaming)))
+	} else if m.turn.tokens > 0 {
+		status += fmt.Sprintf(" · %s tokens", formatTokens(m.turn.tokens))
+	}
+	if m.turn.trimmed {
+		status += " · older history trimmed"
+	}
+	if m.turn.retry != "" {
+		status += " · " + m.turn.retry
+	}
+	return status, m.turn.remaining() <= turnWarning
+}
+
+// endTurn releases the current turn's resources
+func (m *model) endTurn() {
+	if m.turn != nil {
+		m.turn.cancel()
+		m.turn = nil
+	}
+	m.live = nil
+	m.streaming = ""
+	m.thinking = false
+}
+
+// requestResponse asks Claude for the next step of the current turn
+func (m model) requestResponse() tea.Cmd {
+	t := m.turn
+	messages := append([]ai.Message(nil), ai.TrimHistory(m.messages, m.historyBudget)...)
+	t.trimmed = len(messages) < len(m.messages)
+	tools := m.executor.GetAvailableTools()
+	remaining := t.remaining()
+
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(t.ctx, remaining)
+
+		events, err := m.provider.StreamMessageWithTools(ctx, messages, tools, m.requestOptions(ctx)...)
+		if err != nil {
+			cancel()
+			return streamFailed(t, err)
+		}
+		return readStream(&responseStream{turn: t, events: events, cancel: cancel})()
+	}
+}
+
+// readStream delivers the next piece of a streamed response: a text delta,
+// or once the stream ends, the complete turn or the error that stopped it
+func readStream(stream *responseStream) tea.Cmd {
+	return func() tea.Msg {
+		for event := range stream.events {
+			switch event.Type {
+			case ai.StreamTextDelta:
+				return streamDeltaMsg{text: event.Text, stream: stream}
+			case ai.StreamRetry:
+				return streamRetryMsg{status: *event.Retry, stream: stream}
+			case ai.StreamThrottled:
+				return streamThrottleMsg{wait: event.Wait, stream: stream}
+			case ai.StreamDone:
+				stream.cancel()
+				return assistantTurnMsg{turn: stream.turn, response: event.Response}
+			case ai.StreamError:
+				stream.cancel()
+				return streamFailed(stream.turn, event.Err)
+			}
+			// Tool calls are handled from the complete response
+		}
+		stream.cancel()
+		return nil
+	}
+}
+
+// turnOf returns the turn a message from the agent loop belongs to, and
+// whether msg is one at all
+func turnOf(msg tea.Msg) (*turn, bool) {
+	switch msg := msg.(type) {
+	case responseMsg:
+		return msg.turn, true
+	case assistantTurnMsg:
+		return msg.turn, true
+	case streamDeltaMsg:
+		return msg.stream.turn, true
+	case streamRetryMsg:
+		return msg.stream.turn, true
+	case streamThrottleMsg:
+		return msg.stream.turn, true
+	case toolProgressMsg:
+		return msg.turn, true
+	case toolResultMsg:
+		return msg.turn, true
+	case toolBatchMsg:
+		return msg.turn, true
+	}
+	return nil, false
+}
+
+// streamFailed turns an API error into the message that ends the turn
+func streamFailed(t *turn, err error) tea.Msg {
+	if t.ctx.Err() != nil {
+		// Cancelled by the user; the UI has already moved on
+		return nil
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return responseMsg{turn: t, outOfTime: true}
+	}
+	return responseMsg{turn: t, err: err}
+}
+
+// budgetExhausted ends a turn that ran out of time, listing the tool
+// outputs it gathered so the user can follow up on them
+func (m model) budgetExhausted() error {
+	summary := fmt.Sprintf("stopped after using the %s time budget for this message, before Claude could answer", m.turn.budget)
+
+	calls := make(map[string]ai.ToolCall)
+	var found []string
+	toolIndex := 0
+	for i, msg := range m.messages {
+		switch {
+		case msg.Role == "assistant" && msg.ToolCallID != "":
+			calls[msg.ToolCallID] = ai.ToolCall{ID: msg.ToolCallID, Name: msg.ToolCallName, Input: msg.ToolCallInput}
+		case msg.Role == "tool":
+			// Numbered as the conversation shows them, for /ref
+			toolIndex++
+			if i < m.turn.first {
+				continue
+			}
+			line := fmt.Sprintf("- Tool output #%d: %s", toolIndex, m.toolSummary(calls[msg.ToolCallID]))
+			if msg.ToolError {
+				line += " (failed)"
+			}
+			found = append(found, line)
+		}
+	}
+	if len(found) == 0 {
+		return errors.New(summary + ". Raise the budget with KILO_TURN_BUDGET if it needs longer.")
+	}
+	return errors.New(summary + ". What was found so far:\n" + strings.Join(found, "\n") +
+		"\n\nAsk Claude to continue from these results, or raise the budget with KILO_TURN_BUDGET.")
+}
+
+// handleStreamDelta shows the response text as it's generated
+func (m model) handleStreamDelta(msg streamDeltaMsg) (tea.Model, tea.Cmd) {
+	m.turn.retry = ""
+	m.streaming += msg.text
+	m.refreshViewport()
+	return m, readStream(msg.stream)
+}
+
+// handleStreamRetry shows that the request is being retried
+func (m model) handleStreamRetry(msg streamRetryMsg) (tea.Model, tea.Cmd) {
+	m.turn.retry = fmt.Sprintf("retrying (%d/%d)...", msg.status.Attempt, msg.status.MaxAttempts)
+	return m, readStream(msg.stream)
+}
+
+// handleStreamThrottle shows that the request is waiting for the rate limit
+func (m model) handleStreamThrottle(msg streamThrottleMsg) (tea.Model, tea.Cmd) {
+	m.turn.retry = "rate limited, waiting..."
+	if wait := msg.wait.Round(time.Second); wait >= time.Second {
+		m.turn.retry = fmt.Sprintf("rate limited, waiting %s...", wait)
+	}
+	return m, readStream(msg.stream)
+}
+
+// handleAssistantTurn either finishes the turn or queues the requested tool calls
+func (m model) handleAssistantTurn(response *ai.Response) (tea.Model, tea.Cmd) {
+	m.usage = m.usage.Add(response.Usage)
+	// The request and the reply are the history the next request builds on
+	m.contextTokens = response.Usage.Total()
+	m.turn.tokens += response.Usage.OutputTokens
+	m.turn.retry = ""
+	// The complete response supersedes the streamed preview
+	m.streaming = ""
+	if len(response.ToolCalls) == 0 {
+		if response.Content == "" {
+			return m.Update(responseMsg{turn: m.turn, err: fmt.Errorf("empty response from Claude (no error, just empty content)")})
+		}
+		return m.Update(responseMsg{turn: m.turn, content: response.Content, truncated: response.Truncated()})
+	}
+	// Text Claude wrote before calling tools, such as what it's about to
+	// check, stays in the conversation ahead of the calls
+	if response.Content != "" {
+		m.messages = append(m.messages, ai.Message{
+			Role:    "assistant",
+			Content: response.Content,
+		})
+	}
+
+	if m.turn.iteration >= m.maxToolIterations {
+		return m.Update(responseMsg{turn: m.turn, err: fmt.Errorf("reached maximum iterations (%d) - Claude kept calling tools", m.maxToolIterations)})
+	}
+
+	m.turn.iteration++
+	m.turn.pending = response.ToolCalls
+	cmd := m.nextToolCall()
+	m.refreshViewport()
+	return m, cmd
+}
+
+// nextToolCall records the next pending tool call and runs it, or asks for
+// confirmation first. With nothing left pending, results go back to Claude.
+func (m *model) nextToolCall() tea.Cmd {
+	if len(m.turn.pending) == 0 {
+		// Too little time left for Claude to answer: present what the tools
+		// found rather than a request that would time out
+		if t := m.turn; t.remaining() < minRequestTime {
+			return func() tea.Msg { return responseMsg{turn: t, outOfTime: true} }
+		}
+		return m.requestResponse()
+	}
+
+	// Independent calls run together; the rest go one at a time, so each
+	// can be confirmed and show its output as it runs
+	if batch := m.concurrentCalls(); len(batch) > 1 {
+		m.turn.pending = m.turn.pending[len(batch):]
+		return m.executeBatch(batch)
+	}
+
+	call := m.turn.pending[0]
+	m.turn.pending = m.turn.pending[1:]
+
+	// Add assistant message with tool call to history
+	m.messages = append(m.messages, ai.Message{
+		Role:          "assistant",
+		ToolCallID:    call.ID,
+		ToolCallName:  call.Name,
+		ToolCallInput: call.Input,
+	})
+
+	if m.needsConfirmation(call) {
+		m.confirming = &call
+		m.turn.pause()
+		return nil
+	}
+
+	return m.executeTool(call)
+}
+
+// executeTool runs a tool call within the turn's remaining budget, or its
+// own timeout if that's longer (KILO_TOOL_TIMEOUT). Partial output arrives
+// as toolProgressMsg until the final toolResultMsg.
+func (m model) executeTool(call ai.ToolCall) tea.Cmd {
+	t := m.turn
+	remaining := max(t.remaining(), m.executor.Timeout(call.Name))
+	t.running = m.toolSummary(call)
+
+	return func() tea.Msg {
+		events := make(chan tea.Msg, 1)
+
+		t.tools.Add(1)
+		go func() {
+			defer t.tools.Done()
+			ctx, cancel := context.WithTimeout(t.ctx, remaining)
+			defer cancel()
+
+			ctx = ai.WithProgress(ctx, func(output string) {
+				// Each update carries the full output so far, so if the UI
+				// hasn't consumed the last one yet this one can be dropped
+				select {
+				case events <- toolProgressMsg{turn: t, callID: call.ID, output: output, events: events}:
+				default:
+				}
+			})
+
+			result := m.executor.Execute(ctx, call)
+			if t.ctx.Err() != nil {
+				// Cancelled by the user; the UI has already moved on
+				close(events)
+				return
+			}
+			events <- toolResultMsg{turn: t, call: call, result: result}
+		}()
+
+		return <-events
+	}
+}
+
+// concurrentCalls returns the pending calls at the front of the queue that
+// may run at once: tools not marked Serial that run without confirmation
+func (m model) concurrentCalls() []ai.ToolCall {
+	if m.executor.Concurrency() < 2 {
+		return nil
+	}
+	n := 0
+	for _, call := range m.turn.pending {
+		if !m.executor.Concurrent(call.Name) || m.needsConfirmation(call) {
+			break
+		}
+		n++
+	}
+	return m.turn.pending[:n]
+}
+
+// executeBatch runs independent tool calls concurrently, within the turn's
+// remaining budget or the longest of their timeouts. The results arrive
+// together as a toolBatchMsg; there's no live output while they run.
+func (m model) executeBatch(calls []ai.ToolCall) tea.Cmd {
+	t := m.turn
+	remaining := t.remaining()
+	summaries := make([]string, len(calls))
+	for i, call := range calls {
+		remaining = max(remaining, m.executor.Timeout(call.Name))
+		summaries[i] = m.toolSummary(call)
+	}
+	t.running = fmt.Sprintf("%d tools at once: %s", len(calls), strings.Join(summaries, ", "))
+
+	return func() tea.Msg {
+		t.tools.Add(1)
+		defer t.tools.Done()
+		ctx, cancel := context.WithTimeout(t.ctx, remaining)
+		defer cancel()
+
+		results := m.executor.ExecuteAll(ctx, calls)
+		if t.ctx.Err() != nil {
+			// Cancelled by the user; the UI has already moved on
+			return nil
+		}
+		return toolBatchMsg{turn: t, calls: calls, results: results}
+	}
+}
+
+// summaryKeys are the input parameters that best identify what a call does,
+// for tools that don't run a shell command
+var summaryKeys = []string{"path", "url", "pattern", "subcommand", "package", "query"}
+
+// toolSummary names a tool call and what it acts on, e.g. "bash (ls -la)"
+// or "read_file (main.go)"
+func (m model) toolSummary(call ai.ToolCall) string {
+	if detail := m.toolDetail(call); detail != "" {
+		return call.Name + " (" + detail + ")"
+	}
+	return call.Name
+}
+
+// toolDetail is what a tool call acts on, on one line: its shell command,
+// or the input that best identifies it. It's "" when there's nothing to
+// show.
+func (m model) toolDetail(call ai.ToolCall) string {
+	detail := m.executor.Command(call)
+	if detail == "" {
+		var input map[string]any
+		json.Unmarshal([]byte(call.Input), &input)
+		for _, key := range summaryKeys {
+			if value, ok := input[key].(string); ok && value != "" {
+				detail = value
+				break
+			}
+		}
+	}
+	// Keep multi-line scripts on the one line
+	detail = strings.ReplaceAll(strings.TrimSpace(detail), "\n", "; ")
+	return strings.Join(strings.Fields(detail), " ")
+}
+
+// waitForToolEvent delivers the next progress update or the final result
+func waitForToolEvent(events <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		return <-events
+	}
+}
+
+// handleToolProgress replaces the live output region with the latest output
+func (m model) handleToolProgress(msg toolProgressMsg) (tea.Model, tea.Cmd) {
+	m.live = &liveOutput{callID: msg.callID, output: msg.output}
+	m.refreshViewport()
+	return m, waitForToolEvent(msg.events)
+}
+
+// handleToolResult records a tool result and moves on to the next call
+func (m model) handleToolResult(msg toolResultMsg) (tea.Model, tea.Cmd) {
+	// The final result supersedes the live region
+	m.live = nil
+	m.appendToolResult(msg.call, msg.result)
+	return m.continueAfterTools([]ai.ToolCall{msg.call}, []ai.ToolResult{msg.result})
+}
+
+// handleToolBatch records the results of calls that ran together. Each call
+// is added to the history just before its result, as if they'd run in turn.
+func (m model) handleToolBatch(msg toolBatchMsg) (tea.Model, tea.Cmd) {
+	for i, call := range msg.calls {
+		m.messages = append(m.messages, ai.Message{
+			Role:          "assistant",
+			ToolCallID:    call.ID,
+			ToolCallName:  call.Name,
+			ToolCallInput: call.Input,
+		})
+		m.appendToolResult(call, msg.results[i])
+	}
+	return m.continueAfterTools(msg.calls, msg.results)
+}
+
+// appendToolResult adds a tool result message for call
+func (m *model) appendToolResult(call ai.ToolCall, result ai.ToolResult) {
+	m.messages = append(m.messages, ai.Message{
+		Role:           "tool",
+		Content:        result.Content,
+		DisplayContent: result.Full,
+		ToolCallID:     call.ID,
+		ToolCallName:   call.Name,
+		ToolCommand:    result.Command,
+		ToolError:      result.IsError,
+	})
+}
+
+// continueAfterTools moves on to the next pending call once results are in
+func (m model) continueAfterTools(calls []ai.ToolCall, results []ai.ToolResult) (tea.Model, tea.Cmd) {
+	m.turn.running = ""
+
+	// A tool gave up on the task: skip any remaining calls and present its
+	// message as the final answer
+	for _, result := range results {
+		if result.Aborted {
+			m.turn.pending = nil
+			return m.Update(responseMsg{turn: m.turn, content: result.Content})
+		}
+	}
+
+	// With stop on tool error, a failure goes to the user rather than back
+	// to Claude, skipping any remaining calls
+	if m.stopOnToolError {
+		for i, result := range results {
+			if result.IsError {
+				m.turn.pending = nil
+				return m.Update(responseMsg{turn: m.turn, err: fmt.Errorf("stopped because %s failed (stop on tool error is on); its output is above. Reply with how to proceed, or type /stoponerror to let Claude handle tool errors itself", m.toolSummary(calls[i]))})
+			}
+		}
+	}
+
+	cmd := m.nextToolCall()
+	m.refreshViewport()
+	return m, cmd
+}
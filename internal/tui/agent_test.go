@@ -0,0 +1,329 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"kilo/internal/ai"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// fakeReply is one scripted answer from fakeProvider: the response it
+// streams, or the error the request fails with
+type fakeReply struct {
+	response *ai.Response
+	err      error
+}
+
+// fakeProvider stands in for the API, answering each request with the next
+// scripted reply and recording the conversation it was sent
+type fakeProvider struct {
+	mu       sync.Mutex
+	replies  []fakeReply
+	requests [][]ai.Message
+}
+
+func (p *fakeProvider) next(messages []ai.Message) (fakeReply, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requests = append(p.requests, append([]ai.Message(nil), messages...))
+	if len(p.replies) == 0 {
+		return fakeReply{}, errors.New("fake provider: no replies left")
+	}
+	reply := p.replies[0]
+	p.replies = p.replies[1:]
+	return reply, nil
+}
+
+func (p *fakeProvider) SendMessageWithTools(ctx context.Context, messages []ai.Message, tools []ai.Tool, opts ...ai.RequestOption) (*ai.Response, error) {
+	reply, err := p.next(messages)
+	if err != nil {
+		return nil, err
+	}
+	return reply.response, reply.err
+}
+
+func (p *fakeProvider) StreamMessageWithTools(ctx context.Context, messages []ai.Message, tools []ai.Tool, opts ...ai.RequestOption) (<-chan ai.StreamEvent, error) {
+	reply, err := p.next(messages)
+	if err != nil {
+		return nil, err
+	}
+	if reply.err != nil {
+		return nil, reply.err
+	}
+	events := make(chan ai.StreamEvent, 2)
+	if reply.response.Content != "" {
+		events <- ai.StreamEvent{Type: ai.StreamTextDelta, Text: reply.response.Content}
+	}
+	events <- ai.StreamEvent{Type: ai.StreamDone, Response: reply.response}
+	close(events)
+	return events, nil
+}
+
+func (p *fakeProvider) sent() [][]ai.Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.requests
+}
+
+// newTestModel returns a model that talks to provider, with nothing read
+// from or saved to the user's own data directory
+func newTestModel(t *testing.T, provider ai.Provider) model {
+	t.Helper()
+	t.Setenv("KILO_DATA_DIR", t.TempDir())
+	t.Setenv("KILO_CONFIG_DIR", t.TempDir())
+	t.Setenv("KILO_AUTOSAVE", "0")
+	t.Setenv("KILO_CONFIRM_TOOLS", "")
+
+	next, _ := New(WithProvider(provider)).Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+	return next.(model)
+}
+
+// runTurn sends input and plays the turn out the way the Bubble Tea runtime
+// would, running commands and feeding their messages back into Update until
+// the turn ends. Redraw ticks are dropped rather than rescheduled.
+func runTurn(t *testing.T, m model, input string) model {
+	t.Helper()
+	return runTurnWatching(t, m, input, nil)
+}
+
+// runTurnWatching is runTurn, also passing each message to watch before
+// Update sees it
+func runTurnWatching(t *testing.T, m model, input string, watch func(tea.Msg)) model {
+	t.Helper()
+	msgs := make(chan tea.Msg, 16)
+	var run func(cmd tea.Cmd)
+	run = func(cmd tea.Cmd) {
+		if cmd == nil {
+			return
+		}
+		go func() {
+			msg := cmd()
+			if batch, ok := msg.(tea.BatchMsg); ok {
+				for _, cmd := range batch {
+					run(cmd)
+				}
+				return
+			}
+			msgs <- msg
+		}()
+	}
+
+	next, cmd := m.sendMessage(input)
+	m = next.(model)
+	run(cmd)
+
+	deadline := time.After(5 * time.Second)
+	for m.turn != nil {
+		select {
+		case msg := <-msgs:
+			if msg == nil {
+				continue
+			}
+			if watch != nil {
+				watch(msg)
+			}
+			next, cmd := m.Update(msg)
+			m = next.(model)
+			if _, tick := msg.(turnTickMsg); !tick {
+				run(cmd)
+			}
+		case <-deadline:
+			t.Fatalf("turn did not finish; messages so far: %+v", m.messages)
+		}
+	}
+	return m
+}
+
+func roles(messages []ai.Message) string {
+	names := make([]string, len(messages))
+	for i, msg := range messages {
+		names[i] = msg.Role
+	}
+	return strings.Join(names, ",")
+}
+
+func TestTurnAddsReply(t *testing.T) {
+	provider := &fakeProvider{replies: []fakeReply{
+		{response: &ai.Response{Content: "Hi there!", StopReason: "end_turn", Usage: ai.Usage{InputTokens: 12, OutputTokens: 3}}},
+	}}
+	m := runTurn(t, newTestModel(t, provider), "hello")
+
+	if got := roles(m.messages); got != "user,assistant" {
+		t.Fatalf("roles = %s", got)
+	}
+	if got := m.messages[1].Content; got != "Hi there!" {
+		t.Errorf("reply = %q", got)
+	}
+	if m.usage.InputTokens != 12 || m.usage.OutputTokens != 3 {
+		t.Errorf("usage = %+v", m.usage)
+	}
+	sent := provider.sent()
+	if len(sent) != 1 || roles(sent[0]) != "user" || sent[0][0].Content != "hello" {
+		t.Errorf("requests = %+v", sent)
+	}
+}
+
+func TestTurnRunsToolCalls(t *testing.T) {
+	provider := &fakeProvider{replies: []fakeReply{
+		{response: &ai.Response{
+			StopReason: "tool_use",
+			ToolCalls:  []ai.ToolCall{{ID: "toolu_1", Name: "get_time", Input: "{}"}},
+		}},
+		{response: &ai.Response{Content: "It's late.", StopReason: "end_turn"}},
+	}}
+	m := runTurn(t, newTestModel(t, provider), "what time is it?")
+
+	if got := roles(m.messages); got != "user,assistant,tool,assistant" {
+		t.Fatalf("roles = %s", got)
+	}
+	call, result := m.messages[1], m.messages[2]
+	if call.ToolCallID != "toolu_1" || call.ToolCallName != "get_time" {
+		t.Errorf("tool call = %+v", call)
+	}
+	if result.ToolCallID != "toolu_1" || result.ToolError || result.Content == "" {
+		t.Errorf("tool result = %+v", result)
+	}
+	if got := m.messages[3].Content; got != "It's late." {
+		t.Errorf("reply = %q", got)
+	}
+
+	// The second request carries the call and its result back
+	sent := provider.sent()
+	if len(sent) != 2 {
+		t.Fatalf("got %d requests, want 2", len(sent))
+	}
+	if got := roles(sent[1]); got != "user,assistant,tool" {
+		t.Errorf("second request roles = %s", got)
+	}
+}
+
+func TestTurnKeepsTextBeforeToolCalls(t *testing.T) {
+	provider := &fakeProvider{replies: []fakeReply{
+		{response: &ai.Response{
+			Content:    "Let me check the clock.",
+			StopReason: "tool_use",
+			ToolCalls:  []ai.ToolCall{{ID: "toolu_1", Name: "get_time", Input: "{}"}},
+		}},
+		{response: &ai.Response{Content: "It's late.", StopReason: "end_turn"}},
+	}}
+	m := runTurn(t, newTestModel(t, provider), "what time is it?")
+
+	if got := roles(m.messages); got != "user,assistant,assistant,tool,assistant" {
+		t.Fatalf("roles = %s", got)
+	}
+	if preamble := m.messages[1]; preamble.Content != "Let me check the clock." || preamble.ToolCallID != "" {
+		t.Errorf("preamble = %+v", preamble)
+	}
+	if call := m.messages[2]; call.ToolCallID != "toolu_1" {
+		t.Errorf("tool call = %+v", call)
+	}
+
+	// Claude sees its own text again along with the call
+	sent := provider.sent()
+	if len(sent) != 2 {
+		t.Fatalf("got %d requests, want 2", len(sent))
+	}
+	if got := roles(sent[1]); got != "user,assistant,assistant,tool" {
+		t.Errorf("second request roles = %s", got)
+	}
+	if got := sent[1][1].Content; got != "Let me check the clock." {
+		t.Errorf("second request preamble = %q", got)
+	}
+}
+
+func TestTurnReportsErrors(t *testing.T) {
+	provider := &fakeProvider{replies: []fakeReply{
+		{err: errors.New("overloaded")},
+	}}
+	m := runTurn(t, newTestModel(t, provider), "hello")
+
+	if got := roles(m.messages); got != "user,assistant" {
+		t.Fatalf("roles = %s", got)
+	}
+	if got := m.messages[1].Content; !strings.Contains(got, "overloaded") {
+		t.Errorf("error message = %q", got)
+	}
+	if m.thinking {
+		t.Error("still thinking after the turn failed")
+	}
+}
+
+func TestTurnOutOfTimeAfterSlowTool(t *testing.T) {
+	provider := &fakeProvider{replies: []fakeReply{
+		{response: &ai.Response{
+			StopReason: "tool_use",
+			ToolCalls:  []ai.ToolCall{{ID: "toolu_1", Name: "bash", Input: `{"command":"sleep 1.5"}`}},
+		}},
+		{response: &ai.Response{Content: "Done.", StopReason: "end_turn"}},
+	}}
+	m := newTestModel(t, provider)
+	m.turnBudget = time.Second
+	m.trust.all = true
+
+	var got responseMsg
+	m = runTurnWatching(t, m, "wait a bit", func(msg tea.Msg) {
+		if r, ok := msg.(responseMsg); ok {
+			got = r
+		}
+	})
+
+	if !got.outOfTime {
+		t.Errorf("turn ended with %+v, want outOfTime", got)
+	}
+	if n := len(provider.sent()); n != 1 {
+		t.Errorf("got %d requests, want 1: no request once the budget is spent", n)
+	}
+	last := m.messages[len(m.messages)-1]
+	if !strings.Contains(last.Content, "time budget") || !strings.Contains(last.Content, "Tool output #1") {
+		t.Errorf("final message = %q", last.Content)
+	}
+}
+
+func TestLateMessagesFromCancelledTurnAreDropped(t *testing.T) {
+	m := newTestModel(t, &fakeProvider{})
+	next, _ := m.sendMessage("first")
+	m = next.(model)
+	old := m.turn
+	next, _ = m.cancelTurn()
+	m = next.(model)
+	next, _ = m.sendMessage("second")
+	m = next.(model)
+	current := m.turn
+	defer m.endTurn()
+	before := len(m.messages)
+
+	events := make(chan ai.StreamEvent)
+	close(events)
+	stale := &responseStream{turn: old, events: events, cancel: func() {}}
+	late := []tea.Msg{
+		streamDeltaMsg{text: "stale text", stream: stale},
+		streamRetryMsg{status: ai.RetryStatus{Attempt: 2, MaxAttempts: 3}, stream: stale},
+		assistantTurnMsg{turn: old, response: &ai.Response{Content: "stale answer", StopReason: "end_turn"}},
+		toolResultMsg{turn: old, call: ai.ToolCall{ID: "toolu_1", Name: "get_time"}, result: ai.ToolResult{Content: "noon"}},
+		responseMsg{turn: old, content: "stale answer"},
+	}
+	for _, msg := range late {
+		next, cmd := m.Update(msg)
+		m = next.(model)
+		if cmd != nil {
+			t.Errorf("%T from the cancelled turn returned a command", msg)
+		}
+	}
+	if m.turn != current || m.streaming != "" || m.turn.retry != "" || len(m.messages) != before {
+		t.Errorf("cancelled turn leaked: turn = %p (want %p), streaming = %q, retry = %q, messages = %+v",
+			m.turn, current, m.streaming, m.turn.retry, m.messages)
+	}
+
+	// The current turn's own text still comes through
+	next, _ = m.Update(streamDeltaMsg{text: "fresh", stream: &responseStream{turn: current, events: events, cancel: func() {}}})
+	m = next.(model)
+	if m.streaming != "fresh" {
+		t.Errorf("streaming = %q", m.streaming)
+	}
+}
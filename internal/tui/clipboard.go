@@ -0,0 +1,35 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/atotto/clipboard"
+)
+
+// lastResponse returns the most recent assistant reply with text, skipping
+// messages that only carry a tool call
+func (m model) lastResponse() (string, bool) {
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		msg := m.messages[i]
+		if msg.Role == "assistant" && msg.ToolCallID == "" && msg.Content != "" {
+			return msg.Content, true
+		}
+	}
+	return "", false
+}
+
+// copyLastResponse puts the latest reply on the system clipboard, since
+// selecting text with the mouse is awkward in the alternate screen
+func (m model) copyLastResponse() model {
+	content, ok := m.lastResponse()
+	if !ok {
+		m.notice = "No response to copy yet"
+		return m
+	}
+	if err := clipboard.WriteAll(content); err != nil {
+		m.notice = fmt.Sprintf("Copy failed: %v", err)
+		return m
+	}
+	m.notice = "Copied!"
+	return m
+}
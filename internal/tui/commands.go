@@ -0,0 +1,516 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"kilo/internal/ai"
+	"kilo/internal/doctor"
+	"kilo/internal/storage"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// commandHelp lists the slash commands for /help
+const commandHelp = `Commands
+  /help                    show this list
+  /clear                   start a fresh conversation
+  /trim                    drop older turns to free up the context window
+  /model [name]            show or switch the model
+  /save <name>             save the conversation
+  /load <name>             load a saved conversation
+  /sessions                pick a saved conversation to load or delete
+  /export [-f] <file>      write the conversation to a Markdown file (-f overwrites)
+  /settings [key value]    show or change model, temperature, top_p, max_tokens, persona
+  /temp [value|default]    show or change the temperature
+  /lang <language|off>     respond in another language
+  /confirm                 toggle confirmation for every tool with side effects
+  /stoponerror             toggle stopping to ask you when a tool call fails
+  /refresh-tools           re-run read-only tools in the history
+  /ref <n>                 reference tool output #n in your next message
+  /reload                  re-read .kilo.md and the project layout
+  /cd [dir]                show or change the directory tools run in
+  /detach                  clear attached files
+  /gitcontext              toggle sending git state with each request
+  /worddiff                toggle word-level diff highlighting
+  /showcmd                 toggle showing the command behind each tool output
+  /stats                   show the API rate limit budget
+  /info                    toggle a panel with the model, directory, usage, and tools
+  /doctor [live]           run diagnostics`
+
+// handleCommand dispatches a slash command typed into the input box
+func (m model) handleCommand(input string) (tea.Model, tea.Cmd) {
+	fields := strings.Fields(input)
+	name := fields[0]
+
+	switch name {
+	case "/help":
+		m.messages = append(m.messages, ai.Message{
+			Role:    "system",
+			Content: commandHelp,
+		})
+		m.refreshViewport()
+		return m, nil
+
+	case "/clear":
+		if m.thinking {
+			m.notice = "Wait for the current response, or press Ctrl+R to cancel it, before clearing"
+			return m, nil
+		}
+		m.clearConversation()
+		m.notice = "Conversation cleared"
+		return m, nil
+
+	case "/trim":
+		if m.thinking {
+			m.notice = "Wait for the current response, or press Ctrl+R to cancel it, before trimming"
+			return m, nil
+		}
+		// Keep the recent turns in about a quarter of the context window
+		kept := ai.TrimHistory(m.messages, ai.ContextWindow(m.client.Model())/4)
+		if len(kept) == len(m.messages) {
+			m.notice = "Nothing to trim"
+			return m, nil
+		}
+		m.notice = fmt.Sprintf("Dropped %d older messages; Claude gets a note listing your earlier requests", len(m.messages)-len(kept))
+		m.messages = kept
+		m.contextTokens = 0
+		m.autosave()
+		m.refreshViewport()
+		return m, nil
+
+	case "/model":
+		if len(fields) < 2 {
+			m.notice = fmt.Sprintf("Model: %s (known: %s)", m.client.Model(), strings.Join(ai.KnownModels, ", "))
+			return m, nil
+		}
+		if !m.allowUnknownModels {
+			if err := ai.ValidateModel(fields[1]); err != nil {
+				m.notice = fmt.Sprintf("Error: %v", err)
+				return m, nil
+			}
+		}
+		m.client.SetModel(fields[1])
+		m.notice = "Switched to " + fields[1]
+		return m, nil
+
+	case "/refresh-tools":
+		if m.thinking {
+			m.notice = "Wait for the current response before refreshing tools"
+			return m, nil
+		}
+		if len(m.refreshableToolCalls()) == 0 {
+			m.notice = "No idempotent tool calls to refresh"
+			return m, nil
+		}
+		// Re-running can still have side effects, so ask first
+		m.confirmRefresh = true
+		return m, nil
+
+	case "/gitcontext":
+		m.gitContext = !m.gitContext
+		if m.gitContext {
+			m.notice = "Git context on: branch and repo state included with each request"
+		} else {
+			m.notice = "Git context off"
+		}
+		return m, nil
+
+	case "/worddiff":
+		m.wordDiff = !m.wordDiff
+		if m.wordDiff {
+			m.notice = "Word diff on: changed words are highlighted within diff lines"
+		} else {
+			m.notice = "Word diff off"
+		}
+		m.refreshViewport()
+		return m, nil
+
+	case "/showcmd":
+		m.showCommands = !m.showCommands
+		if m.showCommands {
+			m.notice = "Showing the resolved command for each tool output"
+		} else {
+			m.notice = "Resolved commands hidden"
+		}
+		m.refreshViewport()
+		return m, nil
+
+	case "/confirm":
+		m.confirmTools = !m.confirmTools
+		switch {
+		case m.confirmTools:
+			m.confirmDangerous = true
+			m.notice = "Tool confirmation on: tools with side effects wait for approval"
+		case m.confirmDangerous:
+			m.notice = "Tool confirmation back to the default: only bash and file writes wait for approval"
+		default:
+			m.notice = "Tool confirmation off"
+		}
+		return m, nil
+
+	case "/stoponerror":
+		m.stopOnToolError = !m.stopOnToolError
+		if m.stopOnToolError {
+			m.notice = "Stop on tool error on: a failed tool call ends the turn so you can decide what's next"
+		} else {
+			m.notice = "Stop on tool error off: Claude sees failed tool calls and tries something else"
+		}
+		return m, nil
+
+	case "/save":
+		if len(fields) < 2 {
+			m.notice = "Usage: /save <name>"
+			return m, nil
+		}
+		path, err := m.saveSession(fields[1])
+		if err != nil {
+			m.notice = fmt.Sprintf("Save failed: %v", err)
+			return m, nil
+		}
+		m.notice = "Saved to " + path
+		return m, nil
+
+	case "/load":
+		if len(fields) < 2 {
+			m.notice = "Usage: /load <name>"
+			return m, nil
+		}
+		return m.loadSession(fields[1])
+
+	case "/sessions":
+		return m.openSessionPicker(), nil
+
+	case "/export":
+		arg := strings.TrimSpace(strings.TrimPrefix(input, "/export"))
+		force := len(fields) > 1 && fields[1] == "-f"
+		if force {
+			arg = strings.TrimSpace(strings.TrimPrefix(arg, "-f"))
+		}
+		if arg == "" {
+			m.notice = "Usage: /export [-f] <file>, e.g. /export chat.md"
+			return m, nil
+		}
+		path, err := m.exportMarkdown(arg, force)
+		if errors.Is(err, fs.ErrExist) {
+			m.notice = fmt.Sprintf("%s already exists; use /export -f %s to overwrite it", path, arg)
+			return m, nil
+		}
+		if err != nil {
+			m.notice = fmt.Sprintf("Export failed: %v", err)
+			return m, nil
+		}
+		m.notice = "Exported to " + path
+		return m, nil
+
+	case "/settings":
+		return m.handleSettings(fields[1:])
+
+	case "/temp":
+		if len(fields) < 2 {
+			m.notice = "Temperature: " + formatSampling(m.client.Temperature())
+			return m, nil
+		}
+		return m.handleSettings([]string{"temperature", fields[1]})
+
+	case "/stats":
+		m.messages = append(m.messages, ai.Message{
+			Role:    "system",
+			Content: m.client.RateLimit().String(),
+		})
+		m.refreshViewport()
+		return m, nil
+
+	case "/info":
+		m.showInfo = !m.showInfo
+		return m, nil
+
+	case "/ref":
+		if len(fields) < 2 {
+			m.notice = "Usage: /ref <n>: reference tool output #n in your next message"
+			return m, nil
+		}
+		return m.insertRef(fields[1]), nil
+
+	case "/lang":
+		if len(fields) < 2 {
+			if language := m.client.Language(); language != "" {
+				m.notice = "Responding in " + language + " (/lang off to reset)"
+			} else {
+				m.notice = "Usage: /lang <language>, e.g. /lang German"
+			}
+			return m, nil
+		}
+		language := strings.Join(fields[1:], " ")
+		if language == "off" {
+			language = ""
+		}
+		m.client.SetLanguage(language)
+		if m.client.Language() != "" {
+			m.notice = "Responses will be in " + m.client.Language()
+		} else {
+			m.notice = "Response language reset"
+		}
+		return m, nil
+
+	case "/reload":
+		if err := m.reloadProjectInstructions(); err != nil {
+			m.notice = fmt.Sprintf("Reload failed: %v", err)
+			return m, nil
+		}
+		if err := m.reloadProjectContext(); err != nil {
+			m.notice = fmt.Sprintf("Reload failed: %v", err)
+			return m, nil
+		}
+		if m.projectContext {
+			m.notice = "Project layout and " + projectInstructionsFile + " refreshed"
+		} else {
+			m.notice = projectInstructionsFile + " refreshed"
+		}
+		return m, nil
+
+	case "/cd":
+		if len(fields) < 2 {
+			m.notice = "Tools run in " + m.executor.WorkingDir()
+			return m, nil
+		}
+		if m.thinking {
+			m.notice = "Wait for the current response before changing directory"
+			return m, nil
+		}
+		if err := m.executor.SetWorkingDir(strings.TrimSpace(strings.TrimPrefix(input, "/cd"))); err != nil {
+			m.notice = fmt.Sprintf("Error: %v", err)
+			return m, nil
+		}
+		// Attachments and project files were relative to the old directory
+		m.attachments = nil
+		m.notice = "Tools now run in " + m.executor.WorkingDir()
+		if err := m.reloadProjectInstructions(); err != nil {
+			m.notice += fmt.Sprintf("; ignoring %s: %v", projectInstructionsFile, err)
+		}
+		if err := m.reloadProjectContext(); err != nil {
+			m.notice += fmt.Sprintf("; project context unavailable: %v", err)
+		}
+		return m, nil
+
+	case "/detach":
+		m.attachments = nil
+		m.notice = "Attachments cleared"
+		return m, nil
+
+	case "/doctor":
+		live := len(fields) > 1 && fields[1] == "live"
+		m.notice = "Running diagnostics..."
+		return m, runDoctor(live)
+
+	default:
+		m.notice = fmt.Sprintf("Unknown command: %s (type /help for a list)", name)
+		return m, nil
+	}
+}
+
+// exportMarkdown writes the conversation as a Markdown transcript. A relative
+// path is taken from the directory tools run in. An existing file is only
+// replaced when force is set; otherwise the error wraps fs.ErrExist and the
+// resolved path is still returned.
+func (m model) exportMarkdown(path string, force bool) (string, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(m.executor.WorkingDir(), path)
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	f, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return path, err
+	}
+	transcript := ai.ExportMarkdown(m.messages, m.client.Model(), time.Now())
+	if _, err := f.WriteString(transcript); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// handleRefreshConfirm answers the /refresh-tools confirmation prompt
+func (m model) handleRefreshConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "y", "Y", "enter":
+		m.confirmRefresh = false
+		m.notice = "Refreshing tool results..."
+		cmd := m.refreshTools()
+		return m, tea.Batch(cmd, turnTick(m.turn), m.spinner.Tick)
+	case "n", "N", "esc":
+		m.confirmRefresh = false
+		m.notice = "Refresh cancelled"
+		return m, nil
+	case "ctrl+c":
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+// clearConversation empties the conversation, moving autosave to a new file
+// so the old conversation's is kept rather than overwritten
+func (m *model) clearConversation() {
+	m.messages = []ai.Message{}
+	m.contextTokens = 0
+	m.attachments = nil
+	if m.autosavePath != "" {
+		if path, err := m.nextAutosavePath(); err == nil {
+			m.autosavePath = path
+		}
+	}
+	m.refreshViewport()
+}
+
+// nextAutosavePath names a new autosave file, numbering it when the name
+// for this second is taken, as it is when a conversation is cleared within
+// a second of starting
+func (m model) nextAutosavePath() (string, error) {
+	name := storage.AutosaveName(time.Now())
+	path, err := storage.SessionPath(name)
+	for n := 2; err == nil; n++ {
+		if _, statErr := os.Stat(path); path != m.autosavePath && os.IsNotExist(statErr) {
+			break
+		}
+		path, err = storage.SessionPath(fmt.Sprintf("%s-%d", name, n))
+	}
+	return path, err
+}
+
+// requestNewConversation handles Ctrl+N, asking first unless there's
+// nothing to lose
+func (m model) requestNewConversation() model {
+	if m.thinking {
+		m.notice = "Wait for the current response, or press Ctrl+R to cancel it, before starting a new conversation"
+		return m
+	}
+	if len(m.messages) == 0 {
+		m.notice = "This is already a new conversation"
+		return m
+	}
+	m.confirmNew = true
+	return m
+}
+
+// newConversationPrompt asks to confirm Ctrl+N, saying what happens to the
+// current conversation
+func (m model) newConversationPrompt() string {
+	if m.autosavePath == "" {
+		return "Start a new conversation? Autosave is off, so this one will be lost unless you /save it (Enter/y: yes, n: no)"
+	}
+	return "Start a new conversation? This one stays in the autosave history (Enter/y: yes, n: no)"
+}
+
+// handleNewConfirm answers the Ctrl+N confirmation prompt
+func (m model) handleNewConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "y", "Y", "enter":
+		m.confirmNew = false
+		return m.newConversation(), nil
+	case "n", "N", "esc":
+		m.confirmNew = false
+		m.notice = "Kept the current conversation"
+		return m, nil
+	case "ctrl+c":
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+// newConversation archives the conversation to its autosave and starts a
+// fresh one in the same window, with the token counts and view reset. The
+// archive can be loaded again with /load.
+func (m model) newConversation() model {
+	archived := ""
+	if m.autosavePath != "" {
+		settings := m.client.Settings()
+		if err := ai.SaveSession(m.autosavePath, ai.Session{Settings: &settings, Messages: m.messages}); err != nil {
+			m.notice = fmt.Sprintf("Kept the current conversation; archiving it failed: %v", err)
+			return m
+		}
+		archived = strings.TrimSuffix(filepath.Base(m.autosavePath), ".json")
+	}
+
+	m.clearConversation()
+	m.usage = ai.Usage{}
+	m.startedAt = time.Now()
+	m.queued = ""
+	m.newBelow = false
+	m.atBottom = true
+	m.viewport.GotoTop()
+	m.notice = "New conversation"
+	if archived != "" {
+		m.notice += "; the last one is saved as " + archived
+	}
+	return m
+}
+
+// refreshableToolCalls collects tool calls in history whose tools are
+// idempotent. Calls that always need the user's approval (ConfirmReason)
+// aren't re-run behind a single prompt.
+func (m model) refreshableToolCalls() []ai.ToolCall {
+	var calls []ai.ToolCall
+	for _, msg := range m.messages {
+		if msg.Role != "assistant" || msg.ToolCallID == "" {
+			continue
+		}
+		if !m.executor.IsIdempotent(msg.ToolCallName) {
+			continue
+		}
+		call := ai.ToolCall{
+			ID:    msg.ToolCallID,
+			Name:  msg.ToolCallName,
+			Input: msg.ToolCallInput,
+		}
+		if m.executor.ConfirmReason(call) != "" {
+			continue
+		}
+		calls = append(calls, call)
+	}
+	return calls
+}
+
+// refreshTimeout bounds how long /refresh-tools may take
+const refreshTimeout = 60 * time.Second
+
+// refreshTools re-executes idempotent tool calls so restored history
+// reflects current state. The refresh runs as a turn, so Esc or Ctrl+R
+// cancels it like any other response.
+func (m *model) refreshTools() tea.Cmd {
+	calls := m.refreshableToolCalls()
+	m.turn = newTurn(refreshTimeout, len(m.messages))
+	m.thinking = true
+	t := m.turn
+
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(t.ctx, refreshTimeout)
+		defer cancel()
+
+		results := make(map[string]ai.ToolResult, len(calls))
+		for i, result := range m.executor.ExecuteAll(ctx, calls) {
+			results[calls[i].ID] = result
+		}
+
+		return toolsRefreshedMsg{turn: t, results: results}
+	}
+}
+
+// runDoctor runs setup diagnostics off the UI goroutine
+func runDoctor(live bool) tea.Cmd {
+	return func() tea.Msg {
+		checks := doctor.Run(context.Background(), doctor.Options{Live: live})
+		return doctorResultMsg{report: doctor.Format(checks), passed: doctor.Passed(checks)}
+	}
+}
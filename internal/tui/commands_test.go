@@ -0,0 +1,115 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"kilo/internal/ai"
+	"kilo/internal/storage"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestSaveStaysInSessionsDir(t *testing.T) {
+	m := newTestModel(t, &fakeProvider{})
+	m.messages = []ai.Message{{Role: "user", Content: "hello"}}
+	dir, err := storage.SessionsDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	project := t.TempDir()
+	readme := filepath.Join(project, "README.md")
+	if err := os.WriteFile(readme, []byte("# project\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{readme, "README.md", "../escape", filepath.Join(project, "notes")} {
+		next, _ := m.handleCommand("/save " + name)
+		got := next.(model)
+		path := strings.TrimPrefix(got.notice, "Saved to ")
+		if path == got.notice {
+			t.Fatalf("/save %s: %s", name, got.notice)
+		}
+		if filepath.Dir(path) != dir {
+			t.Errorf("/save %s wrote %s, outside %s", name, path, dir)
+		}
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("/save %s: %v", name, err)
+		}
+	}
+
+	if data, _ := os.ReadFile(readme); string(data) != "# project\n" {
+		t.Errorf("README.md was overwritten: %q", data)
+	}
+	if entries, _ := os.ReadDir(project); len(entries) != 1 {
+		t.Errorf("project directory has %d entries, want just README.md", len(entries))
+	}
+}
+
+func TestRefreshToolsCancel(t *testing.T) {
+	m := newTestModel(t, &fakeProvider{})
+	history := []ai.Message{
+		{Role: "user", Content: "what time is it?"},
+		{Role: "assistant", ToolCallID: "toolu_1", ToolCallName: "get_time", ToolCallInput: "{}"},
+		{Role: "tool", ToolCallID: "toolu_1", ToolCallName: "get_time", Content: "stale"},
+		{Role: "assistant", Content: "Noon."},
+	}
+
+	// A refresh that finishes replaces the result
+	refreshed := m
+	refreshed.messages = append([]ai.Message(nil), history...)
+	msg := refreshed.refreshTools()()
+	if refreshed.turn == nil || !refreshed.thinking {
+		t.Fatal("refresh didn't start a turn")
+	}
+	next, _ := refreshed.Update(msg)
+	refreshed = next.(model)
+	if refreshed.turn != nil || refreshed.thinking {
+		t.Error("refresh didn't end its turn")
+	}
+	if got := refreshed.messages[2].Content; got == "stale" {
+		t.Error("tool result wasn't refreshed")
+	}
+
+	// Esc cancels it, and its results are dropped when they arrive
+	cancelled := m
+	cancelled.messages = append([]ai.Message(nil), history...)
+	msg = cancelled.refreshTools()()
+	next, _ = cancelled.handleKey(tea.KeyMsg{Type: tea.KeyEsc})
+	cancelled = next.(model)
+	if cancelled.turn != nil {
+		t.Fatal("Esc didn't cancel the refresh")
+	}
+	next, _ = cancelled.Update(msg)
+	if got := next.(model).messages[2].Content; got != "stale" {
+		t.Errorf("cancelled refresh replaced the result with %q", got)
+	}
+}
+
+func TestExportRefusesToOverwrite(t *testing.T) {
+	m := newTestModel(t, &fakeProvider{})
+	m.messages = []ai.Message{{Role: "user", Content: "hello"}}
+	path := filepath.Join(t.TempDir(), "README.md")
+	if err := os.WriteFile(path, []byte("# project\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	next, _ := m.handleCommand("/export " + path)
+	if notice := next.(model).notice; !strings.Contains(notice, "already exists") {
+		t.Errorf("/export over an existing file: %s", notice)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "# project\n" {
+		t.Fatalf("README.md was overwritten: %q", data)
+	}
+
+	next, _ = m.handleCommand("/export -f " + path)
+	if notice := next.(model).notice; notice != "Exported to "+path {
+		t.Errorf("/export -f: %s", notice)
+	}
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), "hello") {
+		t.Errorf("/export -f didn't replace the file: %q", data)
+	}
+}
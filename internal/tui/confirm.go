@@ -0,0 +1,214 @@
+package tui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"kilo/internal/ai"
+	"kilo/internal/theme"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// declinedToolResult is fed back to Claude when the user rejects a tool call
+const declinedToolResult = "The user declined to run this tool call."
+
+func newInputEditor() textarea.Model {
+	editor := textarea.New()
+	editor.Prompt = "┃ "
+	editor.CharLimit = 4000
+	editor.SetWidth(80)
+	editor.SetHeight(3)
+	editor.ShowLineNumbers = false
+	return editor
+}
+
+// needsConfirmation reports whether a tool call must be approved before running
+func (m model) needsConfirmation(call ai.ToolCall) bool {
+	// Sensitive calls are asked about even when confirmation is off, and
+	// "trust all" doesn't cover them
+	if m.executor.ConfirmReason(call) != "" {
+		return !m.trust.calls[trustKey(call)]
+	}
+	// Dangerous tools (bash, file writes) ask unless the user opted out
+	dangerous := m.confirmDangerous && m.executor.RequiresConfirmation(call.Name)
+	if !dangerous && (!m.confirmTools || m.executor.IsIdempotent(call.Name)) {
+		return false
+	}
+	if m.trust.all || m.trust.calls[trustKey(call)] {
+		return false
+	}
+	// Harmless reads like ls or git status aren't worth asking about
+	return !m.executor.IsSafeCommand(call)
+}
+
+// sessionTrust remembers "don't ask again" decisions. It lives only in memory,
+// so every new session starts out prompting again.
+type sessionTrust struct {
+	all   bool            // Skip confirmation for every tool call
+	calls map[string]bool // Skip confirmation for these exact calls
+}
+
+// trustKey identifies a tool call by tool name and normalized input
+func trustKey(call ai.ToolCall) string {
+	input := call.Input
+	if compacted, err := compactToolInput(input); err == nil {
+		input = compacted
+	}
+	return call.Name + "\x00" + input
+}
+
+// handleToolConfirm answers the tool confirmation prompt
+func (m model) handleToolConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "y", "Y", "enter":
+		return m.runConfirmed()
+
+	case "a", "A":
+		m.trust.calls[trustKey(*m.confirming)] = true
+		m.notice = fmt.Sprintf("Won't ask again this session for this %s call", m.confirming.Name)
+		return m.runConfirmed()
+
+	case "t", "T":
+		m.trust.all = true
+		m.notice = "Trusting all tool calls for the rest of this session"
+		return m.runConfirmed()
+
+	case "n", "N", "esc":
+		call := *m.confirming
+		m.confirming = nil
+		m.turn.resume()
+		m.messages = append(m.messages, ai.Message{
+			Role:         "tool",
+			Content:      declinedToolResult,
+			ToolCallID:   call.ID,
+			ToolCallName: call.Name,
+			ToolError:    true,
+		})
+		cmd := m.nextToolCall()
+		m.refreshViewport()
+		return m, cmd
+
+	case "ctrl+r":
+		// Stop the whole turn, not just this call
+		return m.cancelTurn()
+
+	case "e", "E":
+		m.editingInput = true
+		m.editor.SetValue(indentJSON(m.confirming.Input))
+		m.input.Blur()
+		return m, m.editor.Focus()
+
+	case "ctrl+c":
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+// handleInputEditor edits the pending tool call's JSON input
+func (m model) handleInputEditor(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlS:
+		edited, err := compactToolInput(m.editor.Value())
+		if err != nil {
+			m.notice = fmt.Sprintf("Invalid input: %v", err)
+			return m, nil
+		}
+		m.confirming.Input = edited
+		m.recordEditedInput(m.confirming.ID, edited)
+		m.notice = "Running edited tool input"
+		return m.runConfirmed()
+
+	case tea.KeyEsc:
+		m.editingInput = false
+		m.editor.Blur()
+		return m, nil
+
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+	}
+
+	var cmd tea.Cmd
+	m.editor, cmd = m.editor.Update(msg)
+	return m, cmd
+}
+
+// runConfirmed executes the approved tool call and resumes the turn
+func (m model) runConfirmed() (tea.Model, tea.Cmd) {
+	call := *m.confirming
+	m.confirming = nil
+	m.editingInput = false
+	m.editor.Blur()
+	m.input.Focus()
+	m.turn.resume()
+	return m, m.executeTool(call)
+}
+
+// recordEditedInput updates the history so Claude sees the input that actually ran
+func (m *model) recordEditedInput(callID, input string) {
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].Role == "assistant" && m.messages[i].ToolCallID == callID {
+			m.messages[i].ToolCallInput = input
+			return
+		}
+	}
+}
+
+// compactToolInput validates edited tool input as a JSON object and compacts it
+func compactToolInput(input string) (string, error) {
+	var params map[string]any
+	if err := json.Unmarshal([]byte(input), &params); err != nil {
+		return "", fmt.Errorf("tool input must be a JSON object: %w", err)
+	}
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, []byte(input)); err != nil {
+		return "", err
+	}
+	return compacted.String(), nil
+}
+
+// indentJSON pretty-prints JSON for editing, falling back to the raw input
+func indentJSON(input string) string {
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, []byte(input), "", "  "); err != nil {
+		return input
+	}
+	return indented.String()
+}
+
+// renderConfirmPanel shows the pending tool call in place of the input box
+func (m model) renderConfirmPanel() string {
+	if m.editingInput {
+		return m.editor.View()
+	}
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(theme.Active().Warning).
+		Bold(true)
+	inputStyle := lipgloss.NewStyle().
+		Foreground(theme.Active().Text)
+
+	summary := strings.Join(strings.Fields(m.confirming.Input), " ")
+	// Show shell commands as they'll run rather than as JSON
+	if command := m.executor.Command(*m.confirming); command != "" {
+		summary = "$ " + strings.Join(strings.Fields(command), " ")
+	}
+	if maxLen := m.width - 10; maxLen > 0 && len(summary) > maxLen {
+		summary = summary[:maxLen] + "…"
+	}
+
+	lines := []string{
+		titleStyle.Render(fmt.Sprintf("Run %s?", m.confirming.Name)),
+		inputStyle.Render(summary),
+	}
+	if reason := m.executor.ConfirmReason(*m.confirming); reason != "" {
+		lines = append(lines, titleStyle.Render("⚠ This call "+reason))
+	} else {
+		lines = append(lines, "")
+	}
+	return lipgloss.JoinVertical(lipgloss.Left, lines...)
+}
@@ -0,0 +1,129 @@
+package tui
+
+import (
+	"strings"
+
+	"kilo/internal/diff"
+	"kilo/internal/theme"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Diff styles follow the active theme, so they're built when used
+
+func diffHeaderStyle() lipgloss.Style {
+	return lipgloss.NewStyle().Foreground(theme.Active().Text).Bold(true)
+}
+
+func diffHunkStyle() lipgloss.Style {
+	return lipgloss.NewStyle().Foreground(theme.Active().Secondary)
+}
+
+func diffAddStyle() lipgloss.Style {
+	return lipgloss.NewStyle().Foreground(theme.Active().Added)
+}
+
+func diffDelStyle() lipgloss.Style {
+	return lipgloss.NewStyle().Foreground(theme.Active().Removed)
+}
+
+func diffContext() lipgloss.Style {
+	return lipgloss.NewStyle().Foreground(theme.Active().Muted)
+}
+
+// Word-level highlights for the tokens that actually changed
+
+func wordAddStyle() lipgloss.Style {
+	return lipgloss.NewStyle().Foreground(theme.Active().Inverse).Background(theme.Active().Added)
+}
+
+func wordDelStyle() lipgloss.Style {
+	return lipgloss.NewStyle().Foreground(theme.Active().Inverse).Background(theme.Active().Removed)
+}
+
+// isUnifiedDiff reports whether content looks like a unified diff
+func isUnifiedDiff(content string) bool {
+	hasHeader, hasHunk := false, false
+	for _, line := range strings.Split(content, "\n") {
+		switch {
+		case strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "+++ "):
+			hasHeader = true
+		case strings.HasPrefix(line, "@@"):
+			hasHunk = true
+		}
+		if hasHeader && hasHunk {
+			return true
+		}
+	}
+	return false
+}
+
+// renderDiff colors a unified diff. In word mode, a run of removed lines
+// followed by the same number of added lines is paired up and only the changed
+// tokens within each pair are highlighted.
+func renderDiff(content string, wordDiff bool) string {
+	lines := strings.Split(content, "\n")
+	rendered := make([]string, 0, len(lines))
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "--- ") || strings.HasPrefix(line, "+++ "):
+			rendered = append(rendered, diffHeaderStyle().Render(line))
+		case strings.HasPrefix(line, "@@"):
+			rendered = append(rendered, diffHunkStyle().Render(line))
+		case strings.HasPrefix(line, "-"):
+			removed := changeRun(lines[i:], "-")
+			added := changeRun(lines[i+len(removed):], "+")
+			if wordDiff && len(removed) == len(added) {
+				// Keep unified layout: all removals first, then all additions
+				adds := make([]string, len(added))
+				for k := range removed {
+					del, add := renderWordPair(removed[k][1:], added[k][1:])
+					rendered = append(rendered, del)
+					adds[k] = add
+				}
+				rendered = append(rendered, adds...)
+				i += len(removed) + len(added) - 1
+				continue
+			}
+			rendered = append(rendered, diffDelStyle().Render(line))
+		case strings.HasPrefix(line, "+"):
+			rendered = append(rendered, diffAddStyle().Render(line))
+		default:
+			rendered = append(rendered, diffContext().Render(line))
+		}
+	}
+
+	return strings.Join(rendered, "\n")
+}
+
+// changeRun returns the leading lines that start with prefix (but are not file headers)
+func changeRun(lines []string, prefix string) []string {
+	n := 0
+	for n < len(lines) && strings.HasPrefix(lines[n], prefix) && !strings.HasPrefix(lines[n], prefix+prefix+prefix+" ") {
+		n++
+	}
+	return lines[:n]
+}
+
+// renderWordPair renders a removed/added line pair with word-level highlights
+func renderWordPair(oldLine, newLine string) (string, string) {
+	var del, add strings.Builder
+	del.WriteString(diffDelStyle().Render("-"))
+	add.WriteString(diffAddStyle().Render("+"))
+
+	for _, seg := range diff.Words(oldLine, newLine) {
+		switch seg.Kind {
+		case diff.Equal:
+			del.WriteString(diffDelStyle().Render(seg.Text))
+			add.WriteString(diffAddStyle().Render(seg.Text))
+		case diff.Delete:
+			del.WriteString(wordDelStyle().Render(seg.Text))
+		case diff.Insert:
+			add.WriteString(wordAddStyle().Render(seg.Text))
+		}
+	}
+
+	return del.String(), add.String()
+}
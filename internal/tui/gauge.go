@@ -0,0 +1,60 @@
+package tui
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"kilo/internal/ai"
+	"kilo/internal/theme"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// gaugeWidth is how many cells the context gauge's bar spans
+const gaugeWidth = 10
+
+// Context window fill at which the gauge turns to the warning and danger
+// colors
+const (
+	contextWarnFill   = 0.7
+	contextDangerFill = 0.9
+)
+
+// contextFill returns the fraction of the model's context window the latest
+// request and reply took, or 0 before there is one
+func (m model) contextFill() float64 {
+	return float64(m.contextTokens) / float64(ai.ContextWindow(m.client.Model()))
+}
+
+// contextGauge draws how full the context window is, e.g.
+// "Context ███░░░░░░░ 31%", or "" until a reply says how full it is
+func (m model) contextGauge() string {
+	if m.contextTokens == 0 {
+		return ""
+	}
+	fill := m.contextFill()
+	filled := min(int(math.Round(fill*gaugeWidth)), gaugeWidth)
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", gaugeWidth-filled)
+	label := fmt.Sprintf("Context %s %d%%", bar, int(fill*100))
+
+	style := lipgloss.NewStyle().Foreground(theme.Active().Secondary)
+	switch {
+	case fill >= contextDangerFill:
+		style = style.Foreground(theme.Active().Danger).Bold(true)
+		label += " (/trim)"
+	case fill >= contextWarnFill:
+		style = style.Foreground(theme.Active().Warning)
+	}
+	return style.Render(label)
+}
+
+// withGauge right-aligns the gauge on the status line when there's room,
+// and otherwise follows the status text
+func (m model) withGauge(status, gauge string) string {
+	if gauge == "" {
+		return status
+	}
+	gap := m.width - lipgloss.Width(status) - lipgloss.Width(gauge) - 2
+	return status + strings.Repeat(" ", max(gap, 1)) + gauge
+}
@@ -0,0 +1,120 @@
+package tui
+
+import (
+	"encoding/json"
+	"os"
+	"regexp"
+	"strings"
+
+	"kilo/internal/ai"
+	"kilo/internal/theme"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// maxHighlightBytes skips highlighting for outputs too large to be worth it
+const maxHighlightBytes = 64 * 1024
+
+// fencePattern matches a fenced code block and its optional language tag
+var fencePattern = regexp.MustCompile("(?s)```([\\w+#.-]*)[^\\n]*\\n(.*?)\\n?```")
+
+// numberedLine matches the "    12\t" prefix read_file puts on each line
+var numberedLine = regexp.MustCompile(`^(\s*\d+\t)(.*)$`)
+
+// colorDisabled reports whether KILO_NO_COLOR (or the NO_COLOR convention)
+// asks for plain output
+func colorDisabled() bool {
+	return envEnabled("KILO_NO_COLOR") || os.Getenv("NO_COLOR") != ""
+}
+
+// highlightToolOutput colors the fenced code blocks in tool output, using the
+// block's language tag or else guessing from its contents. Other text is
+// returned unchanged.
+func highlightToolOutput(content string) string {
+	if colorDisabled() || len(content) > maxHighlightBytes || !strings.Contains(content, "```") {
+		return content
+	}
+	return fencePattern.ReplaceAllStringFunc(content, func(block string) string {
+		match := fencePattern.FindStringSubmatch(block)
+		lexer := lexers.Get(match[1])
+		if lexer == nil {
+			lexer = lexers.Analyse(match[2])
+		}
+		if lexer == nil {
+			return block
+		}
+		return highlight(match[2], lexer)
+	})
+}
+
+// highlightFile colors a file's contents by the language its name suggests,
+// keeping read_file's line-number prefixes uncolored. Content that doesn't
+// look like a known language is returned unchanged.
+func highlightFile(content, filename string) string {
+	if colorDisabled() || len(content) > maxHighlightBytes || strings.HasPrefix(content, "Error: ") {
+		return content
+	}
+	lexer := lexers.Match(filename)
+	if lexer == nil {
+		return content
+	}
+
+	// Highlight the code without the prefixes so the lexer sees real source
+	trimmed := strings.TrimRight(content, "\n")
+	lines := strings.Split(trimmed, "\n")
+	prefixes := make([]string, len(lines))
+	code := make([]string, len(lines))
+	for i, line := range lines {
+		if match := numberedLine.FindStringSubmatch(line); match != nil {
+			prefixes[i], code[i] = match[1], match[2]
+		} else {
+			code[i] = line
+		}
+	}
+
+	highlighted := strings.Split(highlight(strings.Join(code, "\n"), lexer), "\n")
+	if len(highlighted) != len(lines) {
+		return content
+	}
+	for i := range highlighted {
+		highlighted[i] = diffContext().Render(prefixes[i]) + highlighted[i]
+	}
+	return strings.Join(highlighted, "\n") + content[len(trimmed):]
+}
+
+// highlight formats code with lexer for a 256-color terminal, falling back
+// to the plain code on error
+func highlight(code string, lexer chroma.Lexer) string {
+	formatter := formatters.Get("terminal256")
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, code)
+	if err != nil {
+		return code
+	}
+	var out strings.Builder
+	if err := formatter.Format(&out, styles.Get(theme.Active().Code), iterator); err != nil {
+		return code
+	}
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+// toolCallPath returns the file a read_file call read, or "" for other calls
+func toolCallPath(messages []ai.Message, callID string) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		msg := messages[i]
+		if msg.Role != "assistant" || msg.ToolCallID != callID {
+			continue
+		}
+		if msg.ToolCallName != "read_file" {
+			return ""
+		}
+		var input struct {
+			Path string `json:"path"`
+		}
+		json.Unmarshal([]byte(msg.ToolCallInput), &input)
+		return input.Path
+	}
+	return ""
+}
@@ -0,0 +1,105 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"kilo/internal/ai"
+	"kilo/internal/theme"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/charmbracelet/x/cellbuf"
+)
+
+// maxInfoWidth caps the /info panel's width on wide terminals
+const maxInfoWidth = 72
+
+// renderInfoPanel draws the /info box: the session's configuration and what
+// it has used so far
+func (m model) renderInfoPanel() string {
+	colors := theme.Active()
+	labelStyle := lipgloss.NewStyle().Foreground(colors.Secondary).Bold(true)
+	dimStyle := lipgloss.NewStyle().Foreground(colors.Muted).Italic(true)
+
+	// Border and padding take 4 columns
+	width := max(min(m.viewport.Width-4, maxInfoWidth), 20)
+	valueWidth := width - 4 - 12
+
+	row := func(label, value string) string {
+		lines := strings.Split(cellbuf.Wrap(value, valueWidth, ","), "\n")
+		for i := range lines {
+			lines[i] = strings.TrimLeft(lines[i], " ")
+		}
+		return labelStyle.Render(fmt.Sprintf("%-12s", label)) +
+			strings.Join(lines, "\n"+strings.Repeat(" ", 12))
+	}
+
+	usage := "none yet"
+	if m.usage.Total() > 0 {
+		usage = fmt.Sprintf("%s in, %s out", formatTokens(m.usage.InputTokens), formatTokens(m.usage.OutputTokens))
+	}
+	window := "not measured yet"
+	if m.contextTokens > 0 {
+		window = fmt.Sprintf("%s of %s (%d%%)", formatTokens(m.contextTokens),
+			formatTokens(ai.ContextWindow(m.client.Model())), int(m.contextFill()*100))
+	}
+	var names []string
+	for _, tool := range m.executor.GetAvailableTools() {
+		names = append(names, tool.Name)
+	}
+
+	rows := []string{
+		labelStyle.Render("Session info"),
+		"",
+		row("Model", m.client.Model()),
+		row("Directory", m.executor.WorkingDir()),
+		row("Messages", fmt.Sprint(len(m.messages))),
+		row("Tokens", usage),
+		row("Context", window),
+		row("Started", fmt.Sprintf("%s (%s)", m.startedAt.Format("Jan 2 15:04"), sessionAge(time.Since(m.startedAt)))),
+		row(fmt.Sprintf("Tools (%d)", len(names)), strings.Join(names, ", ")),
+		"",
+		dimStyle.Render("/info or Esc to close"),
+	}
+
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(colors.Accent).
+		Padding(0, 1).
+		Width(width - 2).
+		Render(strings.Join(rows, "\n"))
+}
+
+// sessionAge describes how long ago the session started, to the minute
+func sessionAge(d time.Duration) string {
+	if d < time.Minute {
+		return "just now"
+	}
+	return strings.TrimSuffix(d.Truncate(time.Minute).String(), "0s") + " ago"
+}
+
+// overlay draws panel centered over background, which is width cells wide,
+// leaving the background visible around it
+func overlay(background, panel string, width int) string {
+	lines := strings.Split(background, "\n")
+	panelLines := strings.Split(panel, "\n")
+	panelWidth := lipgloss.Width(panel)
+	x := max((width-panelWidth)/2, 0)
+	top := max((len(lines)-len(panelLines))/2, 0)
+
+	for i, panelLine := range panelLines {
+		row := top + i
+		if row >= len(lines) {
+			lines = append(lines, "")
+		}
+		line := lines[row]
+		left := ansi.Truncate(line, x, "")
+		left += strings.Repeat(" ", x-ansi.StringWidth(left))
+		right := ansi.TruncateLeft(line, x+panelWidth, "")
+		// Reset styles so the background's colors don't bleed into the panel
+		lines[row] = left + "\x1b[0m" + panelLine + "\x1b[0m" + right
+	}
+	return strings.Join(lines, "\n")
+}
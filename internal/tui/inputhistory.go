@@ -0,0 +1,71 @@
+package tui
+
+import "kilo/internal/storage"
+
+// inputHistory is the list of previously submitted inputs that Up and Down
+// step through, shell-style
+type inputHistory struct {
+	entries []string // Oldest first
+	index   int      // Entry being shown; len(entries) when not browsing
+	draft   string   // What was typed before browsing started
+}
+
+// newInputHistory loads the inputs saved by earlier sessions
+func newInputHistory() inputHistory {
+	// Recall is a convenience; an unreadable file just starts empty
+	entries, _ := storage.LoadInputHistory()
+	return inputHistory{entries: entries, index: len(entries)}
+}
+
+// add records a submitted input and stops browsing
+func (h *inputHistory) add(input string) {
+	if n := len(h.entries); n == 0 || h.entries[n-1] != input {
+		h.entries = append(h.entries, input)
+		storage.AppendInputHistory(input)
+	}
+	h.index = len(h.entries)
+	h.draft = ""
+}
+
+// recallPrevious shows the input before the one on screen, keeping the
+// typed draft so recallNext can return to it. It reports false at the oldest.
+func (m *model) recallPrevious() bool {
+	h := &m.history
+	if h.index == 0 {
+		return false
+	}
+	if h.index == len(h.entries) {
+		h.draft = m.input.Value()
+	}
+	h.index--
+	m.input.SetValue(h.entries[h.index])
+	return true
+}
+
+// recallNext shows the input after the one on screen, or the draft after
+// the newest. It reports false when not browsing.
+func (m *model) recallNext() bool {
+	h := &m.history
+	if h.index >= len(h.entries) {
+		return false
+	}
+	h.index++
+	if h.index == len(h.entries) {
+		m.input.SetValue(h.draft)
+	} else {
+		m.input.SetValue(h.entries[h.index])
+	}
+	return true
+}
+
+// onFirstInputRow reports whether the cursor is on the input's top row, where
+// Up has nowhere left to move within the text
+func (m model) onFirstInputRow() bool {
+	return m.input.Line() == 0 && m.input.LineInfo().RowOffset == 0
+}
+
+// onLastInputRow reports whether the cursor is on the input's bottom row
+func (m model) onLastInputRow() bool {
+	info := m.input.LineInfo()
+	return m.input.Line() == m.input.LineCount()-1 && info.RowOffset == info.Height-1
+}
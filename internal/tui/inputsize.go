@@ -0,0 +1,69 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// The input box grows with its content between these heights, then scrolls
+const (
+	minInputHeight = 3
+	maxInputHeight = 10
+)
+
+// layout sizes the conversation and input to the terminal, giving the
+// conversation whatever the input box doesn't use
+func (m *model) layout() {
+	// Clamp so a tiny or mid-resize terminal never yields negative sizes
+	// Inside the conversation box's border and padding
+	m.viewport.Width = max(m.width-6, 1)
+	m.viewport.Height = max(m.height-10-m.extraInputRows(), 1)
+	m.input.SetWidth(max(m.width-4, 1))
+	m.editor.SetWidth(max(m.width-4, 1))
+}
+
+// extraInputRows is how far the input box has grown past its minimum
+func (m model) extraInputRows() int {
+	return m.input.Height() - minInputHeight
+}
+
+// fitInput resizes the input box to its wrapped content, within
+// minInputHeight and maxInputHeight, and shrinks the conversation to match
+func (m *model) fitInput() {
+	if !m.ready {
+		return
+	}
+	width := max(m.input.Width(), 1)
+	rows := 0
+	for _, line := range strings.Split(m.input.Value(), "\n") {
+		// One extra column for the cursor at the end of the line
+		rows += (lipgloss.Width(line) + width) / width
+	}
+	height := min(max(rows, minInputHeight), maxInputHeight)
+	if height == m.input.Height() {
+		return
+	}
+	m.input.SetHeight(height)
+	m.layout()
+	if m.atBottom {
+		m.viewport.GotoBottom()
+	}
+}
+
+// inputCounter summarizes the typed message, e.g. "12 words · 2 lines ·
+// 80/2000 chars", or "" when the input is empty
+func (m model) inputCounter() string {
+	value := m.input.Value()
+	if value == "" {
+		return ""
+	}
+	return fmt.Sprintf("%d words · %d lines · %d/%d chars",
+		len(strings.Fields(value)), m.input.LineCount(), len([]rune(value)), m.input.CharLimit)
+}
+
+// nearCharLimit reports whether the input is within 10% of its limit
+func (m model) nearCharLimit() bool {
+	return len([]rune(m.input.Value())) >= m.input.CharLimit*9/10
+}
@@ -0,0 +1,260 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"kilo/internal/ai"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// inputMode is the interaction context that decides what a key press means.
+// In particular Enter sends a message only in modeChat; in dialogs it confirms.
+type inputMode int
+
+const (
+	modeChat           inputMode = iota // Typing and sending messages
+	modeReplay                          // Stepping through a saved session
+	modeConfirmRefresh                  // Approving /refresh-tools
+	modeConfirmNew                      // Approving Ctrl+N
+	modeConfirmTool                     // Approving a pending tool call
+	modeEditToolInput                   // Editing a pending tool call's input
+	modeFilePicker                      // Choosing a file to attach
+	modeSessionPicker                   // Choosing a saved session to load
+	modeSearch                          // Searching the conversation
+)
+
+// mode derives the current input mode from model state, most specific first
+func (m model) mode() inputMode {
+	switch {
+	case m.replay != nil:
+		return modeReplay
+	case m.editingInput:
+		return modeEditToolInput
+	case m.confirming != nil:
+		return modeConfirmTool
+	case m.confirmRefresh:
+		return modeConfirmRefresh
+	case m.confirmNew:
+		return modeConfirmNew
+	case m.picker != nil:
+		return modeFilePicker
+	case m.sessions != nil:
+		return modeSessionPicker
+	case m.search != nil:
+		return modeSearch
+	}
+	return modeChat
+}
+
+// handleKey routes a key press to the handler for the current mode
+func (m model) handleKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch m.mode() {
+	case modeReplay:
+		return m.handleReplayKey(msg)
+	case modeEditToolInput:
+		return m.handleInputEditor(msg)
+	case modeConfirmTool:
+		return m.handleToolConfirm(msg)
+	case modeConfirmRefresh:
+		return m.handleRefreshConfirm(msg)
+	case modeConfirmNew:
+		return m.handleNewConfirm(msg)
+	case modeFilePicker:
+		return m.handlePickerKey(msg)
+	case modeSessionPicker:
+		return m.handleSessionKey(msg)
+	case modeSearch:
+		return m.handleSearchKey(msg)
+	}
+	return m.handleChatKey(msg)
+}
+
+// handleChatKey handles keys while typing: Enter sends, everything else edits
+// the input or scrolls the conversation
+func (m model) handleChatKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+
+	case tea.KeyEsc:
+		if m.showInfo {
+			m.showInfo = false
+			return m, nil
+		}
+		// Esc stops a running turn; only when idle does it quit
+		if m.turn != nil {
+			return m.cancelTurn()
+		}
+		return m, tea.Quit
+
+	case tea.KeyCtrlR:
+		// Like Esc, but never quits, so it's safe to press as the turn ends
+		if m.turn != nil {
+			return m.cancelTurn()
+		}
+		m.notice = "Nothing to cancel"
+		return m, nil
+
+	case tea.KeyEnter:
+		return m.submitInput()
+
+	case tea.KeyCtrlT:
+		return m.openFilePicker()
+
+	case tea.KeyCtrlY:
+		return m.copyLastResponse(), nil
+
+	case tea.KeyCtrlO:
+		return m.editLastMessage(), nil
+
+	case tea.KeyCtrlF:
+		return m.openSearch(), nil
+
+	case tea.KeyCtrlN:
+		return m.requestNewConversation(), nil
+
+	case tea.KeyCtrlX:
+		if m.queued != "" {
+			m.queued = ""
+			m.notice = "Queued message discarded"
+			return m, nil
+		}
+
+	case tea.KeyUp:
+		// Only at the top row, so Up still moves through multi-line input
+		if m.onFirstInputRow() && m.recallPrevious() {
+			return m, nil
+		}
+
+	case tea.KeyDown:
+		if m.onLastInputRow() && m.recallNext() {
+			return m, nil
+		}
+
+	case tea.KeyPgUp, tea.KeyPgDown:
+		var cmd tea.Cmd
+		m.viewport, cmd = m.viewport.Update(msg)
+		m.syncScroll()
+		return m, cmd
+	}
+
+	// Other keys edit the input; letters must not scroll the conversation
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+// submitInput sends the typed message to Claude or runs it as a slash command
+func (m model) submitInput() (tea.Model, tea.Cmd) {
+	userInput := strings.TrimSpace(m.input.Value())
+	if userInput == "" {
+		return m, nil
+	}
+	m.history.add(userInput)
+
+	// Slash commands are handled locally, never sent to Claude
+	if strings.HasPrefix(userInput, "/") {
+		m.input.Reset()
+		return m.handleCommand(userInput)
+	}
+
+	// Hold the message until the current turn finishes
+	if m.thinking {
+		m.queued = userInput
+		m.input.Reset()
+		m.notice = "Message queued; it will be sent when the current response finishes"
+		return m, nil
+	}
+
+	m.input.Reset()
+	// Sending a message means the user wants to follow the reply
+	m.atBottom = true
+	return m.sendMessage(userInput)
+}
+
+// sendMessage adds a user message and starts a turn for it
+func (m model) sendMessage(userInput string) (tea.Model, tea.Cmd) {
+	// Inline any referenced tool outputs
+	content, err := m.expandRefs(userInput)
+	if err != nil {
+		m.notice = fmt.Sprintf("Can't send: %v", err)
+		return m, nil
+	}
+	attached, err := m.attachmentContent()
+	if err != nil {
+		m.notice = fmt.Sprintf("Can't send: %v", err)
+		return m, nil
+	}
+	// Files mentioned as @path are read from the message as typed, not
+	// from the tool output inlined above
+	mentioned, images, failed := m.mentionContent(userInput)
+	content += attached + mentioned
+	m.attachments = nil
+
+	m.notice = ""
+	if len(failed) > 0 {
+		m.notice = "Not attached: " + strings.Join(failed, ", ")
+	}
+
+	// Add user message, keeping what was typed for editing it later
+	message := ai.Message{Role: "user", Content: content, Images: images}
+	if content != userInput {
+		message.Prompt = userInput
+	}
+	m.messages = append(m.messages, message)
+
+	// Send message to Claude
+	cmd := m.startTurn()
+	m.refreshViewport()
+	return m, cmd
+}
+
+// editLastMessage takes back the last user message, dropping it and every
+// reply and tool call that followed, and puts its text in the input box.
+// Sending it again regenerates the response from that point.
+func (m model) editLastMessage() model {
+	if m.turn != nil {
+		m.notice = "Cancel the current response (Esc) before editing your message"
+		return m
+	}
+	if strings.TrimSpace(m.input.Value()) != "" {
+		m.notice = "Clear the input before editing your last message"
+		return m
+	}
+	last := -1
+	for i, msg := range m.messages {
+		if msg.Role == "user" {
+			last = i
+		}
+	}
+	if last < 0 {
+		m.notice = "No message to edit"
+		return m
+	}
+
+	// The turns before it each ended with a reply or a tool result, so the
+	// history stays valid to send
+	prompt := m.messages[last].Prompt
+	if prompt == "" {
+		prompt = m.messages[last].Content
+	}
+	m.messages = m.messages[:last]
+	m.contextTokens = 0
+	m.input.SetValue(prompt)
+	m.input.CursorEnd()
+	m.notice = "Editing your last message; press Enter to send it again"
+	m.refreshViewport()
+	return m
+}
+
+// sendQueued sends the message typed while the last turn was running, if any
+func (m model) sendQueued() (tea.Model, tea.Cmd) {
+	if m.queued == "" || m.thinking {
+		return m, nil
+	}
+	queued := m.queued
+	m.queued = ""
+	return m.sendMessage(queued)
+}
@@ -0,0 +1,485 @@
+package tui
+
+import (
+	"testing"
+
+	"kilo/internal/ai"
+	"kilo/internal/storage"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// keyPress builds the key message Bubble Tea sends for a key name as
+// msg.String() reports it
+func keyPress(name string) tea.KeyMsg {
+	switch name {
+	case "enter":
+		return tea.KeyMsg{Type: tea.KeyEnter}
+	case "esc":
+		return tea.KeyMsg{Type: tea.KeyEsc}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(name)}
+}
+
+// quits reports whether cmd asks the program to exit
+func quits(cmd tea.Cmd) bool {
+	if cmd == nil {
+		return false
+	}
+	_, ok := cmd().(tea.QuitMsg)
+	return ok
+}
+
+var timeCall = ai.ToolCall{ID: "toolu_1", Name: "get_time", Input: "{}"}
+
+// withTurn puts the model mid-turn, after the user asked something
+func withTurn(m *model) {
+	m.messages = []ai.Message{{Role: "user", Content: "what time is it?"}}
+	m.turn = newTurn(m.turnBudget, 0)
+	m.thinking = true
+}
+
+// confirmingTool puts the model at the prompt for a get_time call
+func confirmingTool(m *model) {
+	withTurn(m)
+	m.messages = append(m.messages, ai.Message{Role: "assistant", ToolCallID: timeCall.ID, ToolCallName: timeCall.Name, ToolCallInput: timeCall.Input})
+	call := timeCall
+	m.confirming = &call
+	m.turn.pause()
+}
+
+// withHistory gives the model a finished exchange that used a tool
+func withHistory(m *model) {
+	m.messages = []ai.Message{
+		{Role: "user", Content: "what time is it?"},
+		{Role: "assistant", ToolCallID: timeCall.ID, ToolCallName: timeCall.Name, ToolCallInput: timeCall.Input},
+		{Role: "tool", ToolCallID: timeCall.ID, ToolCallName: timeCall.Name, Content: "noon"},
+		{Role: "assistant", Content: "It's noon, like the needle says."},
+	}
+}
+
+func TestHandleKey(t *testing.T) {
+	tests := []struct {
+		name  string
+		mode  inputMode
+		setup func(m *model)
+		keys  []string
+		want  func(t *testing.T, m model, cmd tea.Cmd)
+	}{
+		// Chat input
+		{
+			name: "chat enter sends the message",
+			mode: modeChat,
+			setup: func(m *model) {
+				m.input.SetValue("hello")
+			},
+			keys: []string{"enter"},
+			want: func(t *testing.T, m model, cmd tea.Cmd) {
+				if m.turn == nil || roles(m.messages) != "user" || m.messages[0].Content != "hello" {
+					t.Errorf("turn = %v, messages = %+v", m.turn, m.messages)
+				}
+			},
+		},
+		{
+			name: "chat enter with empty input does nothing",
+			mode: modeChat,
+			keys: []string{"enter"},
+			want: func(t *testing.T, m model, cmd tea.Cmd) {
+				if m.turn != nil || len(m.messages) != 0 || cmd != nil {
+					t.Errorf("turn = %v, messages = %+v", m.turn, m.messages)
+				}
+			},
+		},
+		{
+			name: "chat enter while thinking queues the message",
+			mode: modeChat,
+			setup: func(m *model) {
+				withTurn(m)
+				m.input.SetValue("and tomorrow?")
+			},
+			keys: []string{"enter"},
+			want: func(t *testing.T, m model, cmd tea.Cmd) {
+				if m.queued != "and tomorrow?" || len(m.messages) != 1 {
+					t.Errorf("queued = %q, messages = %+v", m.queued, m.messages)
+				}
+			},
+		},
+		{
+			name: "chat esc when idle quits",
+			mode: modeChat,
+			keys: []string{"esc"},
+			want: func(t *testing.T, m model, cmd tea.Cmd) {
+				if !quits(cmd) {
+					t.Error("didn't quit")
+				}
+			},
+		},
+		{
+			name:  "chat esc cancels a running turn",
+			mode:  modeChat,
+			setup: withTurn,
+			keys:  []string{"esc"},
+			want: func(t *testing.T, m model, cmd tea.Cmd) {
+				if m.turn != nil || m.thinking || quits(cmd) {
+					t.Errorf("turn = %v, thinking = %v", m.turn, m.thinking)
+				}
+			},
+		},
+		{
+			name: "chat y and n are typed",
+			mode: modeChat,
+			keys: []string{"y", "n"},
+			want: func(t *testing.T, m model, cmd tea.Cmd) {
+				if got := m.input.Value(); got != "yn" {
+					t.Errorf("input = %q", got)
+				}
+			},
+		},
+
+		// Tool confirmation
+		{
+			name:  "tool confirm enter runs the call",
+			mode:  modeConfirmTool,
+			setup: confirmingTool,
+			keys:  []string{"enter"},
+			want: func(t *testing.T, m model, cmd tea.Cmd) {
+				if m.confirming != nil || m.turn == nil || m.turn.waiting || cmd == nil {
+					t.Errorf("confirming = %v, turn = %+v", m.confirming, m.turn)
+				}
+			},
+		},
+		{
+			name:  "tool confirm y runs the call",
+			mode:  modeConfirmTool,
+			setup: confirmingTool,
+			keys:  []string{"y"},
+			want: func(t *testing.T, m model, cmd tea.Cmd) {
+				if m.confirming != nil || m.turn == nil || cmd == nil {
+					t.Errorf("confirming = %v, turn = %v", m.confirming, m.turn)
+				}
+			},
+		},
+		{
+			name:  "tool confirm n declines the call",
+			mode:  modeConfirmTool,
+			setup: confirmingTool,
+			keys:  []string{"n"},
+			want:  wantDeclined,
+		},
+		{
+			name:  "tool confirm esc declines the call",
+			mode:  modeConfirmTool,
+			setup: confirmingTool,
+			keys:  []string{"esc"},
+			want:  wantDeclined,
+		},
+
+		// /refresh-tools confirmation
+		{
+			name: "refresh confirm enter starts the refresh",
+			mode: modeConfirmRefresh,
+			setup: func(m *model) {
+				withHistory(m)
+				m.confirmRefresh = true
+			},
+			keys: []string{"enter"},
+			want: wantRefreshing,
+		},
+		{
+			name: "refresh confirm y starts the refresh",
+			mode: modeConfirmRefresh,
+			setup: func(m *model) {
+				withHistory(m)
+				m.confirmRefresh = true
+			},
+			keys: []string{"y"},
+			want: wantRefreshing,
+		},
+		{
+			name: "refresh confirm n cancels",
+			mode: modeConfirmRefresh,
+			setup: func(m *model) {
+				withHistory(m)
+				m.confirmRefresh = true
+			},
+			keys: []string{"n"},
+			want: wantRefreshCancelled,
+		},
+		{
+			name: "refresh confirm esc cancels",
+			mode: modeConfirmRefresh,
+			setup: func(m *model) {
+				withHistory(m)
+				m.confirmRefresh = true
+			},
+			keys: []string{"esc"},
+			want: wantRefreshCancelled,
+		},
+
+		// Ctrl+N confirmation
+		{
+			name: "new conversation y starts over",
+			mode: modeConfirmNew,
+			setup: func(m *model) {
+				withHistory(m)
+				m.confirmNew = true
+			},
+			keys: []string{"y"},
+			want: func(t *testing.T, m model, cmd tea.Cmd) {
+				if m.confirmNew || len(m.messages) != 0 {
+					t.Errorf("confirmNew = %v, messages = %+v", m.confirmNew, m.messages)
+				}
+			},
+		},
+		{
+			name: "new conversation enter starts over",
+			mode: modeConfirmNew,
+			setup: func(m *model) {
+				withHistory(m)
+				m.confirmNew = true
+			},
+			keys: []string{"enter"},
+			want: func(t *testing.T, m model, cmd tea.Cmd) {
+				if m.confirmNew || len(m.messages) != 0 {
+					t.Errorf("confirmNew = %v, messages = %+v", m.confirmNew, m.messages)
+				}
+			},
+		},
+		{
+			name: "new conversation n keeps it",
+			mode: modeConfirmNew,
+			setup: func(m *model) {
+				withHistory(m)
+				m.confirmNew = true
+			},
+			keys: []string{"n"},
+			want: wantKeptConversation,
+		},
+		{
+			name: "new conversation esc keeps it",
+			mode: modeConfirmNew,
+			setup: func(m *model) {
+				withHistory(m)
+				m.confirmNew = true
+			},
+			keys: []string{"esc"},
+			want: wantKeptConversation,
+		},
+
+		// Search
+		{
+			name: "search enter stops editing the query",
+			mode: modeSearch,
+			setup: func(m *model) {
+				withHistory(m)
+				*m = m.openSearch()
+			},
+			keys: []string{"n", "o", "o", "n", "enter"},
+			want: func(t *testing.T, m model, cmd tea.Cmd) {
+				if m.search == nil || m.search.typing || m.search.query != "noon" || len(m.search.matches) == 0 {
+					t.Errorf("search = %+v", m.search)
+				}
+			},
+		},
+		{
+			name: "search n moves to the next match",
+			mode: modeSearch,
+			setup: func(m *model) {
+				withHistory(m)
+				*m = m.openSearch()
+			},
+			keys: []string{"n", "o", "o", "n", "enter", "n"},
+			want: func(t *testing.T, m model, cmd tea.Cmd) {
+				if m.search == nil || len(m.search.matches) < 2 || m.search.current != 1 {
+					t.Errorf("search = %+v", m.search)
+				}
+			},
+		},
+		{
+			name: "search second enter closes it",
+			mode: modeSearch,
+			setup: func(m *model) {
+				withHistory(m)
+				*m = m.openSearch()
+			},
+			keys: []string{"n", "o", "o", "n", "enter", "enter"},
+			want: wantSearchClosed,
+		},
+		{
+			name: "search esc closes it while typing",
+			mode: modeSearch,
+			setup: func(m *model) {
+				withHistory(m)
+				*m = m.openSearch()
+			},
+			keys: []string{"y", "esc"},
+			want: wantSearchClosed,
+		},
+
+		// File picker
+		{
+			name:  "file picker enter attaches the selection",
+			mode:  modeFilePicker,
+			setup: pickingFile,
+			keys:  []string{"enter"},
+			want: func(t *testing.T, m model, cmd tea.Cmd) {
+				if m.picker != nil || len(m.attachments) != 1 {
+					t.Errorf("picker = %v, attachments = %q", m.picker, m.attachments)
+				}
+			},
+		},
+		{
+			name:  "file picker y filters",
+			mode:  modeFilePicker,
+			setup: pickingFile,
+			keys:  []string{"y"},
+			want: func(t *testing.T, m model, cmd tea.Cmd) {
+				if m.picker == nil || m.picker.query != "y" || len(m.picker.matches) != 1 {
+					t.Errorf("picker = %+v", m.picker)
+				}
+			},
+		},
+		{
+			name:  "file picker esc closes it",
+			mode:  modeFilePicker,
+			setup: pickingFile,
+			keys:  []string{"esc"},
+			want: func(t *testing.T, m model, cmd tea.Cmd) {
+				if m.picker != nil || len(m.attachments) != 0 || quits(cmd) {
+					t.Errorf("picker = %v, attachments = %q", m.picker, m.attachments)
+				}
+			},
+		},
+
+		// Session picker
+		{
+			name:  "session picker enter loads the session",
+			mode:  modeSessionPicker,
+			setup: pickingSession(t),
+			keys:  []string{"enter"},
+			want: func(t *testing.T, m model, cmd tea.Cmd) {
+				if m.sessions != nil || roles(m.messages) != "user,assistant" || m.messages[0].Content != "saved question" {
+					t.Errorf("sessions = %v, messages = %+v", m.sessions, m.messages)
+				}
+			},
+		},
+		{
+			name:  "session picker esc closes it",
+			mode:  modeSessionPicker,
+			setup: pickingSession(t),
+			keys:  []string{"esc"},
+			want: func(t *testing.T, m model, cmd tea.Cmd) {
+				if m.sessions != nil || len(m.messages) != 0 || quits(cmd) {
+					t.Errorf("sessions = %v, messages = %+v", m.sessions, m.messages)
+				}
+			},
+		},
+		{
+			name:  "session picker d then y deletes",
+			mode:  modeSessionPicker,
+			setup: pickingSession(t),
+			keys:  []string{"d", "y"},
+			want: func(t *testing.T, m model, cmd tea.Cmd) {
+				if m.sessions == nil || len(m.sessions.sessions) != 0 {
+					t.Fatalf("sessions = %+v", m.sessions)
+				}
+				if saved, _ := storage.ListSessions(); len(saved) != 0 {
+					t.Errorf("session file still there: %+v", saved)
+				}
+			},
+		},
+		{
+			name:  "session picker d then n keeps it",
+			mode:  modeSessionPicker,
+			setup: pickingSession(t),
+			keys:  []string{"d", "n"},
+			want: func(t *testing.T, m model, cmd tea.Cmd) {
+				if m.sessions == nil || len(m.sessions.sessions) != 1 || m.sessions.deleting {
+					t.Fatalf("sessions = %+v", m.sessions)
+				}
+				if saved, _ := storage.ListSessions(); len(saved) != 1 {
+					t.Errorf("sessions on disk = %+v", saved)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestModel(t, &fakeProvider{replies: []fakeReply{{response: &ai.Response{Content: "ok"}}}})
+			if tt.setup != nil {
+				tt.setup(&m)
+			}
+			if got := m.mode(); got != tt.mode {
+				t.Fatalf("mode = %d, want %d", got, tt.mode)
+			}
+
+			var cmd tea.Cmd
+			for _, key := range tt.keys {
+				var next tea.Model
+				next, cmd = m.handleKey(keyPress(key))
+				m = next.(model)
+			}
+			tt.want(t, m, cmd)
+			m.endTurn()
+		})
+	}
+}
+
+func wantDeclined(t *testing.T, m model, cmd tea.Cmd) {
+	last := m.messages[len(m.messages)-1]
+	if m.confirming != nil || last.Role != "tool" || last.Content != declinedToolResult || !last.ToolError {
+		t.Errorf("confirming = %v, last message = %+v", m.confirming, last)
+	}
+	if m.turn == nil || quits(cmd) {
+		t.Error("declining ended the turn")
+	}
+}
+
+func wantRefreshing(t *testing.T, m model, cmd tea.Cmd) {
+	if m.confirmRefresh || m.turn == nil || cmd == nil {
+		t.Errorf("confirmRefresh = %v, turn = %v", m.confirmRefresh, m.turn)
+	}
+}
+
+func wantRefreshCancelled(t *testing.T, m model, cmd tea.Cmd) {
+	if m.confirmRefresh || m.turn != nil || m.notice != "Refresh cancelled" || quits(cmd) {
+		t.Errorf("confirmRefresh = %v, turn = %v, notice = %q", m.confirmRefresh, m.turn, m.notice)
+	}
+}
+
+func wantKeptConversation(t *testing.T, m model, cmd tea.Cmd) {
+	if m.confirmNew || len(m.messages) != 4 || quits(cmd) {
+		t.Errorf("confirmNew = %v, messages = %+v", m.confirmNew, m.messages)
+	}
+}
+
+func wantSearchClosed(t *testing.T, m model, cmd tea.Cmd) {
+	if m.search != nil || quits(cmd) {
+		t.Errorf("search = %+v", m.search)
+	}
+}
+
+// pickingFile opens the file picker over a fixed file list
+func pickingFile(m *model) {
+	m.picker = &filePicker{files: []string{"main.go", "README.md", "yarn.lock"}}
+	m.picker.filter()
+}
+
+// pickingSession saves a conversation and opens the session picker on it
+func pickingSession(t *testing.T) func(m *model) {
+	return func(m *model) {
+		path, err := storage.SessionPath("saved")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ai.SaveSession(path, ai.Session{Messages: []ai.Message{
+			{Role: "user", Content: "saved question"},
+			{Role: "assistant", Content: "saved answer"},
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		*m = m.openSessionPicker()
+	}
+}
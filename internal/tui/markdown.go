@@ -0,0 +1,64 @@
+package tui
+
+import (
+	"strings"
+	"sync"
+
+	"kilo/internal/theme"
+
+	"github.com/charmbracelet/glamour"
+)
+
+// maxMarkdownCache bounds how many rendered messages are kept between redraws
+const maxMarkdownCache = 256
+
+// markdownCache holds a renderer for the current width and the messages it
+// has already rendered, since the whole conversation is redrawn on every
+// update
+var markdownCache struct {
+	sync.Mutex
+	width    int
+	renderer *glamour.TermRenderer
+	rendered map[string]string
+}
+
+// renderMarkdown renders Markdown for a terminal of the given width,
+// returning content unchanged if it can't be rendered
+func renderMarkdown(content string, width int) string {
+	if width <= 0 {
+		return content
+	}
+
+	markdownCache.Lock()
+	defer markdownCache.Unlock()
+
+	if markdownCache.renderer == nil || markdownCache.width != width {
+		// A configured style: detecting the terminal background would query
+		// the terminal while Bubble Tea owns it
+		renderer, err := glamour.NewTermRenderer(
+			glamour.WithStandardStyle(theme.Active().Markdown),
+			glamour.WithWordWrap(width),
+		)
+		if err != nil {
+			return content
+		}
+		markdownCache.width = width
+		markdownCache.renderer = renderer
+		markdownCache.rendered = make(map[string]string)
+	}
+
+	if rendered, ok := markdownCache.rendered[content]; ok {
+		return rendered
+	}
+	rendered, err := markdownCache.renderer.Render(content)
+	if err != nil {
+		return content
+	}
+	rendered = strings.Trim(rendered, "\n")
+
+	if len(markdownCache.rendered) >= maxMarkdownCache {
+		markdownCache.rendered = make(map[string]string)
+	}
+	markdownCache.rendered[content] = rendered
+	return rendered
+}
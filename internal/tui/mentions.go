@@ -0,0 +1,86 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"kilo/internal/ai"
+	"kilo/internal/files"
+)
+
+// mentionPattern matches @path file mentions. The @ must start a word, so
+// email addresses aren't mistaken for mentions.
+var mentionPattern = regexp.MustCompile(`(?:^|\s)@([^\s@]+)`)
+
+// mentionContent reads the files mentioned in input into a block appended to
+// the user's message, like attachmentContent, and mentioned images into
+// images to send with it. Mentions that can't be read get an inline note
+// instead, and are returned so the user can be told.
+func (m model) mentionContent(input string) (string, []ai.Image, []string) {
+	var content strings.Builder
+	var images []ai.Image
+	var failed []string
+	seen := make(map[string]bool)
+
+	for _, match := range mentionPattern.FindAllStringSubmatch(input, -1) {
+		path, full, err := m.resolveMention(match[1])
+		if seen[path] {
+			continue
+		}
+		seen[path] = true
+
+		var data string
+		if err == nil {
+			if ai.IsImageFile(full) {
+				var image ai.Image
+				if image, err = ai.LoadImage(full); err == nil {
+					images = append(images, image)
+					continue
+				}
+			} else {
+				data, err = files.Read(full)
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(&content, "\n\n[@%s was not attached: %v]", path, err)
+			failed = append(failed, "@"+path)
+			continue
+		}
+		fmt.Fprintf(&content, "\n\n<file path=%q>\n%s\n</file>", path, data)
+	}
+	return content.String(), images, failed
+}
+
+// resolveMention finds the file a mention names, relative to the directory
+// tools run in, returning the path as mentioned and in full. Punctuation
+// ending a sentence, as in "look at @main.go.", is dropped unless a file by
+// the longer name exists.
+func (m model) resolveMention(mention string) (path, full string, err error) {
+	dir := m.executor.WorkingDir()
+	path = mention
+	if trimmed := strings.TrimRight(mention, ".,;:!?)]}'\""); trimmed != mention && trimmed != "" {
+		if _, err := os.Stat(filepath.Join(dir, mention)); err != nil {
+			path = trimmed
+		}
+	}
+
+	full = path
+	if !filepath.IsAbs(full) {
+		full = filepath.Join(dir, path)
+	}
+	info, err := os.Stat(full)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return path, "", errors.New("file not found")
+	case err != nil:
+		return path, "", err
+	case info.IsDir():
+		return path, "", errors.New("it is a directory")
+	}
+	return path, full, nil
+}
@@ -0,0 +1,171 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"kilo/internal/files"
+	"kilo/internal/theme"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// pickerRows is how many matches the file picker shows at once
+const pickerRows = 12
+
+// filePicker is the Ctrl+T overlay for fuzzy-finding files to attach
+type filePicker struct {
+	query   string
+	files   []string // Every candidate, nil until the listing finishes
+	matches []string
+	cursor  int
+	err     error
+}
+
+type filesListedMsg struct {
+	files []string
+	err   error
+}
+
+// openFilePicker shows the picker and indexes the working directory in the background
+func (m model) openFilePicker() (tea.Model, tea.Cmd) {
+	m.picker = &filePicker{}
+	cwd := m.executor.WorkingDir()
+	return m, func() tea.Msg {
+		list, err := files.List(cwd)
+		return filesListedMsg{files: list, err: err}
+	}
+}
+
+// handleFilesListed fills the picker once the directory walk completes
+func (m model) handleFilesListed(msg filesListedMsg) (tea.Model, tea.Cmd) {
+	if m.picker == nil {
+		return m, nil
+	}
+	m.picker.files = msg.files
+	m.picker.err = msg.err
+	m.picker.filter()
+	return m, nil
+}
+
+// filter recomputes matches for the current query
+func (p *filePicker) filter() {
+	p.matches = files.Match(p.query, p.files, pickerRows)
+	if p.cursor >= len(p.matches) {
+		p.cursor = max(len(p.matches)-1, 0)
+	}
+}
+
+// handlePickerKey filters as the user types; Enter attaches the selected file
+func (m model) handlePickerKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := m.picker
+
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+
+	case tea.KeyEsc, tea.KeyCtrlT:
+		m.picker = nil
+		return m, nil
+
+	case tea.KeyEnter:
+		if len(p.matches) == 0 {
+			return m, nil
+		}
+		m.picker = nil
+		return m.attach(p.matches[p.cursor]), nil
+
+	case tea.KeyUp, tea.KeyCtrlP:
+		if p.cursor > 0 {
+			p.cursor--
+		}
+
+	case tea.KeyDown, tea.KeyCtrlN:
+		if p.cursor < len(p.matches)-1 {
+			p.cursor++
+		}
+
+	case tea.KeyBackspace:
+		if p.query != "" {
+			runes := []rune(p.query)
+			p.query = string(runes[:len(runes)-1])
+			p.cursor = 0
+			p.filter()
+		}
+
+	case tea.KeyRunes, tea.KeySpace:
+		p.query += string(msg.Runes)
+		p.cursor = 0
+		p.filter()
+	}
+
+	return m, nil
+}
+
+// attach stages a file to be sent with the next message
+func (m model) attach(path string) model {
+	for _, existing := range m.attachments {
+		if existing == path {
+			m.notice = path + " is already attached"
+			return m
+		}
+	}
+	m.attachments = append(m.attachments, path)
+	m.notice = "Attached " + path
+	return m
+}
+
+// attachmentContent reads the staged files into a block appended to the
+// user's message
+func (m model) attachmentContent() (string, error) {
+	var content strings.Builder
+	for _, path := range m.attachments {
+		data, err := files.Read(filepath.Join(m.executor.WorkingDir(), path))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&content, "\n\n<file path=%q>\n%s\n</file>", path, data)
+	}
+	return content.String(), nil
+}
+
+// renderFilePicker draws the picker in place of the conversation
+func (m model) renderFilePicker() string {
+	p := m.picker
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(theme.Active().Secondary).
+		Bold(true)
+	dimStyle := lipgloss.NewStyle().
+		Foreground(theme.Active().Muted).
+		Italic(true)
+	selectedStyle := lipgloss.NewStyle().
+		Foreground(theme.Active().Primary).
+		Bold(true)
+
+	var out strings.Builder
+	out.WriteString(titleStyle.Render("Attach file: " + p.query + "▏"))
+	out.WriteString("\n\n")
+
+	switch {
+	case p.err != nil:
+		out.WriteString(dimStyle.Render(fmt.Sprintf("Couldn't list files: %v", p.err)))
+	case p.files == nil:
+		out.WriteString(dimStyle.Render("Indexing files..."))
+	case len(p.matches) == 0:
+		out.WriteString(dimStyle.Render("No matching files"))
+	}
+
+	for i, path := range p.matches {
+		if i == p.cursor {
+			out.WriteString(selectedStyle.Render("▸ " + path))
+		} else {
+			out.WriteString("  " + path)
+		}
+		out.WriteString("\n")
+	}
+
+	return out.String()
+}
@@ -0,0 +1,77 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"kilo/internal/files"
+)
+
+const (
+	// projectContextDepth limits how many directory levels the snapshot shows
+	projectContextDepth = 3
+	// projectContextEntries caps the snapshot so large repos stay affordable
+	projectContextEntries = 300
+	// projectInstructionsFile holds project-specific instructions appended
+	// to the system prompt
+	projectInstructionsFile = ".kilo.md"
+	// maxPromptFileBytes caps prompt files so a stray large file can't
+	// blow the token budget
+	maxPromptFileBytes = 32 * 1024
+)
+
+// reloadProjectContext re-reads the working directory layout when project
+// context is enabled
+func (m *model) reloadProjectContext() error {
+	if !m.projectContext {
+		return nil
+	}
+
+	cwd := m.executor.WorkingDir()
+	tree, err := files.Tree(cwd, projectContextDepth, projectContextEntries)
+	if err != nil {
+		return err
+	}
+
+	m.projectContextSummary = fmt.Sprintf("# Project layout\nFiles in the working directory %s (depth %d, .gitignore respected):\n%s",
+		cwd, projectContextDepth, tree)
+	return nil
+}
+
+// reloadProjectInstructions re-reads .kilo.md from the working directory; a
+// missing file just means there are none
+func (m *model) reloadProjectInstructions() error {
+	m.projectInstructions = ""
+	instructions, err := readPromptFile(filepath.Join(m.executor.WorkingDir(), projectInstructionsFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if instructions != "" {
+		m.projectInstructions = "# Project instructions\nFrom " + projectInstructionsFile + " in the working directory:\n" + instructions
+	}
+	return nil
+}
+
+// readPromptFile reads prompt text from a file, refusing ones too large to
+// send with every request
+func readPromptFile(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.Size() > maxPromptFileBytes {
+		return "", fmt.Errorf("%s is %d bytes (max %d)", path, info.Size(), maxPromptFileBytes)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,72 @@
+package tui
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"kilo/internal/ai"
+)
+
+// refPattern matches tool output references inserted by /ref, e.g. "[ref #3]"
+var refPattern = regexp.MustCompile(`\[ref #(\d+)\]`)
+
+// toolOutputs returns tool results in conversation order. A result's 1-based
+// position is its stable reference number: messages are only ever appended,
+// and /refresh-tools rewrites results in place.
+func (m model) toolOutputs() []ai.Message {
+	var outputs []ai.Message
+	for _, msg := range m.messages {
+		if msg.Role == "tool" {
+			outputs = append(outputs, msg)
+		}
+	}
+	return outputs
+}
+
+// insertRef adds a "[ref #N]" pointer to the input for the user to ask about
+func (m model) insertRef(arg string) model {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 || n > len(m.toolOutputs()) {
+		m.notice = fmt.Sprintf("No tool output #%s (%d available)", arg, len(m.toolOutputs()))
+		return m
+	}
+	m.input.SetValue(strings.TrimSpace(m.input.Value()+" "+refToken(n)) + " ")
+	m.input.CursorEnd()
+	m.notice = fmt.Sprintf("Referencing tool output #%d; type your question and press Enter", n)
+	return m
+}
+
+// refToken is the pointer text for tool output n
+func refToken(n int) string {
+	return fmt.Sprintf("[ref #%d]", n)
+}
+
+// expandRefs appends the content of each referenced tool output to the
+// message so Claude is scoped to exactly that data
+func (m model) expandRefs(input string) (string, error) {
+	outputs := m.toolOutputs()
+	var expanded strings.Builder
+	seen := make(map[int]bool)
+
+	for _, match := range refPattern.FindAllStringSubmatch(input, -1) {
+		n, _ := strconv.Atoi(match[1])
+		if n < 1 || n > len(outputs) {
+			return "", fmt.Errorf("no tool output #%d (%d available)", n, len(outputs))
+		}
+		if seen[n] {
+			continue
+		}
+		seen[n] = true
+
+		out := outputs[n-1]
+		fmt.Fprintf(&expanded, "\n\n<tool_output ref=\"%d\" tool=%q>\n%s\n</tool_output>",
+			n, out.ToolCallName, out.Content)
+	}
+
+	if len(seen) == 0 {
+		return input, nil
+	}
+	return input + "\n\nAnswer using the referenced tool output below." + expanded.String(), nil
+}
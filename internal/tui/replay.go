@@ -0,0 +1,133 @@
+package tui
+
+import (
+	"fmt"
+	"time"
+
+	"kilo/internal/ai"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// replayInterval is the delay between turns when auto-playing
+const replayInterval = 2 * time.Second
+
+// replayState steps through a saved conversation without making API calls
+type replayState struct {
+	messages []ai.Message
+	turnEnds []int // Index just past the last message of each turn
+	shown    int   // Number of turns revealed so far
+	auto     bool
+}
+
+type replayTickMsg struct{}
+
+// newReplay splits a saved conversation into turns, each starting at a user message
+func newReplay(messages []ai.Message) *replayState {
+	r := &replayState{messages: messages}
+	for i := 1; i < len(messages); i++ {
+		if messages[i].Role == "user" {
+			r.turnEnds = append(r.turnEnds, i)
+		}
+	}
+	if len(messages) > 0 {
+		r.turnEnds = append(r.turnEnds, len(messages))
+	}
+	return r
+}
+
+func (r *replayState) done() bool {
+	return r.shown >= len(r.turnEnds)
+}
+
+// visible returns the messages revealed so far
+func (r *replayState) visible() []ai.Message {
+	if r.shown == 0 {
+		return nil
+	}
+	return r.messages[:r.turnEnds[r.shown-1]]
+}
+
+func replayTick() tea.Cmd {
+	return tea.Tick(replayInterval, func(time.Time) tea.Msg {
+		return replayTickMsg{}
+	})
+}
+
+// handleReplayTick reveals the next turn while auto-playing
+func (m model) handleReplayTick() (tea.Model, tea.Cmd) {
+	if m.replay == nil || !m.replay.auto {
+		return m, nil
+	}
+	m.advanceReplay()
+	if m.replay.done() {
+		m.replay.auto = false
+		return m, nil
+	}
+	return m, replayTick()
+}
+
+// handleReplayKey steps through the replay; input and sending are disabled
+func (m model) handleReplayKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "esc", "q":
+		return m, tea.Quit
+	case " ", "enter", "right", "n":
+		m.advanceReplay()
+	case "a":
+		m.replay.auto = !m.replay.auto
+		if m.replay.auto && !m.replay.done() {
+			return m, replayTick()
+		}
+	case "up", "down", "pgup", "pgdown":
+		var cmd tea.Cmd
+		m.viewport, cmd = m.viewport.Update(msg)
+		m.syncScroll()
+		return m, cmd
+	}
+	return m, nil
+}
+
+// advanceReplay reveals the next turn
+func (m *model) advanceReplay() {
+	if m.replay.done() {
+		return
+	}
+	m.replay.shown++
+	m.messages = m.replay.visible()
+	m.refreshViewport()
+}
+
+// replayStatus describes replay progress for the status bar
+func (m model) replayStatus() string {
+	status := fmt.Sprintf("REPLAY turn %d/%d", m.replay.shown, len(m.replay.turnEnds))
+	switch {
+	case m.replay.done():
+		status += " (end)"
+	case m.replay.auto:
+		status += " (auto-playing)"
+	}
+	return status
+}
+
+// Replay opens a saved conversation read-only and reveals it turn by turn
+func Replay(path string) error {
+	messages, err := ai.LoadConversation(path)
+	if err != nil {
+		return err
+	}
+
+	m := New()
+	m.replay = newReplay(messages)
+	m.input.Blur()
+
+	p := tea.NewProgram(
+		m,
+		tea.WithAltScreen(),
+		tea.WithMouseCellMotion(),
+	)
+	if _, err := p.Run(); err != nil {
+		return fmt.Errorf("error running program: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"kilo/internal/theme"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// search is the state of Ctrl+F search through the conversation
+type search struct {
+	query         string
+	typing        bool // Editing the query; otherwise n/N move between matches
+	caseSensitive bool
+	matches       []searchMatch // In the rendered conversation, top to bottom
+	current       int           // Index into matches of the selected one
+}
+
+// searchMatch is one occurrence of the query in the rendered conversation
+type searchMatch struct {
+	line       int // Line of the rendered conversation
+	start, end int // Byte offsets into the line with styling removed
+}
+
+// openSearch starts typing a search query
+func (m model) openSearch() model {
+	m.search = &search{typing: true}
+	return m
+}
+
+// handleSearchKey edits the query, then moves between matches with n and N
+func (m model) handleSearchKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	s := m.search
+
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		return m, tea.Quit
+
+	case tea.KeyEsc:
+		m.search = nil
+		m.refreshViewport()
+		return m, nil
+
+	case tea.KeyTab:
+		s.caseSensitive = !s.caseSensitive
+		m.updateSearch(true)
+		return m, nil
+
+	case tea.KeyPgUp, tea.KeyPgDown:
+		var cmd tea.Cmd
+		m.viewport, cmd = m.viewport.Update(msg)
+		m.syncScroll()
+		return m, cmd
+	}
+
+	if s.typing {
+		switch msg.Type {
+		case tea.KeyEnter:
+			s.typing = false
+		case tea.KeyBackspace:
+			if runes := []rune(s.query); len(runes) > 0 {
+				s.query = string(runes[:len(runes)-1])
+				m.updateSearch(true)
+			}
+		case tea.KeyRunes, tea.KeySpace:
+			s.query += string(msg.Runes)
+			m.updateSearch(true)
+		}
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "n", "down":
+		m.jumpToMatch(1)
+	case "N", "up":
+		m.jumpToMatch(-1)
+	case "/", "ctrl+f":
+		s.typing = true
+	case "enter", "q":
+		// Close, staying where the search left the conversation
+		m.search = nil
+		m.refreshViewport()
+	}
+	return m, nil
+}
+
+// updateSearch re-renders with the current query highlighted. With jump, it
+// selects the first match at or below the top of the view and scrolls to it.
+func (m *model) updateSearch(jump bool) {
+	m.viewport.SetContent(m.conversationView())
+	if !jump || len(m.search.matches) == 0 {
+		return
+	}
+	m.search.current = 0
+	for i, match := range m.search.matches {
+		if match.line >= m.viewport.YOffset {
+			m.search.current = i
+			break
+		}
+	}
+	m.scrollToMatch()
+}
+
+// jumpToMatch selects the next (1) or previous (-1) match, wrapping around
+func (m *model) jumpToMatch(step int) {
+	n := len(m.search.matches)
+	if n == 0 {
+		return
+	}
+	m.search.current = (m.search.current + step + n) % n
+	m.viewport.SetContent(m.conversationView())
+	m.scrollToMatch()
+}
+
+// scrollToMatch brings the selected match into view, a third of the way down
+func (m *model) scrollToMatch() {
+	match := m.search.matches[m.search.current]
+	m.viewport.SetYOffset(match.line - m.viewport.Height/3)
+	m.syncScroll()
+}
+
+// conversationView is the rendered conversation, wrapped to the viewport,
+// with search matches highlighted while a search is open
+func (m *model) conversationView() string {
+	rendered := softWrap(m.renderMessages(), m.viewport.Width)
+	if m.search == nil {
+		return rendered
+	}
+	lines := strings.Split(rendered, "\n")
+	m.search.matches = findMatches(lines, m.search.query, m.search.caseSensitive)
+	m.search.current = min(m.search.current, max(len(m.search.matches)-1, 0))
+	return highlightMatches(lines, m.search.matches, m.search.current)
+}
+
+// findMatches locates query in each line of rendered output, ignoring styling
+func findMatches(lines []string, query string, caseSensitive bool) []searchMatch {
+	if query == "" {
+		return nil
+	}
+	if !caseSensitive {
+		query = strings.ToLower(query)
+	}
+	var matches []searchMatch
+	for i, line := range lines {
+		plain := ansi.Strip(line)
+		if !caseSensitive {
+			plain = lowerSameLength(plain)
+		}
+		for offset := 0; ; {
+			idx := strings.Index(plain[offset:], query)
+			if idx < 0 {
+				break
+			}
+			start := offset + idx
+			matches = append(matches, searchMatch{line: i, start: start, end: start + len(query)})
+			offset = start + len(query)
+		}
+	}
+	return matches
+}
+
+// lowerSameLength lowercases s, leaving characters whose lowercase form has
+// a different encoded length as they are, so byte offsets still line up
+func lowerSameLength(s string) string {
+	return strings.Map(func(r rune) rune {
+		lower := []rune(strings.ToLower(string(r)))
+		if len(lower) == 1 && len(string(lower[0])) == len(string(r)) {
+			return lower[0]
+		}
+		return r
+	}, s)
+}
+
+// highlightMatches marks the matches in lines. A line with a match loses its
+// own styling, since the highlights are placed in the plain text.
+func highlightMatches(lines []string, matches []searchMatch, current int) string {
+	colors := theme.Active()
+	matchStyle := lipgloss.NewStyle().Foreground(colors.Inverse).Background(colors.Accent)
+	currentStyle := lipgloss.NewStyle().Foreground(colors.Inverse).Background(colors.Warning).Bold(true)
+
+	for i := 0; i < len(matches); {
+		line := matches[i].line
+		plain := ansi.Strip(lines[line])
+		var out strings.Builder
+		last := 0
+		for ; i < len(matches) && matches[i].line == line; i++ {
+			match := matches[i]
+			style := matchStyle
+			if i == current {
+				style = currentStyle
+			}
+			out.WriteString(plain[last:match.start])
+			out.WriteString(style.Render(plain[match.start:match.end]))
+			last = match.end
+		}
+		out.WriteString(plain[last:])
+		lines[line] = out.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// renderSearchBar shows the query and match count in place of the input box
+func (m model) renderSearchBar() string {
+	s := m.search
+	titleStyle := lipgloss.NewStyle().
+		Foreground(theme.Active().Secondary).
+		Bold(true)
+	dimStyle := lipgloss.NewStyle().
+		Foreground(theme.Active().Muted).
+		Italic(true)
+
+	query := s.query
+	if s.typing {
+		query += "▏"
+	}
+	status := "no matches"
+	switch {
+	case s.query == "":
+		status = "type to search"
+	case len(s.matches) > 0:
+		status = fmt.Sprintf("%d of %d", s.current+1, len(s.matches))
+	}
+	mode := "case-insensitive"
+	if s.caseSensitive {
+		mode = "case-sensitive"
+	}
+	return titleStyle.Render("Search: "+query) + "\n" + dimStyle.Render(status+" · "+mode)
+}
@@ -0,0 +1,203 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"kilo/internal/ai"
+	"kilo/internal/storage"
+	"kilo/internal/theme"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// sessionPicker is the /sessions list of saved conversations
+type sessionPicker struct {
+	sessions []sessionEntry
+	cursor   int
+	err      error
+	deleting bool // Waiting for y/n to delete the selected session
+}
+
+// sessionEntry describes one saved conversation in the picker
+type sessionEntry struct {
+	storage.SavedSession
+	title    string // The first thing the user asked
+	messages int
+	err      error // Set when the file couldn't be read
+}
+
+// WithSessionPicker opens the list of saved sessions at startup (--sessions)
+func WithSessionPicker() Option {
+	return func(m *model) {
+		*m = m.openSessionPicker()
+	}
+}
+
+// openSessionPicker lists the saved sessions, newest first
+func (m model) openSessionPicker() model {
+	if m.thinking {
+		m.notice = "Wait for the current response before switching sessions"
+		return m
+	}
+	saved, err := storage.ListSessions()
+	p := &sessionPicker{err: err}
+	for _, s := range saved {
+		entry := sessionEntry{SavedSession: s}
+		session, err := ai.LoadSession(s.Path)
+		if err != nil {
+			entry.err = err
+		} else {
+			entry.title = sessionTitle(session.Messages)
+			entry.messages = len(session.Messages)
+		}
+		p.sessions = append(p.sessions, entry)
+	}
+	m.sessions = p
+	return m
+}
+
+// sessionTitle names a conversation after the user's first message, as
+// typed, on one line
+func sessionTitle(messages []ai.Message) string {
+	for _, msg := range messages {
+		if msg.Role != "user" {
+			continue
+		}
+		text := msg.Prompt
+		if text == "" {
+			text = msg.Content
+		}
+		if title := strings.Join(strings.Fields(text), " "); title != "" {
+			return title
+		}
+	}
+	return "(no messages)"
+}
+
+// handleSessionKey moves through the list; Enter loads the selected
+// session and d deletes it after asking
+func (m model) handleSessionKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	p := m.sessions
+
+	if p.deleting {
+		switch msg.String() {
+		case "y", "Y":
+			p.deleting = false
+			selected := p.sessions[p.cursor]
+			if err := os.Remove(selected.Path); err != nil {
+				m.notice = fmt.Sprintf("Delete failed: %v", err)
+				return m, nil
+			}
+			p.sessions = append(p.sessions[:p.cursor], p.sessions[p.cursor+1:]...)
+			p.cursor = min(p.cursor, max(len(p.sessions)-1, 0))
+			m.notice = "Deleted " + selected.Name
+		case "n", "N", "esc":
+			p.deleting = false
+		case "ctrl+c":
+			return m, tea.Quit
+		}
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+
+	case "esc", "q":
+		m.sessions = nil
+		return m, nil
+
+	case "up", "k", "ctrl+p":
+		if p.cursor > 0 {
+			p.cursor--
+		}
+
+	case "down", "j", "ctrl+n":
+		if p.cursor < len(p.sessions)-1 {
+			p.cursor++
+		}
+
+	case "home", "g":
+		p.cursor = 0
+
+	case "end", "G":
+		p.cursor = max(len(p.sessions)-1, 0)
+
+	case "enter":
+		if len(p.sessions) == 0 {
+			return m, nil
+		}
+		m.sessions = nil
+		return m.loadSession(p.sessions[p.cursor].Path)
+
+	case "d":
+		if len(p.sessions) > 0 {
+			p.deleting = true
+		}
+	}
+	return m, nil
+}
+
+// renderSessionPicker draws the list in place of the conversation, scrolled
+// to keep the selection in view
+func (m model) renderSessionPicker() string {
+	p := m.sessions
+	colors := theme.Active()
+
+	titleStyle := lipgloss.NewStyle().
+		Foreground(colors.Secondary).
+		Bold(true)
+	dimStyle := lipgloss.NewStyle().
+		Foreground(colors.Muted).
+		Italic(true)
+	selectedStyle := lipgloss.NewStyle().
+		Foreground(colors.Primary).
+		Bold(true)
+
+	var out strings.Builder
+	out.WriteString(titleStyle.Render(fmt.Sprintf("Saved sessions (%d)", len(p.sessions))))
+	out.WriteString("\n\n")
+
+	switch {
+	case p.err != nil:
+		out.WriteString(dimStyle.Render(fmt.Sprintf("Couldn't list sessions: %v", p.err)))
+		return out.String()
+	case len(p.sessions) == 0:
+		out.WriteString(dimStyle.Render("No saved sessions yet; conversations are saved as you go"))
+		return out.String()
+	}
+
+	// Two lines per session, below the heading
+	rows := max((m.viewport.Height-2)/2, 1)
+	first := max(min(p.cursor-rows/2, len(p.sessions)-rows), 0)
+	width := max(m.viewport.Width-2, 20)
+
+	for i := first; i < min(first+rows, len(p.sessions)); i++ {
+		s := p.sessions[i]
+		title := s.title
+		details := fmt.Sprintf("%s · %s · %d messages", s.Name, s.Modified.Format("Jan 2 15:04"), s.messages)
+		if s.err != nil {
+			title = "(unreadable)"
+			details = fmt.Sprintf("%s · %v", s.Name, s.err)
+		}
+		title = ansi.Truncate(title, width, "…")
+		details = ansi.Truncate(details, width, "…")
+
+		if i == p.cursor {
+			out.WriteString(selectedStyle.Render("▸ " + title))
+		} else {
+			out.WriteString("  " + title)
+		}
+		out.WriteString("\n  " + dimStyle.Render(details) + "\n")
+	}
+	return out.String()
+}
+
+// sessionPickerPrompt is the status line question while deleting
+func (m model) sessionPickerPrompt() string {
+	return fmt.Sprintf("Delete %s? (y: yes, n: no)", m.sessions.sessions[m.sessions.cursor].Name)
+}
@@ -0,0 +1,218 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"kilo/internal/ai"
+	"kilo/internal/storage"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// saveSession writes the conversation and the settings it's using
+func (m model) saveSession(name string) (string, error) {
+	path, err := storage.SessionPath(name)
+	if err != nil {
+		return "", err
+	}
+	settings := m.client.Settings()
+	return path, ai.SaveSession(path, ai.Session{Settings: &settings, Messages: m.messages})
+}
+
+// autosave writes the conversation to its autosave file, so quitting never
+// loses it. Called after each exchange.
+func (m *model) autosave() {
+	if m.autosavePath == "" || len(m.messages) == 0 {
+		return
+	}
+	settings := m.client.Settings()
+	if err := ai.SaveSession(m.autosavePath, ai.Session{Settings: &settings, Messages: m.messages}); err != nil {
+		m.notice = fmt.Sprintf("Autosave failed: %v", err)
+	}
+}
+
+// shutdownGrace bounds how long exiting waits for cancelled tool commands
+// to be killed
+const shutdownGrace = 3 * time.Second
+
+// shutdown runs once the program has exited, by quitting or a signal. It
+// cancels a turn still in flight, killing any running tool command, and
+// saves the conversation to its autosave file so --resume can continue it.
+// A tool call left without a result is marked interrupted on resume. The
+// debug log is closed last, once nothing can write to it.
+func (m model) shutdown() error {
+	if m.debugLog != nil {
+		defer m.debugLog.Close()
+	}
+	if t := m.turn; t != nil {
+		if m.streaming != "" {
+			m.messages = append(m.messages, ai.Message{Role: "assistant", Content: m.streaming})
+		}
+		m.endTurn()
+
+		done := make(chan struct{})
+		go func() {
+			t.tools.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(shutdownGrace):
+		}
+	}
+
+	if m.autosavePath == "" || len(m.messages) == 0 {
+		return nil
+	}
+	settings := m.client.Settings()
+	if err := ai.SaveSession(m.autosavePath, ai.Session{Settings: &settings, Messages: m.messages}); err != nil {
+		return fmt.Errorf("failed to save the conversation: %w", err)
+	}
+	return nil
+}
+
+// resumeLatest continues the most recently saved session, autosaving back
+// into the same file
+func (m *model) resumeLatest() {
+	path, err := storage.LatestSession()
+	var session ai.Session
+	if err == nil {
+		session, err = ai.LoadSession(path)
+	}
+	if err == nil && session.Settings != nil {
+		err = m.client.ApplySettings(*session.Settings)
+	}
+	if err != nil {
+		m.notice = fmt.Sprintf("Nothing resumed: %v", err)
+		return
+	}
+
+	m.messages = session.Messages
+	// A tool call cut off by quitting still needs a result before Claude
+	// will accept the conversation
+	if n := len(m.messages); n > 0 && m.messages[n-1].Role == "assistant" && m.messages[n-1].ToolCallID != "" {
+		last := m.messages[n-1]
+		m.messages = append(m.messages, ai.Message{
+			Role:         "tool",
+			Content:      "Interrupted: Kilo exited before this tool call finished.",
+			ToolCallID:   last.ToolCallID,
+			ToolCallName: last.ToolCallName,
+			ToolError:    true,
+		})
+	}
+	if m.autosavePath != "" {
+		m.autosavePath = path
+	}
+	m.notice = fmt.Sprintf("Resumed %s (%d messages)", strings.TrimSuffix(filepath.Base(path), ".json"), len(m.messages))
+}
+
+// loadSession replaces the conversation with a saved one and restores its
+// settings over the global defaults
+func (m model) loadSession(name string) (tea.Model, tea.Cmd) {
+	if m.thinking {
+		m.notice = "Wait for the current response before loading a session"
+		return m, nil
+	}
+
+	path, err := storage.FindSession(name)
+	var session ai.Session
+	if err == nil {
+		session, err = ai.LoadSession(path)
+	}
+	if err == nil && session.Settings != nil {
+		err = m.client.ApplySettings(*session.Settings)
+	}
+	if err != nil {
+		m.notice = fmt.Sprintf("Load failed: %v", err)
+		return m, nil
+	}
+
+	m.messages = session.Messages
+	m.contextTokens = 0
+	m.notice = fmt.Sprintf("Loaded %s (%d messages)", strings.TrimSuffix(filepath.Base(path), ".json"), len(session.Messages))
+	if session.Settings != nil {
+		m.notice += " with its saved settings"
+	}
+	m.refreshViewport()
+	return m, nil
+}
+
+// handleSettings shows the active per-conversation settings, or changes one
+// with "/settings <key> <value>"
+func (m model) handleSettings(args []string) (tea.Model, tea.Cmd) {
+	if len(args) == 0 {
+		m.messages = append(m.messages, ai.Message{
+			Role:    "system",
+			Content: formatSettings(m.client.Settings()),
+		})
+		m.refreshViewport()
+		return m, nil
+	}
+	if len(args) < 2 {
+		m.notice = "Usage: /settings [model|temperature|top_p|max_tokens|persona <value>]"
+		return m, nil
+	}
+
+	key, value := args[0], strings.Join(args[1:], " ")
+	var err error
+	switch key {
+	case "model":
+		if !m.allowUnknownModels {
+			if err = ai.ValidateModel(value); err != nil {
+				break
+			}
+		}
+		m.client.SetModel(value)
+	case "temperature":
+		err = setSampling(m.client.SetTemperature, value)
+	case "top_p":
+		err = setSampling(m.client.SetTopP, value)
+	case "max_tokens":
+		var n int
+		if n, err = strconv.Atoi(value); err == nil {
+			err = m.client.SetMaxTokens(n)
+		}
+	case "persona":
+		m.client.SetAssistantName(value)
+	default:
+		err = fmt.Errorf("unknown setting %q", key)
+	}
+	if err != nil {
+		m.notice = fmt.Sprintf("Can't set %s: %v", key, err)
+		return m, nil
+	}
+
+	m.notice = fmt.Sprintf("Set %s for this conversation; /save keeps it with the session", key)
+	return m, nil
+}
+
+// setSampling parses a temperature or top_p value for set, where "default"
+// restores the API default
+func setSampling(set func(*float64) error, value string) error {
+	if value == "default" {
+		return set(nil)
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return err
+	}
+	return set(&f)
+}
+
+// formatSampling shows a temperature or top_p setting
+func formatSampling(value *float64) string {
+	if value == nil {
+		return "API default"
+	}
+	return strconv.FormatFloat(*value, 'g', -1, 64)
+}
+
+// formatSettings renders settings for the /settings report
+func formatSettings(s ai.Settings) string {
+	return fmt.Sprintf("Conversation settings\n  model:       %s\n  temperature: %s\n  top_p:       %s\n  max_tokens:  %d\n  persona:     %s",
+		s.Model, formatSampling(s.Temperature), formatSampling(s.TopP), s.MaxTokens, s.Persona)
+}
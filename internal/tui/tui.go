@@ -2,48 +2,296 @@ package tui
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"kilo/internal/ai"
+	"kilo/internal/config"
+	"kilo/internal/gitctx"
 	"kilo/internal/logo"
+	"kilo/internal/storage"
+	"kilo/internal/theme"
+	"kilo/internal/toolenv"
 	"kilo/internal/tools"
 
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 )
 
 type model struct {
 	width    int
 	height   int
-	client   *ai.Client
+	client   *ai.Client  // Anthropic client, also holding the chat settings
+	provider ai.Provider // Sends requests; the client unless WithProvider replaced it
 	executor *tools.Executor
 	input    textarea.Model
 	viewport viewport.Model
 	messages []ai.Message
 	ready    bool
 	thinking bool
+	spinner  spinner.Model // Animates the status bar while thinking
+	notice   string        // Transient status line feedback (command results, etc.)
+
+	// The conversation follows new content only while scrolled to the end;
+	// otherwise newBelow flags that something arrived out of view
+	atBottom bool
+	newBelow bool
+
+	// Include a refreshed git summary in the system prompt (/gitcontext)
+	gitContext bool
+
+	// Highlight changed words within diff lines (/worddiff)
+	wordDiff bool
+
+	// Show the resolved command above each tool output (/showcmd)
+	showCommands bool
+
+	// Pending /refresh-tools confirmation
+	confirmRefresh bool
+
+	// Pending Ctrl+N confirmation
+	confirmNew bool
+
+	// End the turn at the first failed tool call instead of sending the
+	// error back to Claude (/stoponerror, KILO_STOP_ON_TOOL_ERROR)
+	stopOnToolError bool
+
+	// Agent loop state for the in-flight user message, nil when idle
+	turn *turn
+
+	// Output of the currently running tool, shown in place until it finishes
+	live *liveOutput
+
+	// Ctrl+F search through the conversation, nil when closed
+	search *search
+
+	// /sessions list of saved conversations, nil when closed
+	sessions *sessionPicker
+
+	// Session info panel shown over the conversation (/info), and when the
+	// session began
+	showInfo  bool
+	startedAt time.Time
+
+	// Text of the response being streamed, shown until it completes
+	streaming string
+
+	// Non-nil in --replay mode: input and sending are disabled
+	replay *replayState
+
+	// Tool confirmation: when confirmTools is on, tools with side effects
+	// wait in confirming until approved, declined, or edited. Dangerous
+	// tools wait regardless unless confirmDangerous is off.
+	confirmTools     bool
+	confirmDangerous bool
+	confirming       *ai.ToolCall
+	editingInput     bool
+	editor           textarea.Model
+	trust            sessionTrust
+
+	// Ctrl+T file picker, nil when closed, and the files it staged for the
+	// next message
+	picker      *filePicker
+	attachments []string
+
+	// Message typed while a turn was running, sent when it finishes
+	queued string
+
+	// Submitted inputs, recalled with Up/Down
+	history inputHistory
+
+	// Directory listing sent as context with every request when enabled
+	// (--project-context), refreshed by /reload
+	projectContext        bool
+	projectContextSummary string
+	// projectInstructions is .kilo.md from the working directory, refreshed
+	// by /reload
+	projectInstructions string
+
+	// Tokens used by this conversation's API calls
+	usage ai.Usage
+
+	// Context window taken by the latest request and its reply, 0 until
+	// the next reply after the history changes
+	contextTokens int
+
+	// Estimated tokens of history sent with each request; older turns are
+	// trimmed to fit (KILO_HISTORY_TOKENS, 0 to send everything)
+	historyBudget int
+
+	// Where the conversation is saved after each exchange, "" when
+	// autosave is off (KILO_AUTOSAVE=0)
+	autosavePath string
+
+	// The KILO_DEBUG_LOG file the client logs requests to, closed at shutdown
+	debugLog *os.File
+
+	// Skip the known-model check in /settings model (--unsafe-model)
+	allowUnknownModels bool
+
+	// Limits on one turn of the agent loop: rounds of tool calls and
+	// wall-clock time (KILO_MAX_TOOL_ITERATIONS, KILO_TURN_BUDGET)
+	maxToolIterations int
+	turnBudget        time.Duration
 }
 
-type responseMsg struct {
-	content  string
-	messages []ai.Message // Include updated messages
-	err      error
+// Option configures the TUI at startup
+type Option func(*model)
+
+// WithProjectContext includes a depth-limited listing of the working
+// directory in the system prompt so Claude knows the project layout
+func WithProjectContext() Option {
+	return func(m *model) {
+		m.projectContext = true
+	}
 }
 
-type toolExecutedMsg struct {
-	messages []ai.Message
+// WithProvider sends requests through another model provider. Settings such
+// as the model and temperature only apply to the built-in Anthropic client.
+func WithProvider(provider ai.Provider) Option {
+	return func(m *model) {
+		m.provider = provider
+	}
+}
+
+// WithResume continues the most recently saved session (--resume)
+func WithResume() Option {
+	return func(m *model) {
+		m.resumeLatest()
+	}
+}
+
+// WithModel selects the Claude model. Validate it with ai.ValidateModel
+// first unless unknown models are allowed.
+func WithModel(name string) Option {
+	return func(m *model) {
+		if name != "" {
+			m.client.SetModel(name)
+		}
+	}
+}
+
+// WithConfig applies settings loaded by config.LoadConfig. Unset fields
+// keep the defaults; pass it before WithResume so a resumed session's saved
+// settings win over it.
+func WithConfig(cfg config.Config) Option {
+	return func(m *model) {
+		if cfg.Model != "" {
+			m.client.SetModel(cfg.Model)
+		}
+		if cfg.MaxTokens != 0 {
+			// Out of range values keep the default, as KILO_MAX_TOKENS does
+			m.client.SetMaxTokens(cfg.MaxTokens)
+		}
+		if cfg.Timeout > 0 {
+			tools.WithTimeout(cfg.Timeout)(m.executor)
+		}
+		if cfg.Theme != "" {
+			WithTheme(cfg.Theme)(m)
+		}
+		if cfg.StopOnToolError {
+			m.stopOnToolError = true
+		}
+		if cfg.MaxOutputChars > 0 {
+			tools.WithMaxOutputChars(cfg.MaxOutputChars)(m.executor)
+		}
+		if cfg.SafeCommands != nil {
+			tools.WithSafeCommands(cfg.SafeCommands...)(m.executor)
+		}
+	}
+}
+
+// WithTheme selects the color theme, one of theme.Names(). NO_COLOR or
+// KILO_NO_COLOR still turns colors off.
+func WithTheme(name string) Option {
+	return func(m *model) {
+		theme.Set(name)
+	}
 }
+
+// WithWorkingDir runs tools in dir rather than the directory Kilo was
+// started from (--cwd). Check it with tools.ValidateWorkingDir first.
+func WithWorkingDir(dir string) Option {
+	return func(m *model) {
+		tools.WithWorkingDir(dir)(m.executor)
+	}
+}
+
+// WithoutScreening lets bash run commands that tools.ScreenCommand would
+// refuse (--yolo)
+func WithoutScreening() Option {
+	return func(m *model) {
+		tools.WithoutScreening()(m.executor)
+	}
+}
+
+// WithMaxToolIterations sets how many rounds of tool calls Claude may make
+// to answer one message. Values below 1 are ignored.
+func WithMaxToolIterations(n int) Option {
+	return func(m *model) {
+		if n > 0 {
+			m.maxToolIterations = n
+		}
+	}
+}
+
+// WithTurnBudget sets the wall-clock time Claude may take to answer one
+// message, tool calls included. Time spent waiting for the user to confirm
+// a tool call doesn't count. Values of 0 or less are ignored.
+func WithTurnBudget(d time.Duration) Option {
+	return func(m *model) {
+		if d > 0 {
+			m.turnBudget = d
+		}
+	}
+}
+
+// WithUnknownModels lets /settings switch to models outside ai.KnownModels
+func WithUnknownModels() Option {
+	return func(m *model) {
+		m.allowUnknownModels = true
+	}
+}
+
+// minWidth and minHeight are the smallest terminal the full layout fits in;
+// below that View shows a resize hint instead
+const (
+	minWidth  = 40
+	minHeight = 20
+)
+
+type responseMsg struct {
+	turn      *turn
+	content   string
+	truncated bool // Cut off by the max tokens cap
+	outOfTime bool // The turn's time budget ran out before Claude answered
+	err       error
+}
+
 type toolResponseMsg struct {
 	result string
 	err    error
 }
 
-func New() model {
+type doctorResultMsg struct {
+	report string
+	passed bool
+}
+
+type toolsRefreshedMsg struct {
+	turn    *turn                    // The refresh, dropped if it was cancelled
+	results map[string]ai.ToolResult // Tool call ID -> fresh result
+}
+
+func New(opts ...Option) model {
 	apiKey := os.Getenv("ANTHROPIC_API_KEY")
 
 	// Create textarea for input
@@ -59,13 +307,182 @@ func New() model {
 	// Create viewport for chat history
 	vp := viewport.New(80, 20)
 
-	return model{
-		client:   ai.NewClient(apiKey),
-		executor: tools.New(),
-		input:    ta,
-		viewport: vp,
-		messages: []ai.Message{},
+	var clientOpts []ai.ClientOption
+	if n, err := strconv.Atoi(os.Getenv("KILO_MAX_ATTEMPTS")); err == nil && n > 0 {
+		clientOpts = append(clientOpts, ai.WithRetry(n, ai.DefaultRetryBase))
+	}
+	if n, err := strconv.Atoi(os.Getenv("KILO_MAX_TOKENS")); err == nil {
+		clientOpts = append(clientOpts, ai.WithMaxTokens(n))
+	}
+	if n, err := strconv.Atoi(os.Getenv("KILO_RATE_LIMIT")); err == nil {
+		clientOpts = append(clientOpts, ai.WithRateLimit(n))
+	}
+	var promptErr error
+	if path := os.Getenv("KILO_SYSTEM_PROMPT_FILE"); path != "" {
+		prompt, err := readPromptFile(path)
+		if err == nil {
+			clientOpts = append(clientOpts, ai.WithSystemPrompt(prompt))
+		}
+		promptErr = err
+	}
+	var debugLog *os.File
+	var logErr error
+	if path := os.Getenv("KILO_DEBUG_LOG"); path != "" {
+		debugLog, logErr = os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+		if logErr == nil {
+			clientOpts = append(clientOpts, ai.WithLogger(debugLog))
+		}
+	}
+	client := ai.NewClient(apiKey, clientOpts...)
+	client.SetAssistantName(os.Getenv("KILO_ASSISTANT_NAME"))
+	client.SetLanguage(os.Getenv("KILO_LANGUAGE"))
+
+	m := model{
+		client:            client,
+		provider:          client,
+		executor:          newExecutor(),
+		input:             ta,
+		viewport:          vp,
+		spinner:           spinner.New(spinner.WithSpinner(spinner.Dot)),
+		atBottom:          true,
+		messages:          []ai.Message{},
+		editor:            newInputEditor(),
+		trust:             sessionTrust{calls: make(map[string]bool)},
+		confirmTools:      envEnabled("KILO_CONFIRM_TOOLS"),
+		confirmDangerous:  !envDisabled("KILO_CONFIRM_TOOLS"),
+		showCommands:      envEnabled("KILO_SHOW_COMMANDS"),
+		historyBudget:     ai.DefaultHistoryBudget,
+		history:           newInputHistory(),
+		maxToolIterations: defaultMaxToolIterations,
+		turnBudget:        defaultTurnBudget,
+		startedAt:         time.Now(),
+		debugLog:          debugLog,
+	}
+	if n, err := strconv.Atoi(os.Getenv("KILO_HISTORY_TOKENS")); err == nil && n >= 0 {
+		m.historyBudget = n
+	}
+	if n, err := strconv.Atoi(os.Getenv("KILO_MAX_TOOL_ITERATIONS")); err == nil {
+		WithMaxToolIterations(n)(&m)
+	}
+	if d, ok := config.ParseTimeout(os.Getenv("KILO_TURN_BUDGET")); ok {
+		WithTurnBudget(d)(&m)
+	}
+	if !envDisabled("KILO_AUTOSAVE") {
+		if path, err := storage.SessionPath(storage.AutosaveName(time.Now())); err == nil {
+			m.autosavePath = path
+		}
+	}
+	for _, opt := range opts {
+		opt(&m)
+	}
+	// NO_COLOR wins over any theme
+	if colorDisabled() {
+		theme.DisableColor()
+	}
+	m.spinner.Style = lipgloss.NewStyle().Foreground(theme.Active().Primary)
+	if err := m.reloadProjectContext(); err != nil {
+		m.notice = fmt.Sprintf("Project context unavailable: %v", err)
+	}
+	if err := m.reloadProjectInstructions(); err != nil {
+		m.notice = fmt.Sprintf("Ignoring %s: %v", projectInstructionsFile, err)
+	}
+	if promptErr != nil {
+		m.notice = fmt.Sprintf("Using the default system prompt: %v", promptErr)
+	}
+	if logErr != nil {
+		m.notice = fmt.Sprintf("Debug log disabled: %v", logErr)
+	}
+
+	return m
+}
+
+// newExecutor creates the tool executor, scoping the subprocess environment:
+// KILO_TOOL_ENV_ALLOW lists extra variables to pass through, or
+// KILO_TOOL_ENV_INHERIT passes them all, and KILO_TOOLENV_<NAME>=value sets
+// NAME=value for tools only
+func newExecutor() *tools.Executor {
+	opts := []tools.Option{
+		tools.WithEnvAllowlist(splitList(os.Getenv("KILO_TOOL_ENV_ALLOW"))...),
+		tools.WithEnv(toolenv.Prefixed(os.Environ(), "KILO_TOOLENV_")...),
+	}
+	if envEnabled("KILO_TOOL_ENV_INHERIT") {
+		opts = append(opts, tools.WithToolEnv(nil))
+	}
+	if n, err := strconv.Atoi(os.Getenv("KILO_MAX_TOOL_OUTPUT_BYTES")); err == nil && n > 0 {
+		opts = append(opts, tools.WithCaptureLimit(n))
+	}
+	if n, err := strconv.Atoi(os.Getenv("KILO_READ_FILE_MAX_BYTES")); err == nil && n > 0 {
+		opts = append(opts, tools.WithReadLimit(n))
+	}
+	if envEnabled("KILO_FILE_TOOLS_ANYWHERE") {
+		opts = append(opts, tools.WithFilesAnywhere())
+	}
+	if n, err := strconv.Atoi(os.Getenv("KILO_HTTP_MAX_BYTES")); err == nil && n > 0 {
+		opts = append(opts, tools.WithHTTPLimit(n))
+	}
+	if hosts := splitList(os.Getenv("KILO_HTTP_ALLOW")); len(hosts) > 0 {
+		opts = append(opts, tools.WithHTTPAllow(hosts...))
+	}
+	if hosts := splitList(os.Getenv("KILO_HTTP_DENY")); len(hosts) > 0 {
+		opts = append(opts, tools.WithHTTPDeny(hosts...))
+	}
+	if d, ok := config.ParseTimeout(os.Getenv("KILO_TOOL_TIMEOUT")); ok {
+		opts = append(opts, tools.WithTimeout(d))
+	}
+	if n, err := strconv.Atoi(os.Getenv("KILO_TOOL_CONCURRENCY")); err == nil && n > 0 {
+		opts = append(opts, tools.WithConcurrency(n))
+	}
+	for _, tool := range tools.New().GetAvailableTools() {
+		if d, ok := config.ParseTimeout(os.Getenv("KILO_TOOL_TIMEOUT_" + strings.ToUpper(tool.Name))); ok {
+			opts = append(opts, tools.WithToolTimeout(tool.Name, d))
+		}
+		if n, err := strconv.Atoi(os.Getenv("KILO_MAX_OUTPUT_CHARS_" + strings.ToUpper(tool.Name))); err == nil && n > 0 {
+			opts = append(opts, tools.WithToolMaxOutputChars(tool.Name, n))
+		}
+	}
+	// An unrecognized policy keeps the default of asking first
+	if policy, err := tools.ParsePathPolicy(os.Getenv("KILO_CRITICAL_PATHS")); err == nil {
+		opts = append(opts, tools.WithPathPolicy(policy))
+	}
+	return tools.New(opts...)
+}
+
+// envEnabled reports whether an environment flag is set to a truthy value
+func envEnabled(key string) bool {
+	switch strings.ToLower(os.Getenv(key)) {
+	case "1", "true", "yes", "on":
+		return true
+	}
+	return false
+}
+
+// splitList splits a comma-separated setting, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// envDisabled reports whether an environment flag is explicitly set to a
+// falsy value, as opposed to unset
+func envDisabled(key string) bool {
+	switch strings.ToLower(os.Getenv(key)) {
+	case "0", "false", "no", "off":
+		return true
 	}
+	return false
+}
+
+// formatTokens abbreviates large token counts, e.g. 12345 -> "12.3k"
+func formatTokens(n int) string {
+	if n < 1000 {
+		return strconv.Itoa(n)
+	}
+	return fmt.Sprintf("%.1fk", float64(n)/1000)
 }
 
 func (m model) Init() tea.Cmd {
@@ -78,81 +495,92 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		vpCmd tea.Cmd
 	)
 
+	// Keys mean different things depending on the current mode
+	if keyMsg, ok := msg.(tea.KeyMsg); ok {
+		updated, cmd := m.handleKey(keyMsg)
+		// Typing, pasting, recalling, and sending all change the input's size
+		if m, ok := updated.(model); ok {
+			m.fitInput()
+			return m, cmd
+		}
+		return updated, cmd
+	}
+
 	m.input, tiCmd = m.input.Update(msg)
 	m.viewport, vpCmd = m.viewport.Update(msg)
+	if _, ok := msg.(tea.MouseMsg); ok {
+		m.syncScroll()
+	}
+
+	// Results from a cancelled turn arrive after the UI has moved on, maybe
+	// to a new turn they must not leak into
+	if t, ok := turnOf(msg); ok && (m.turn == nil || t != m.turn) {
+		return m, nil
+	}
 
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
+		m.layout()
+		m.ready = true
+		// The input wraps differently at the new width
+		m.fitInput()
 
-		if !m.ready {
-			m.viewport.Width = msg.Width - 4
-			m.viewport.Height = msg.Height - 10
-			m.input.SetWidth(msg.Width - 4)
-			m.ready = true
-		} else {
-			m.viewport.Width = msg.Width - 4
-			m.viewport.Height = msg.Height - 10
-			m.input.SetWidth(msg.Width - 4)
+		m.viewport.SetContent(m.conversationView())
+		if m.atBottom {
+			m.viewport.GotoBottom()
 		}
-
-		m.viewport.SetContent(m.renderMessages())
 		return m, nil
 
-	case tea.KeyMsg:
-		switch msg.Type {
-		case tea.KeyCtrlC, tea.KeyEsc:
-			return m, tea.Quit
+	case turnTickMsg:
+		return m.handleTurnTick(msg)
 
-		case tea.KeyEnter:
-			if m.thinking {
-				return m, nil
-			}
+	case spinner.TickMsg:
+		// Dropping the tick once idle stops the animation
+		if !m.thinking {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
 
-			userInput := strings.TrimSpace(m.input.Value())
-			if userInput == "" {
-				return m, nil
-			}
+	case replayTickMsg:
+		return m.handleReplayTick()
 
-			// Add user message
+	case responseMsg:
+		if msg.outOfTime {
+			msg.err = m.budgetExhausted()
+		}
+		// Keep the part of the answer that streamed in before an error
+		if msg.err != nil && m.streaming != "" {
 			m.messages = append(m.messages, ai.Message{
-				Role:    "user",
-				Content: userInput,
+				Role:    "assistant",
+				Content: m.streaming,
 			})
-
-			// Clear input
-			m.input.Reset()
-			m.thinking = true
-
-			// Update viewport
-			m.viewport.SetContent(m.renderMessages())
-			m.viewport.GotoBottom()
-
-			// Send message to Claude
-			return m, m.sendMessage()
 		}
-
-	case responseMsg:
-		m.thinking = false
+		m.endTurn()
 		if msg.err != nil {
 			m.messages = append(m.messages, ai.Message{
 				Role:    "assistant",
 				Content: fmt.Sprintf("Error: %v", msg.err),
 			})
 		} else {
-			if len(msg.messages) > 0 {
-				m.messages = msg.messages
-			}
 			// Add Claude's final response
 			m.messages = append(m.messages, ai.Message{
 				Role:    "assistant",
 				Content: msg.content,
 			})
+			if msg.truncated {
+				m.messages = append(m.messages, ai.Message{
+					Role:    "system",
+					Content: fmt.Sprintf("[response truncated at %d tokens; raise it with /settings max_tokens]", m.client.MaxTokens()),
+				})
+			}
 		}
-		m.viewport.SetContent(m.renderMessages())
-		m.viewport.GotoBottom()
-		return m, nil
+		m.autosave()
+		m.refreshViewport()
+		return m.sendQueued()
 
 	case toolResponseMsg:
 		m.thinking = false
@@ -167,200 +595,235 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				Content: msg.result,
 			})
 		}
-		m.viewport.SetContent(m.renderMessages())
-		m.viewport.GotoBottom()
+		m.refreshViewport()
 		return m, nil
-	case toolExecutedMsg:
-		m.messages = msg.messages
-		m.viewport.SetContent(m.renderMessages())
-		m.viewport.GotoBottom()
-		return m, m.sendFinalMessage()
 
-	}
+	case assistantTurnMsg:
+		return m.handleAssistantTurn(msg.response)
 
-	return m, tea.Batch(tiCmd, vpCmd)
-}
+	case streamDeltaMsg:
+		return m.handleStreamDelta(msg)
 
-func (m model) sendMessage() tea.Cmd {
-	return func() tea.Msg {
-		ctx := context.Background()
-		tools := m.executor.GetAvailableTools()
+	case streamRetryMsg:
+		return m.handleStreamRetry(msg)
 
-		response, err := m.client.SendMessageWithTools(ctx, m.messages, tools)
-		if err != nil {
-			return responseMsg{err: err}
-		}
+	case streamThrottleMsg:
+		return m.handleStreamThrottle(msg)
 
-		if len(response.ToolCalls) == 0 {
-			return responseMsg{
-				content:  response.Content,
-				messages: m.messages,
-			}
-		}
+	case toolProgressMsg:
+		return m.handleToolProgress(msg)
 
-		// Add assistant message with tool call to history
-		for _, toolCall := range response.ToolCalls {
-			m.messages = append(m.messages, ai.Message{
-				Role:          "assistant",
-				ToolCallID:    toolCall.ID,
-				ToolCallName:  toolCall.Name,
-				ToolCallInput: toolCall.Input,
-			})
+	case toolResultMsg:
+		return m.handleToolResult(msg)
 
-			// Execute the tool
-			result, err := m.executor.Execute(ctx, toolCall)
-			if err != nil {
-				result = fmt.Sprintf("Error: %v", err)
-			}
+	case toolBatchMsg:
+		return m.handleToolBatch(msg)
 
-			// Truncate very long results (keep first 5000 chars for Claude)
-			if len(result) > 5000 {
-				result = result[:5000] + "\n... [output truncated, too long]"
-			}
+	case filesListedMsg:
+		return m.handleFilesListed(msg)
 
-			// Add tool result to history (truncated version for API)
-			m.messages = append(m.messages, ai.Message{
-				Role:       "tool",
-				Content:    result,
-				ToolCallID: toolCall.ID,
-			})
+	case doctorResultMsg:
+		// Local-only message: SendMessageWithTools skips the "system" role
+		m.messages = append(m.messages, ai.Message{
+			Role:    "system",
+			Content: msg.report,
+		})
+		if msg.passed {
+			m.notice = "All checks passed"
+		} else {
+			m.notice = "Some checks failed"
 		}
+		m.refreshViewport()
+		return m, nil
 
-		return toolExecutedMsg{
-			messages: m.messages,
+	case toolsRefreshedMsg:
+		if msg.turn != m.turn {
+			return m, nil
 		}
-
-	}
-}
-
-func (m model) sendFinalMessage() tea.Cmd {
-	return func() tea.Msg {
-		// Add 60 second timeout
-		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-		defer cancel()
-
-		tools := m.executor.GetAvailableTools()
-		maxIterations := 5 // Prevent infinite loops
-
-		// Loop until Claude responds with text (not more tool calls)
-		for iteration := 0; iteration < maxIterations; iteration++ {
-			// fmt.Fprintf(os.Stderr, "\n[DEBUG] Iteration %d: Sending %d messages to Claude\n", iteration+1, len(m.messages))
-
-			response, err := m.client.SendMessageWithTools(ctx, m.messages, tools)
-			if err != nil {
-				// fmt.Fprintf(os.Stderr, "[DEBUG] API Error: %v\n", err)
-				return responseMsg{err: fmt.Errorf("final response error: %w", err), messages: m.messages}
+		m.endTurn()
+		for i, message := range m.messages {
+			if message.Role != "tool" {
+				continue
+			}
+			if result, ok := msg.results[message.ToolCallID]; ok {
+				m.messages[i].Content = result.Content
+				m.messages[i].DisplayContent = result.Full
+				m.messages[i].ToolError = result.IsError
 			}
+		}
+		m.notice = fmt.Sprintf("Refreshed %d tool result(s)", len(msg.results))
+		m.refreshViewport()
+		return m.sendQueued()
 
-			// fmt.Fprintf(os.Stderr, "[DEBUG] Response: Content=%d chars, ToolCalls=%d\n",
-			// 	len(response.Content), len(response.ToolCalls))
-
-			// If Claude wants to use more tools, execute them and loop
-			if len(response.ToolCalls) > 0 {
-				// fmt.Fprintf(os.Stderr, "[DEBUG] Claude wants to use %d more tool(s)\n", len(response.ToolCalls))
-
-				for _, toolCall := range response.ToolCalls {
-					// fmt.Fprintf(os.Stderr, "  - Executing: %s\n", toolCall.Name)
-
-					// Add tool call to history
-					m.messages = append(m.messages, ai.Message{
-						Role:          "assistant",
-						ToolCallID:    toolCall.ID,
-						ToolCallName:  toolCall.Name,
-						ToolCallInput: toolCall.Input,
-					})
-
-					// Execute the tool
-					result, err := m.executor.Execute(ctx, toolCall)
-					if err != nil {
-						result = fmt.Sprintf("Error: %v", err)
-					}
-
-					// Truncate very long results
-					if len(result) > 5000 {
-						result = result[:5000] + "\n... [output truncated, too long]"
-					}
-
-					// Add tool result to history
-					m.messages = append(m.messages, ai.Message{
-						Role:       "tool",
-						Content:    result,
-						ToolCallID: toolCall.ID,
-					})
-				}
+	}
 
-				// Continue loop to send tool results back to Claude
-				continue
-			}
+	return m, tea.Batch(tiCmd, vpCmd)
+}
 
-			// Claude responded with text - we're done!
-			if response.Content != "" {
-				// fmt.Fprintf(os.Stderr, "[DEBUG] Claude provided final response (%d chars)\n", len(response.Content))
-				return responseMsg{
-					content:  response.Content,
-					messages: m.messages,
-				}
-			}
+// refreshViewport re-renders the conversation, following the latest message
+// unless the user has scrolled up to read earlier ones
+func (m *model) refreshViewport() {
+	lines := m.viewport.TotalLineCount()
+	m.viewport.SetContent(m.conversationView())
+	if m.atBottom {
+		m.viewport.GotoBottom()
+	} else if m.viewport.TotalLineCount() > lines {
+		m.newBelow = true
+	}
+}
 
-			// Empty response with no tool calls - something's wrong
-			return responseMsg{err: fmt.Errorf("empty response from Claude (no error, just empty content)"), messages: m.messages}
-		}
+// syncScroll records whether the user has scrolled away from the end
+func (m *model) syncScroll() {
+	m.atBottom = m.viewport.AtBottom()
+	if m.atBottom {
+		m.newBelow = false
+	}
+}
 
-		// Hit max iterations
-		return responseMsg{err: fmt.Errorf("reached maximum iterations (%d) - Claude kept calling tools", maxIterations), messages: m.messages}
+// requestOptions builds the per-request options for the current model state
+func (m model) requestOptions(ctx context.Context) []ai.RequestOption {
+	var opts []ai.RequestOption
+	if m.projectInstructions != "" {
+		opts = append(opts, ai.SystemContext(m.projectInstructions))
+	}
+	if m.projectContextSummary != "" {
+		opts = append(opts, ai.SystemContext(m.projectContextSummary))
 	}
+	if m.gitContext {
+		// Recomputed every request so Claude sees the repo as it is now
+		opts = append(opts, ai.SystemContext(gitctx.Summary(ctx, m.executor.WorkingDir())))
+	}
+	return opts
 }
 
 func (m model) renderMessages() string {
 	if len(m.messages) == 0 {
+		placeholder := "Start chatting with Claude..."
+		if m.replay != nil {
+			placeholder = "Press Space to reveal the first turn..."
+		}
 		return lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#666666")).
+			Foreground(theme.Active().Muted).
 			Italic(true).
-			Render("Start chatting with Claude...")
+			Render(placeholder)
 	}
 
 	var output strings.Builder
 
 	userStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#00FFFF")).
+		Foreground(theme.Active().Secondary).
 		Bold(true)
 
 	assistantStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#FF10F0")).
+		Foreground(theme.Active().Primary).
 		Bold(true)
 
 	contentStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#FAFAFA"))
+		Foreground(theme.Active().Text)
 
-	for _, msg := range m.messages {
+	toolIndex := 0
+	for i, msg := range m.messages {
 
 		switch msg.Role {
 		case "user":
 			output.WriteString(userStyle.Render("You: "))
 			output.WriteString(contentStyle.Render(msg.Content))
+			for _, image := range msg.Images {
+				output.WriteString(lipgloss.NewStyle().
+					Foreground(theme.Active().Muted).
+					Italic(true).
+					Render("\n[image: " + image.Name + "]"))
+			}
 			output.WriteString("\n\n")
 		case "assistant":
-			// Only render if there's actual content (skip tool call messages)
+			if msg.ToolCallID != "" {
+				// What Claude decided to run, kept to one quiet line above
+				// the output
+				call := ai.ToolCall{ID: msg.ToolCallID, Name: msg.ToolCallName, Input: msg.ToolCallInput}
+				line := "⚙ " + call.Name
+				if detail := m.toolDetail(call); detail != "" {
+					line += ": " + detail
+				}
+				output.WriteString(lipgloss.NewStyle().
+					Foreground(theme.Active().Accent).
+					Render(ansi.Truncate(line, max(m.viewport.Width, 20), "…")))
+				output.WriteString("\n")
+				break
+			}
 			if msg.Content != "" {
-				output.WriteString(assistantStyle.Render("Kilo: "))
-				output.WriteString(contentStyle.Render(msg.Content))
+				output.WriteString(assistantStyle.Render(m.client.AssistantName() + ":"))
+				output.WriteString("\n")
+				output.WriteString(renderMarkdown(msg.Content, m.viewport.Width))
 				output.WriteString("\n\n")
 			}
 		case "tool":
+			toolIndex++
 			toolStyle := lipgloss.NewStyle().
-				Foreground(lipgloss.Color("#666666")).
+				Foreground(theme.Active().Muted).
 				Italic(true)
-			output.WriteString(toolStyle.Render(fmt.Sprintf("Tool output:\n%s",
-				msg.Content)))
+			// The number is what /ref uses to point at this output
+			label := fmt.Sprintf("Tool output #%d:", toolIndex)
+			if msg.ToolError {
+				label = fmt.Sprintf("Tool output #%d (failed):", toolIndex)
+			}
+			if m.showCommands && msg.ToolCommand != "" {
+				label += "\n$ " + strings.ReplaceAll(msg.ToolCommand, "\n", "\n$ ")
+			}
+			// The complete output, even when Claude only saw part of it
+			content := msg.Display()
+			highlighted := highlightToolOutput(content)
+			if path := toolCallPath(m.messages[:i], msg.ToolCallID); path != "" {
+				highlighted = highlightFile(content, path)
+			}
+			switch {
+			case isUnifiedDiff(content) && !colorDisabled():
+				output.WriteString(toolStyle.Render(label))
+				output.WriteString("\n")
+				output.WriteString(renderDiff(content, m.wordDiff))
+			case highlighted != content:
+				output.WriteString(toolStyle.Render(label))
+				output.WriteString("\n")
+				output.WriteString(highlighted)
+			default:
+				output.WriteString(toolStyle.Render(fmt.Sprintf("%s\n%s",
+					label, content)))
+			}
+			output.WriteString("\n\n")
+		case "system":
+			systemStyle := lipgloss.NewStyle().
+				Foreground(theme.Active().Warning)
+			output.WriteString(systemStyle.Render(msg.Content))
 			output.WriteString("\n\n")
 		}
 	}
 
+	if m.streaming != "" {
+		output.WriteString(assistantStyle.Render(m.client.AssistantName() + ":"))
+		output.WriteString("\n")
+		output.WriteString(renderMarkdown(m.streaming, m.viewport.Width))
+		output.WriteString("\n\n")
+	}
+
+	if m.live != nil {
+		liveStyle := lipgloss.NewStyle().
+			Foreground(theme.Active().Muted).
+			Italic(true)
+		output.WriteString(liveStyle.Render("Tool output (running):\n" + m.live.view()))
+		output.WriteString("\n\n")
+	}
+
 	if m.thinking {
+		status, nearDeadline := m.thinkingStatus()
 		output.WriteString(lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#B026FF")).
+			Foreground(theme.Active().Accent).
 			Italic(true).
-			Render("Kilo is thinking..."))
+			Render(status))
+		if nearDeadline {
+			output.WriteString(lipgloss.NewStyle().
+				Foreground(theme.Active().Warning).
+				Bold(true).
+				Render(" · running out of time, press Esc to cancel"))
+		}
 	}
 
 	return output.String()
@@ -375,9 +838,12 @@ func (m model) View() string {
 		return "Initializing..."
 	}
 
-	hotPink := lipgloss.Color("#FF10F0")
-	cyan := lipgloss.Color("#00FFFF")
-	purple := lipgloss.Color("#B026FF")
+	if m.width < minWidth || m.height < minHeight {
+		return fmt.Sprintf("Terminal too small (%dx%d).\nResize to at least %dx%d.",
+			m.width, m.height, minWidth, minHeight)
+	}
+
+	colors := theme.Active()
 
 	// Header with logo
 	headerStyle := lipgloss.NewStyle().
@@ -385,43 +851,153 @@ func (m model) View() string {
 		Width(m.width).
 		Align(lipgloss.Center)
 
-	logoView := logo.RenderWithTagline("AI Support Agent")
+	tagline := "AI Support Agent"
+	if name := m.client.AssistantName(); name != ai.DefaultAssistantName {
+		tagline = name + " · " + tagline
+	}
+	logoView := logo.RenderWithTagline(tagline)
 	header := headerStyle.Render(logoView)
 
 	// Chat viewport
 	viewportStyle := lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
-		BorderForeground(purple).
+		BorderForeground(colors.Accent).
 		Padding(1, 2).
 		Width(m.width - 2).
-		Height(m.height - 12)
+		Height(m.height - 12 - m.extraInputRows())
 
 	chatView := viewportStyle.Render(m.viewport.View())
+	if m.showInfo {
+		chatView = viewportStyle.Render(overlay(m.viewport.View(), m.renderInfoPanel(), m.viewport.Width))
+	}
+	if m.mode() == modeFilePicker {
+		chatView = viewportStyle.Render(m.renderFilePicker())
+	}
+	if m.mode() == modeSessionPicker {
+		chatView = viewportStyle.Render(m.renderSessionPicker())
+	}
 
 	// Input area
 	inputStyle := lipgloss.NewStyle().
 		Border(lipgloss.RoundedBorder()).
-		BorderForeground(hotPink).
+		BorderForeground(colors.Primary).
 		Padding(0, 1).
 		Width(m.width - 2)
 
-	inputView := inputStyle.Render(m.input.View())
+	var staged []string
+	stagedStyle := lipgloss.NewStyle().
+		Foreground(theme.Active().Muted)
+	if m.queued != "" {
+		queued := m.queued
+		if runes := []rune(queued); len(runes) > m.width-30 {
+			queued = string(runes[:m.width-30]) + "…"
+		}
+		staged = append(staged, stagedStyle.Render("Queued: "+queued+" (Ctrl+X to discard)"))
+	}
+	if len(m.attachments) > 0 {
+		staged = append(staged, stagedStyle.Render("Attached: "+strings.Join(m.attachments, ", ")))
+	}
+	inputView := inputStyle.Render(strings.Join(append(staged, m.input.View()), "\n"))
+	if m.replay != nil {
+		replayStyle := lipgloss.NewStyle().
+			Foreground(theme.Active().Warning).
+			Bold(true)
+		inputView = inputStyle.Render(replayStyle.Render("▶ Replay mode: viewing a saved session, no messages are sent") + "\n\n")
+	}
+	if m.confirming != nil {
+		inputView = inputStyle.
+			BorderForeground(theme.Active().Warning).
+			Render(m.renderConfirmPanel())
+	}
+	if m.search != nil {
+		inputView = inputStyle.Render(m.renderSearchBar() + "\n")
+	}
 
 	// Help text
 	helpStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#666666")).
+		Foreground(theme.Active().Muted).
 		Italic(true).
 		Padding(0, 2)
 
-	help := helpStyle.Render("Enter: send message | Esc/Ctrl+C: quit")
+	helpText := "Enter: send message | Ctrl+T: attach file | Ctrl+Y: copy reply | Ctrl+O: edit last message | Ctrl+N: new conversation | /help: commands | Esc/Ctrl+C: quit"
+	switch {
+	case m.replay != nil:
+		helpText = "Space/Enter: next turn | a: toggle auto-play | q/Esc: quit"
+	case m.editingInput:
+		helpText = "Ctrl+S: run with edited input | Esc: back"
+	case m.confirming != nil:
+		helpText = "Enter/y: run | a: always run this call | t: trust all this session | n: decline | e: edit input | Ctrl+R: cancel"
+	case m.picker != nil:
+		helpText = "Type to filter | ↑/↓: select | Enter: attach | Esc: close"
+	case m.sessions != nil && m.sessions.deleting:
+		helpText = "y: delete | n: keep"
+	case m.sessions != nil:
+		helpText = "↑/↓: select | Enter: load | d: delete | Esc: close"
+	case m.search != nil && m.search.typing:
+		helpText = "Type to search | Enter: done | Tab: toggle case | Esc: close"
+	case m.search != nil:
+		helpText = "n/N: next/previous match | /: edit query | Tab: toggle case | Enter: close here | Esc: close"
+	case m.turn != nil:
+		helpText = "Enter: queue message | Esc/Ctrl+R: cancel | Ctrl+C: quit"
+	}
+	help := helpStyle.Render(helpText)
+	if m.newBelow {
+		help = lipgloss.NewStyle().
+			Foreground(theme.Active().Warning).
+			Bold(true).
+			PaddingLeft(2).
+			Render("↓ new messages (PgDn)") + helpStyle.PaddingLeft(1).Render("| "+helpText)
+	}
+	if counter := m.inputCounter(); counter != "" && m.mode() == modeChat {
+		counterStyle := helpStyle.PaddingLeft(0)
+		if m.nearCharLimit() {
+			counterStyle = counterStyle.Foreground(theme.Active().Warning)
+		}
+		help += counterStyle.Render("| " + counter)
+	}
 
 	// Status bar
 	statusStyle := lipgloss.NewStyle().
-		Foreground(cyan).
+		Foreground(colors.Secondary).
 		Bold(true).
 		Padding(0, 2)
 
-	status := statusStyle.Render(fmt.Sprintf("Messages: %d", len(m.messages)))
+	statusText := fmt.Sprintf("Model: %s | Messages: %d", m.client.Model(), len(m.messages))
+	if m.usage.Total() > 0 {
+		statusText += fmt.Sprintf(" | Tokens: %s in, %s out", formatTokens(m.usage.InputTokens), formatTokens(m.usage.OutputTokens))
+	}
+	if m.replay != nil {
+		statusText += " | " + m.replayStatus()
+	} else if m.confirmRefresh {
+		statusText += fmt.Sprintf(" | Re-run %d tool call(s)? (Enter/y: yes, n: no)", len(m.refreshableToolCalls()))
+	} else if m.confirmNew {
+		statusText += " | " + m.newConversationPrompt()
+	} else if m.sessions != nil && m.sessions.deleting {
+		statusText += " | " + m.sessionPickerPrompt()
+	} else if m.notice != "" {
+		statusText += " | " + m.notice
+	}
+	if language := m.client.Language(); language != "" {
+		statusText += " | Language: " + language
+	}
+	if rateLimit := m.client.RateLimit(); rateLimit.Low() {
+		statusText += " | Rate limit nearly exhausted, pacing requests"
+	}
+	gauge := m.contextGauge()
+	if m.turn != nil && m.turn.running != "" {
+		// Cut the command to what's left of the line, keeping the label
+		running := []rune(m.turn.running)
+		if room := max(m.width-6-lipgloss.Width(m.spinner.View())-lipgloss.Width(gauge)-lipgloss.Width(statusText)-len(" | Running: "), 10); len(running) > room {
+			running = append(running[:room-1], '…')
+		}
+		statusText += " | Running: " + string(running)
+	}
+	status := statusStyle.Render(statusText)
+	if m.thinking {
+		// Rendered apart so its color doesn't end the status text's style
+		status = lipgloss.NewStyle().PaddingLeft(2).Render(m.spinner.View()) + statusStyle.PaddingLeft(0).Render(statusText)
+	}
+	status = m.withGauge(status, gauge)
 
 	// Combine everything
 	return lipgloss.JoinVertical(
@@ -434,14 +1010,32 @@ func (m model) View() string {
 	)
 }
 
-func Run() error {
+// ErrMissingAPIKey is returned by Run when there's no key for the default
+// provider, rather than letting the first request fail inside the SDK
+var ErrMissingAPIKey = errors.New("ANTHROPIC_API_KEY is not set; export it, or add ANTHROPIC_API_KEY=<your key> to a .env file in the directory you run Kilo from")
+
+func Run(opts ...Option) error {
+	m := New(opts...)
+	// Other providers bring their own credentials
+	if m.provider == m.client && os.Getenv("ANTHROPIC_API_KEY") == "" {
+		return ErrMissingAPIKey
+	}
+
 	p := tea.NewProgram(
-		New(),
+		m,
 		tea.WithAltScreen(),
 		tea.WithMouseCellMotion(),
 	)
 
-	if _, err := p.Run(); err != nil {
+	final, err := p.Run()
+	// However the program ended, stop the work in flight and keep the
+	// conversation
+	if m, ok := final.(model); ok {
+		if saveErr := m.shutdown(); saveErr != nil && err == nil {
+			err = saveErr
+		}
+	}
+	if err != nil {
 		return fmt.Errorf("error running program: %w", err)
 	}
 
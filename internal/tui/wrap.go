@@ -0,0 +1,43 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/charmbracelet/x/cellbuf"
+)
+
+// softWrap wraps each line of rendered content that is wider than width,
+// breaking at spaces where it can. Styles carry over to the continuation
+// lines, and escape sequences are never split. Continuation lines repeat the
+// line's indentation, so wrapped code and tool output stay aligned.
+func softWrap(content string, width int) string {
+	if width < 1 {
+		return content
+	}
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		if ansi.StringWidth(line) > width {
+			lines[i] = wrapLine(line, width)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// wrapLine wraps one line, indenting its continuations to match it
+func wrapLine(line string, width int) string {
+	plain := ansi.Strip(line)
+	indent := len(plain) - len(strings.TrimLeft(plain, " "))
+	// Deep indentation would leave too little room; wrap to the margin
+	if indent > width/2 {
+		indent = 0
+	}
+
+	body := ansi.TruncateLeft(line, indent, "")
+	pad := strings.Repeat(" ", indent)
+	wrapped := strings.Split(cellbuf.Wrap(body, width-indent, ""), "\n")
+	for i := range wrapped {
+		wrapped[i] = pad + wrapped[i]
+	}
+	return strings.Join(wrapped, "\n")
+}
@@ -1,22 +1,134 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io/fs"
+	"kilo/internal/ai"
+	"kilo/internal/config"
+	"kilo/internal/doctor"
+	"kilo/internal/storage"
+	"kilo/internal/tools"
 	"kilo/internal/tui"
 	"os"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
 func main() {
+	runDoctor := flag.Bool("doctor", false, "run setup diagnostics and exit")
+	live := flag.Bool("live", false, "with --doctor, make a test API call (spends a few tokens)")
+	replay := flag.String("replay", "", "step through a saved session (name or path) without making API calls")
+	modelName := flag.String("model", "", "Claude model to use (default $KILO_MODEL, then "+ai.DefaultModel+")")
+	unsafeModel := flag.Bool("unsafe-model", false, "allow a model that isn't in Kilo's known-good list")
+	resume := flag.Bool("resume", false, "continue the most recently saved session")
+	sessions := flag.Bool("sessions", false, "start by picking a saved session to continue")
+	projectContext := flag.Bool("project-context", false, "send a listing of the working directory with each request (costs tokens)")
+	cwd := flag.String("cwd", "", "directory tools run in and file tools are confined to (default: the current directory)")
+	yolo := flag.Bool("yolo", false, "let bash run commands Kilo would otherwise refuse, such as rm -rf /")
+	flag.Parse()
 
-	err := godotenv.Load()
+	if *runDoctor {
+		// The doctor reports on .env itself, so a missing file is not fatal here
+		godotenv.Load()
+
+		checks := doctor.Run(context.Background(), doctor.Options{Live: *live})
+		fmt.Println(doctor.Format(checks))
+		if !doctor.Passed(checks) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if *replay != "" {
+		path, err := storage.FindSession(*replay)
+		if err == nil {
+			err = tui.Replay(path)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// .env is optional: the key may come from the environment instead, which
+	// tui.Run checks. A .env that exists but can't be parsed is still an error.
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		fmt.Fprintf(os.Stderr, "Error: failed to load .env: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Keep saved sessions and input history within their configured caps
+	if err := storage.Prune(storage.LoadLimits()); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	var workDir string
+	if *cwd != "" {
+		dir, err := tools.ValidateWorkingDir(*cwd)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		workDir = dir
+	}
+
+	// Flags win over the environment, which wins over the config file
+	cfg, err := config.LoadConfig()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	if err := tui.Run(); err != nil {
+	// Whichever model is chosen is checked against the known list
+	model := *modelName
+	if model == "" {
+		model = cfg.Model
+	}
+	if model != "" {
+		if err := ai.ValidateModel(model); err != nil && !*unsafeModel {
+			fmt.Fprintf(os.Stderr, "Error: %v\nUse --unsafe-model to run it anyway.\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// A resumed session's saved settings override the config, but not --model
+	opts := []tui.Option{tui.WithConfig(cfg)}
+	if *resume {
+		opts = append(opts, tui.WithResume())
+		model = *modelName
+	}
+	opts = append(opts, tui.WithModel(model))
+	if *unsafeModel {
+		opts = append(opts, tui.WithUnknownModels())
+	}
+	if workDir != "" {
+		opts = append(opts, tui.WithWorkingDir(workDir))
+	}
+	if *projectContext {
+		opts = append(opts, tui.WithProjectContext())
+	}
+	if *sessions {
+		opts = append(opts, tui.WithSessionPicker())
+	}
+	if *yolo {
+		opts = append(opts, tui.WithoutScreening())
+	}
+	// The TUI's own client serves the default provider, so /settings apply
+	if name := os.Getenv("KILO_PROVIDER"); name != "" && !strings.EqualFold(name, ai.DefaultProvider) {
+		provider, err := ai.NewProvider(name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		opts = append(opts, tui.WithProvider(provider))
+	}
+
+	if err := tui.Run(opts...); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
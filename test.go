@@ -1,9 +1,9 @@
 package main
 
 import (
-	"kilo/internal/ai"
+	"kilo/internal/tools"
 )
 
 func Test() {
-	ai.Example()
+	tools.Example()
 }